@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/LucasAndFlores/user_api/database"
+	"github.com/LucasAndFlores/user_api/internal/audit"
+	"github.com/LucasAndFlores/user_api/internal/dto"
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/gofiber/fiber/v2"
+)
+
+type auditTrail struct {
+	Records    []dto.AuditRecordDTO `json:"records"`
+	NextCursor string               `json:"next_cursor"`
+}
+
+func fetchAuditTrail(t *testing.T, app *fiber.App, url string, headers map[string]string) auditTrail {
+	t.Helper()
+
+	status, body := sendJSON(t, app, "GET", url, nil, headers)
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusOK, string(body))
+	}
+
+	var trail auditTrail
+
+	err := json.Unmarshal(body, &trail)
+
+	if err != nil {
+		t.Fatalf("Failed to decode the audit trail: %v", err)
+	}
+
+	return trail
+}
+
+func findChange(record dto.AuditRecordDTO, field string) *model.AuditChange {
+	for i := range record.Changes {
+		if record.Changes[i].Field == field {
+			return &record.Changes[i]
+		}
+	}
+
+	return nil
+}
+
+func TestAuditTrailScenario(t *testing.T) {
+	adminId := "3e7f8a9b-0c1d-4e2f-8a3b-5c6d7e8f9a4c"
+
+	tApp, admin := runTestServerWithAdmin(t, "audit_admin@example.com", adminId)
+
+	id := "4f8a9b0c-1d2e-4f3a-9b4c-6d7e8f9a0b5d"
+
+	createUserWithPassword(t, tApp, "audit_user@example.com", id)
+
+	owner := map[string]string{
+		"Authorization":        fmt.Sprintf("Bearer %v", login(t, tApp, "audit_user@example.com", TEST_PASSWORD)["access_token"]),
+		fiber.HeaderXRequestID: "audit-scenario-update",
+	}
+
+	url := fmt.Sprintf("/api/v1/users/%v", id)
+
+	status, body := sendJSON(t, tApp, "PUT", url, map[string]interface{}{
+		"name":          "audited user",
+		"email":         "audit_user@example.com",
+		"date_of_birth": "1990-01-01T00:00:00Z",
+	}, owner)
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusOK, string(body))
+	}
+
+	status, body = sendJSON(t, tApp, "GET", url+"/audit", nil, owner)
+
+	if status != fiber.StatusForbidden {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusForbidden, string(body))
+	}
+
+	status, body = sendJSON(t, tApp, "DELETE", url, nil, admin)
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusOK, string(body))
+	}
+
+	// the trail outlives the user, two records per page
+	first := fetchAuditTrail(t, tApp, url+"/audit?limit=2", admin)
+
+	if len(first.Records) != 2 || first.NextCursor == "" {
+		t.Fatalf("Result is different from expected. Result: %+v. Expected: 2 records and a next cursor", first)
+	}
+
+	second := fetchAuditTrail(t, tApp, url+"/audit?limit=2&cursor="+first.NextCursor, admin)
+
+	if len(second.Records) != 2 || second.NextCursor != "" {
+		t.Fatalf("Result is different from expected. Result: %+v. Expected: 2 records and no next cursor", second)
+	}
+
+	created, verified, updated, deleted := first.Records[0], first.Records[1], second.Records[0], second.Records[1]
+
+	if created.Action != model.AUDIT_ACTION_CREATE || created.Actor != audit.ANONYMOUS || created.RequestId == "" {
+		t.Fatalf("Result is different from expected. Result: %+v. Expected: an anonymous creation", created)
+	}
+
+	if change := findChange(created, "password_hash"); change == nil || change.After != audit.MASKED {
+		t.Fatalf("Result is different from expected. Result: %+v. Expected: %v", change, audit.MASKED)
+	}
+
+	if change := findChange(verified, "email_verified_at"); verified.Action != model.AUDIT_ACTION_UPDATE || change == nil || change.Before != nil {
+		t.Fatalf("Result is different from expected. Result: %+v. Expected: the verification of the email", verified)
+	}
+
+	if updated.Action != model.AUDIT_ACTION_UPDATE || updated.Actor != "user:"+id || updated.RequestId != "audit-scenario-update" {
+		t.Fatalf("Result is different from expected. Result: %+v. Expected: an update by the user", updated)
+	}
+
+	if change := findChange(updated, "name"); len(updated.Changes) != 1 || change == nil || change.Before != "session user" || change.After != "audited user" {
+		t.Fatalf("Result is different from expected. Result: %+v. Expected: the name change only", updated.Changes)
+	}
+
+	if deleted.Action != model.AUDIT_ACTION_DELETE || deleted.Actor != "admin:"+adminId {
+		t.Fatalf("Result is different from expected. Result: %+v. Expected: a deletion by the admin", deleted)
+	}
+
+	if change := findChange(deleted, "email"); change == nil || change.Before != audit.MASKED || change.After != nil {
+		t.Fatalf("Result is different from expected. Result: %+v. Expected: the masked removed email", change)
+	}
+
+	db, err := database.ConnectDatabase()
+
+	if err != nil {
+		t.Fatalf("An error occurred when tried to connect to database: %v", err)
+	}
+
+	for _, statement := range []string{
+		"UPDATE audit_records SET actor = 'someone' WHERE user_external_id = ?",
+		"DELETE FROM audit_records WHERE user_external_id = ?",
+	} {
+		err = db.Exec(statement, id).Error
+
+		if err == nil {
+			t.Fatalf("The audit records were changed by: %v", statement)
+		}
+	}
+}
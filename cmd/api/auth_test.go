@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/LucasAndFlores/user_api/database"
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/LucasAndFlores/user_api/internal/pii"
+	"github.com/gofiber/fiber/v2"
+)
+
+const TEST_PASSWORD = "Str0ng-Passw0rd"
+
+func sendJSON(t *testing.T, app *fiber.App, method string, url string, body interface{}, headers map[string]string) (int, []byte) {
+	t.Helper()
+
+	var reader io.Reader
+
+	if body != nil {
+		request, err := json.Marshal(body)
+
+		if err != nil {
+			t.Fatalf("Failed to marshal paylod to JSON: %v", err)
+		}
+
+		reader = bytes.NewReader(request)
+	}
+
+	req := httptest.NewRequest(method, url, reader)
+
+	req.Header.Set("content-type", "application/json")
+
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := app.Test(req, -1)
+
+	if err != nil {
+		t.Fatalf("Failed when trying to execute fiber.Test: %v", err)
+	}
+
+	rBody, err := io.ReadAll(resp.Body)
+
+	if err != nil {
+		t.Fatalf("Failed when trying to execute io.ReadAll: %v", err)
+	}
+
+	return resp.StatusCode, rBody
+}
+
+func login(t *testing.T, app *fiber.App, email string, password string) map[string]interface{} {
+	t.Helper()
+
+	status, body := sendJSON(t, app, "POST", "/api/auth/login", map[string]interface{}{
+		"email":    email,
+		"password": password,
+	}, nil)
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Login failed. Status: %v. Body: %v", status, string(body))
+	}
+
+	var tokens map[string]interface{}
+
+	err := json.Unmarshal(body, &tokens)
+
+	if err != nil {
+		t.Fatalf("Failed to unmarshal login response: %v", err)
+	}
+
+	return tokens
+}
+
+func TestLoginSuccessfulScenario(t *testing.T) {
+	tApp := runTestServer()
+
+	status, body := sendJSON(t, tApp, "POST", "/api/save", map[string]interface{}{
+		"name":          "login user",
+		"email":         "login@example.com",
+		"id":            "0d8f3f4e-39a5-4f1e-8a3c-6c1f2d7b9a10",
+		"date_of_birth": "1990-01-01T00:00:00Z",
+		"password":      TEST_PASSWORD,
+	}, nil)
+
+	if status != fiber.StatusCreated {
+		t.Fatalf("The result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusCreated, string(body))
+	}
+
+	status, body = sendJSON(t, tApp, "POST", "/api/auth/login", map[string]interface{}{
+		"email":    "login@example.com",
+		"password": TEST_PASSWORD,
+	}, nil)
+
+	if status != fiber.StatusForbidden {
+		t.Fatalf("Login of an unverified user should be forbidden. Result: %v. Body: %v", status, string(body))
+	}
+
+	verifyEmail(t, tApp, "login@example.com")
+
+	tokens := login(t, tApp, "login@example.com", TEST_PASSWORD)
+
+	if tokens["access_token"] == "" || tokens["refresh_token"] == "" || tokens["token_type"] != "Bearer" {
+		t.Fatalf("The login response is missing tokens: %v", tokens)
+	}
+}
+
+type LoginErrorTest struct {
+	request            map[string]interface{}
+	expectedStatusCode int
+	expectedBody       string
+}
+
+func TestLoginErrorScenario(t *testing.T) {
+	tApp := runTestServer()
+
+	sendJSON(t, tApp, "POST", "/api/save", map[string]interface{}{
+		"name":          "login user",
+		"email":         "login_error@example.com",
+		"id":            "8c2b8f0e-7d1b-4b9f-b2f4-0f3e6a1d5c22",
+		"date_of_birth": "1990-01-01T00:00:00Z",
+		"password":      TEST_PASSWORD,
+	}, nil)
+
+	sendJSON(t, tApp, "POST", "/api/save", map[string]interface{}{
+		"name":          "passwordless user",
+		"email":         "passwordless@example.com",
+		"id":            "5a9e1c3d-2b7f-4e8a-9c6d-1f0b3a5e7d44",
+		"date_of_birth": "1990-01-01T00:00:00Z",
+	}, nil)
+
+	testCases := []LoginErrorTest{
+		{
+			request:            map[string]interface{}{"email": "login_error@example.com", "password": "wrong-Passw0rd"},
+			expectedStatusCode: fiber.StatusUnauthorized,
+			expectedBody:       "{\"message\":\"invalid credentials\"}",
+		},
+		{
+			request:            map[string]interface{}{"email": "unknown@example.com", "password": TEST_PASSWORD},
+			expectedStatusCode: fiber.StatusUnauthorized,
+			expectedBody:       "{\"message\":\"invalid credentials\"}",
+		},
+		{
+			request:            map[string]interface{}{"email": "passwordless@example.com", "password": TEST_PASSWORD},
+			expectedStatusCode: fiber.StatusUnauthorized,
+			expectedBody:       "{\"message\":\"invalid credentials\"}",
+		},
+		{
+			request:            map[string]interface{}{"email": "login_error@example.com"},
+			expectedStatusCode: fiber.StatusUnprocessableEntity,
+			expectedBody:       "[{\"Field\":\"Password\",\"Tag\":\"required\",\"Value\":\"\"}]",
+		},
+	}
+
+	for i, value := range testCases {
+		status, body := sendJSON(t, tApp, "POST", "/api/auth/login", value.request, nil)
+
+		if status != value.expectedStatusCode {
+			t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Test case index: %v", status, value.expectedStatusCode, i)
+		}
+
+		if string(body) != value.expectedBody {
+			t.Fatalf("The body result is different from expected. Result: %v. Expected: %v. Test case index: %v", string(body), value.expectedBody, i)
+		}
+	}
+}
+
+func TestCreateUserWeakPasswordScenario(t *testing.T) {
+	tApp := runTestServer()
+
+	status, body := sendJSON(t, tApp, "POST", "/api/save", map[string]interface{}{
+		"name":          "weak user",
+		"email":         "weak@example.com",
+		"id":            "c7e2d9a1-4f3b-4d8e-a6c5-9b1e0f2d3a77",
+		"date_of_birth": "1990-01-01T00:00:00Z",
+		"password":      "password",
+	}, nil)
+
+	if status != fiber.StatusUnprocessableEntity {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status, fiber.StatusUnprocessableEntity)
+	}
+
+	expectedBody := "[{\"Field\":\"Password\",\"Tag\":\"password\",\"Value\":\"\"}]"
+
+	if string(body) != expectedBody {
+		t.Fatalf("The body result is different from expected. Result: %v. Expected: %v", string(body), expectedBody)
+	}
+}
+
+func createUserWithPassword(t *testing.T, app *fiber.App, email string, id string) {
+	t.Helper()
+
+	status, body := sendJSON(t, app, "POST", "/api/save", map[string]interface{}{
+		"name":          "session user",
+		"email":         email,
+		"id":            id,
+		"date_of_birth": "1990-01-01T00:00:00Z",
+		"password":      TEST_PASSWORD,
+	}, nil)
+
+	if status != fiber.StatusCreated {
+		t.Fatalf("Failed to create user. Status: %v. Body: %v", status, string(body))
+	}
+
+	verifyEmail(t, app, email)
+}
+
+func promoteToAdmin(t *testing.T, email string) {
+	t.Helper()
+
+	promoteTo(t, email, model.ROLE_ADMIN)
+}
+
+func promoteTo(t *testing.T, email string, role string) {
+	t.Helper()
+
+	db, err := database.ConnectDatabase()
+
+	if err != nil {
+		t.Fatalf("An error occurred when tried to connect to database: %v", err)
+	}
+
+	err = db.Model(&model.User{}).Where("email_index = ?", pii.BlindIndex(email)).Update("role", role).Error
+
+	if err != nil {
+		t.Fatalf("Failed to promote user to %v: %v", role, err)
+	}
+}
+
+// runTestServerWithAdmin starts the API with admins signing in without a
+// second factor, and returns it with the headers of a new admin.
+func runTestServerWithAdmin(t *testing.T, email string, id string) (*fiber.App, map[string]string) {
+	t.Helper()
+
+	t.Setenv("AUTH_REQUIRE_ADMIN_MFA", "false")
+
+	app := runTestServer()
+
+	createUserWithPassword(t, app, email, id)
+
+	promoteToAdmin(t, email)
+
+	return app, map[string]string{"Authorization": fmt.Sprintf("Bearer %v", login(t, app, email, TEST_PASSWORD)["access_token"])}
+}
+
+func TestRefreshTokenRotationScenario(t *testing.T) {
+	tApp := runTestServer()
+
+	createUserWithPassword(t, tApp, "rotation@example.com", "1b0e6f7a-3c2d-4e5f-8a9b-0c1d2e3f4a51")
+
+	tokens := login(t, tApp, "rotation@example.com", TEST_PASSWORD)
+
+	status, body := sendJSON(t, tApp, "POST", "/api/auth/refresh", map[string]interface{}{"refresh_token": tokens["refresh_token"]}, nil)
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusOK, string(body))
+	}
+
+	var rotated map[string]interface{}
+
+	json.Unmarshal(body, &rotated)
+
+	if rotated["refresh_token"] == tokens["refresh_token"] {
+		t.Fatalf("The refresh token was not rotated")
+	}
+
+	// reusing the first token revokes the family, including the rotated token
+	status, _ = sendJSON(t, tApp, "POST", "/api/auth/refresh", map[string]interface{}{"refresh_token": tokens["refresh_token"]}, nil)
+
+	if status != fiber.StatusUnauthorized {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status, fiber.StatusUnauthorized)
+	}
+
+	status, _ = sendJSON(t, tApp, "POST", "/api/auth/refresh", map[string]interface{}{"refresh_token": rotated["refresh_token"]}, nil)
+
+	if status != fiber.StatusUnauthorized {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status, fiber.StatusUnauthorized)
+	}
+}
+
+func TestLogoutScenario(t *testing.T) {
+	tApp := runTestServer()
+
+	createUserWithPassword(t, tApp, "logout@example.com", "2c1f7a8b-4d3e-4f60-9bac-1d2e3f4a5b62")
+
+	tokens := login(t, tApp, "logout@example.com", TEST_PASSWORD)
+
+	status, body := sendJSON(t, tApp, "POST", "/api/auth/logout", map[string]interface{}{"refresh_token": tokens["refresh_token"]}, nil)
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusOK, string(body))
+	}
+
+	status, _ = sendJSON(t, tApp, "POST", "/api/auth/refresh", map[string]interface{}{"refresh_token": tokens["refresh_token"]}, nil)
+
+	if status != fiber.StatusUnauthorized {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status, fiber.StatusUnauthorized)
+	}
+}
+
+func TestRevokeAllSessionsScenario(t *testing.T) {
+	tApp := runTestServer()
+
+	createUserWithPassword(t, tApp, "revoke_admin@example.com", "3d2a8b9c-5e4f-4a71-8cbd-2e3f4a5b6c73")
+	createUserWithPassword(t, tApp, "revoke_target@example.com", "4e3b9c0d-6f5a-4b82-9dce-3f4a5b6c7d84")
+
+	promoteToAdmin(t, "revoke_admin@example.com")
+
+	adminPassword := login(t, tApp, "revoke_admin@example.com", TEST_PASSWORD)
+	first := login(t, tApp, "revoke_target@example.com", TEST_PASSWORD)
+	second := login(t, tApp, "revoke_target@example.com", TEST_PASSWORD)
+
+	url := "/api/admin/users/4e3b9c0d-6f5a-4b82-9dce-3f4a5b6c7d84/sessions"
+
+	status, _ := sendJSON(t, tApp, "DELETE", url, nil, map[string]string{"Authorization": fmt.Sprintf("Bearer %v", first["access_token"])})
+
+	if status != fiber.StatusForbidden {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status, fiber.StatusForbidden)
+	}
+
+	// admins must have opened their session with a second factor
+	status, _ = sendJSON(t, tApp, "DELETE", url, nil, map[string]string{"Authorization": fmt.Sprintf("Bearer %v", adminPassword["access_token"])})
+
+	if status != fiber.StatusForbidden {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status, fiber.StatusForbidden)
+	}
+
+	secret, _ := enableTOTP(t, tApp, adminPassword["access_token"])
+
+	status, admin := loginWithMFA(t, tApp, "revoke_admin@example.com", nextTOTPCode(t, secret))
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Failed to login with mfa. Status: %v", status)
+	}
+
+	status, body := sendJSON(t, tApp, "DELETE", url, nil, map[string]string{"Authorization": fmt.Sprintf("Bearer %v", admin["access_token"])})
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusOK, string(body))
+	}
+
+	for _, tokens := range []map[string]interface{}{first, second} {
+		status, _ = sendJSON(t, tApp, "POST", "/api/auth/refresh", map[string]interface{}{"refresh_token": tokens["refresh_token"]}, nil)
+
+		if status != fiber.StatusUnauthorized {
+			t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status, fiber.StatusUnauthorized)
+		}
+	}
+}
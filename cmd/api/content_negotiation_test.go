@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/LucasAndFlores/user_api/internal/codec"
+	"github.com/LucasAndFlores/user_api/internal/redact"
+	"github.com/gofiber/fiber/v2"
+)
+
+func sendEncoded(t *testing.T, app *fiber.App, c *codec.Codec, method string, url string, body interface{}) (int, interface{}) {
+	var reader io.Reader
+
+	if body != nil {
+		encoded, err := c.Marshal(body)
+
+		if err != nil {
+			t.Fatalf("Failed to encode the body: %v", err)
+		}
+
+		reader = bytes.NewReader(encoded)
+	}
+
+	req := httptest.NewRequest(method, url, reader)
+
+	req.Header.Set("Content-Type", c.MediaType)
+	req.Header.Set("Accept", c.MediaType)
+
+	resp, err := app.Test(req, -1)
+
+	if err != nil {
+		t.Fatalf("Failed when trying to execute fiber.Test: %v", err)
+	}
+
+	if resp.Header.Get("Content-Type") != c.MediaType {
+		t.Fatalf("The content type is different from expected. Result: %v. Expected: %v", resp.Header.Get("Content-Type"), c.MediaType)
+	}
+
+	rBody, _ := io.ReadAll(resp.Body)
+
+	var decoded interface{}
+
+	err = c.Unmarshal(rBody, &decoded)
+
+	if err != nil {
+		t.Fatalf("Failed to decode the body: %v", err)
+	}
+
+	return resp.StatusCode, decoded
+}
+
+func TestContentNegotiationScenario(t *testing.T) {
+	tApp := runTestServer()
+
+	ids := map[*codec.Codec]string{
+		codec.JSON:        "1e5f6a7b-8c9d-4e0f-8a1b-3c4d5e6f7a80",
+		codec.MessagePack: "2f6a7b8c-9d0e-4f1a-9b2c-4d5e6f7a8b91",
+		codec.CBOR:        "3a7b8c9d-0e1f-4a2b-8c3d-5e6f7a8b9ca2",
+		codec.XML:         "4b8c9d0e-1f2a-4b3c-9d4e-6f7a8b9c0db3",
+	}
+
+	for c, id := range ids {
+		t.Run(c.MediaType, func(t *testing.T) {
+			user := map[string]string{
+				"name":          "encoded user",
+				"email":         fmt.Sprintf("%v@example.com", id),
+				"id":            id,
+				"date_of_birth": "1990-01-01T00:00:00Z",
+			}
+
+			status, body := sendEncoded(t, tApp, c, "POST", "/api/v1/users", user)
+
+			if status != fiber.StatusCreated {
+				t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusCreated, body)
+			}
+
+			status, body = sendEncoded(t, tApp, c, "GET", "/api/v1/users/"+id+"?fields=name,email", nil)
+
+			if status != fiber.StatusOK {
+				t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusOK, body)
+			}
+
+			response, _ := body.(map[string]interface{})
+			found, _ := response["user"].(map[string]interface{})
+
+			// anonymous callers see the email masked
+			if found["name"] != user["name"] || found["email"] != redact.Email(user["email"]) {
+				t.Fatalf("Result is different from expected. Result: %v. Expected: %v", found, user)
+			}
+
+			// validation errors are encoded like any other answer
+			status, _ = sendEncoded(t, tApp, c, "POST", "/api/v1/users", map[string]string{"name": "x"})
+
+			if status != fiber.StatusUnprocessableEntity {
+				t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status, fiber.StatusUnprocessableEntity)
+			}
+		})
+	}
+}
+
+func TestContentNegotiationErrorScenario(t *testing.T) {
+	tApp := runTestServer()
+
+	req := httptest.NewRequest("GET", "/api/v1/users/5c9d0e1f-2a3b-4c4d-8e5f-7a8b9c0d1ec4", nil)
+
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := tApp.Test(req, -1)
+
+	if err != nil {
+		t.Fatalf("Failed when trying to execute fiber.Test: %v", err)
+	}
+
+	if resp.StatusCode != fiber.StatusNotAcceptable {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", resp.StatusCode, fiber.StatusNotAcceptable)
+	}
+
+	req = httptest.NewRequest("POST", "/api/v1/users", bytes.NewReader([]byte("name=form")))
+
+	req.Header.Set("Content-Type", fiber.MIMEApplicationForm)
+
+	resp, err = tApp.Test(req, -1)
+
+	if err != nil {
+		t.Fatalf("Failed when trying to execute fiber.Test: %v", err)
+	}
+
+	if resp.StatusCode != fiber.StatusUnsupportedMediaType {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", resp.StatusCode, fiber.StatusUnsupportedMediaType)
+	}
+}
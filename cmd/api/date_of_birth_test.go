@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestDateOfBirthScenario(t *testing.T) {
+	t.Setenv("DATE_OF_BIRTH_MIN_AGE", "13")
+
+	tApp, admin := runTestServerWithAdmin(t, "birth_admin@example.com", "3e4f5a6b-7c8d-4e9f-8a0b-1c2d3e4f5a09")
+
+	// RFC 3339 times keep the date they were written with
+	for id, dateOfBirth := range map[string]string{
+		"4f5a6b7c-8d9e-4f0a-9b1c-2d3e4f5a6b1a": "1990-01-01",
+		"5a6b7c8d-9e0f-4a1b-8c2d-3e4f5a6b7c2b": "1990-01-01T23:30:00-05:00",
+	} {
+		status, body := sendJSON(t, tApp, "POST", "/api/v1/users", map[string]interface{}{
+			"name":          "birth user",
+			"email":         fmt.Sprintf("birth_%v@example.com", id),
+			"id":            id,
+			"date_of_birth": dateOfBirth,
+		}, nil)
+
+		if status != fiber.StatusCreated {
+			t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusCreated, string(body))
+		}
+
+		found := findUserAs(t, tApp, id, admin)
+
+		if found.DateOfBirth != "1990-01-01" {
+			t.Fatalf("Result is different from expected. Result: %v. Expected: %v", found.DateOfBirth, "1990-01-01")
+		}
+
+		// the response is accepted back as is
+		status, body = sendJSON(t, tApp, "PUT", "/api/v1/users/"+id, map[string]interface{}{
+			"name":          found.Name,
+			"email":         found.Email,
+			"date_of_birth": found.DateOfBirth,
+		}, admin)
+
+		if status != fiber.StatusOK {
+			t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusOK, string(body))
+		}
+	}
+
+	today := time.Now().In(time.FixedZone("UTC+14", 14*60*60))
+
+	invalid := map[string]string{
+		"not a date":     "1990-02-30",
+		"in the future":  today.AddDate(0, 0, 1).Format("2006-01-02"),
+		"too young":      today.AddDate(-12, 0, 0).Format("2006-01-02"),
+		"too old":        today.AddDate(-131, 0, 0).Format("2006-01-02"),
+		"without dashes": "19900101",
+	}
+
+	for reason, dateOfBirth := range invalid {
+		status, body := sendJSON(t, tApp, "PUT", "/api/v1/users/4f5a6b7c-8d9e-4f0a-9b1c-2d3e4f5a6b1a", map[string]interface{}{
+			"name":          "birth user",
+			"email":         "birth_4f5a6b7c-8d9e-4f0a-9b1c-2d3e4f5a6b1a@example.com",
+			"date_of_birth": dateOfBirth,
+		}, admin)
+
+		if status != fiber.StatusUnprocessableEntity {
+			t.Fatalf("Result is different from expected for a date %v. Result: %v. Expected: %v. Body: %v", reason, status, fiber.StatusUnprocessableEntity, string(body))
+		}
+	}
+
+	status, body := sendJSON(t, tApp, "PUT", "/api/v1/users/4f5a6b7c-8d9e-4f0a-9b1c-2d3e4f5a6b1a", map[string]interface{}{
+		"name":          "birth user",
+		"email":         "birth_4f5a6b7c-8d9e-4f0a-9b1c-2d3e4f5a6b1a@example.com",
+		"date_of_birth": today.AddDate(-13, 0, 0).Format("2006-01-02"),
+	}, admin)
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusOK, string(body))
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestConditionalRequestsScenario(t *testing.T) {
+	tApp := runTestServer()
+
+	id := "1f0c6d7e-3a2b-4ce9-b4d5-a0b1c2d3e4f1"
+
+	createUserWithPassword(t, tApp, "etag@example.com", id)
+	createUserWithPassword(t, tApp, "etag_other@example.com", "2a1d7e8f-4b3c-4dfa-85e6-b1c2d3e4f5a2")
+
+	owner := map[string]string{"Authorization": fmt.Sprintf("Bearer %v", login(t, tApp, "etag@example.com", TEST_PASSWORD)["access_token"])}
+	other := map[string]string{"Authorization": fmt.Sprintf("Bearer %v", login(t, tApp, "etag_other@example.com", TEST_PASSWORD)["access_token"])}
+
+	url := fmt.Sprintf("/api/%v", id)
+
+	resp, err := tApp.Test(httptest.NewRequest("GET", url, nil), -1)
+
+	if err != nil {
+		t.Fatalf("Failed when trying to execute fiber.Test: %v", err)
+	}
+
+	anonymous := "\"2;masked=date_of_birth,email\""
+
+	if resp.Header.Get("ETag") != anonymous {
+		t.Fatalf("The ETag is different from expected. Result: %v. Expected: %v", resp.Header.Get("ETag"), anonymous)
+	}
+
+	req := httptest.NewRequest("GET", url, nil)
+
+	req.Header.Set("If-None-Match", anonymous)
+
+	resp, err = tApp.Test(req, -1)
+
+	if err != nil {
+		t.Fatalf("Failed when trying to execute fiber.Test: %v", err)
+	}
+
+	if resp.StatusCode != fiber.StatusNotModified {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", resp.StatusCode, fiber.StatusNotModified)
+	}
+
+	if vary := resp.Header.Get("Vary"); !strings.Contains(vary, "Authorization") || !strings.Contains(vary, "Accept") {
+		t.Fatalf("The Vary header is different from expected. Result: %v", vary)
+	}
+
+	// the owner and another media type get other representations, which
+	// the masked ETag must not validate
+	testCases := []struct {
+		headers      map[string]string
+		expectedETag string
+	}{
+		{headers: map[string]string{"Authorization": owner["Authorization"]}, expectedETag: "\"2\""},
+		{headers: map[string]string{"Accept": "application/msgpack"}, expectedETag: "\"2;masked=date_of_birth,email;application/msgpack\""},
+	}
+
+	for i, value := range testCases {
+		req := httptest.NewRequest("GET", url, nil)
+
+		req.Header.Set("If-None-Match", anonymous)
+
+		for name, header := range value.headers {
+			req.Header.Set(name, header)
+		}
+
+		resp, err = tApp.Test(req, -1)
+
+		if err != nil {
+			t.Fatalf("Failed when trying to execute fiber.Test: %v", err)
+		}
+
+		if resp.StatusCode != fiber.StatusOK || resp.Header.Get("ETag") != value.expectedETag {
+			t.Fatalf("Result is different from expected. Status: %v. ETag: %v. Expected: %v. Test case index: %v", resp.StatusCode, resp.Header.Get("ETag"), value.expectedETag, i)
+		}
+	}
+
+	update := map[string]interface{}{
+		"name":          "renamed user",
+		"email":         "etag@example.com",
+		"date_of_birth": "1991-02-03T00:00:00Z",
+	}
+
+	status, _ := sendJSON(t, tApp, "PUT", url, update, other)
+
+	if status != fiber.StatusForbidden {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status, fiber.StatusForbidden)
+	}
+
+	conditionalCases := []struct {
+		method             string
+		ifMatch            string
+		expectedStatusCode int
+	}{
+		{method: "PUT", ifMatch: "\"2\"", expectedStatusCode: fiber.StatusOK},
+		{method: "PUT", ifMatch: "\"2\"", expectedStatusCode: fiber.StatusPreconditionFailed},
+		{method: "DELETE", ifMatch: "\"2\"", expectedStatusCode: fiber.StatusPreconditionFailed},
+		{method: "DELETE", ifMatch: "W/\"3\"", expectedStatusCode: fiber.StatusPreconditionFailed},
+		{method: "DELETE", ifMatch: "\"3\"", expectedStatusCode: fiber.StatusOK},
+	}
+
+	for i, value := range conditionalCases {
+		headers := map[string]string{"If-Match": value.ifMatch, "Authorization": owner["Authorization"]}
+
+		var body interface{}
+
+		if value.method == "PUT" {
+			body = update
+		}
+
+		status, rBody := sendJSON(t, tApp, value.method, url, body, headers)
+
+		if status != value.expectedStatusCode {
+			t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v. Test case index: %v", status, value.expectedStatusCode, string(rBody), i)
+		}
+	}
+
+	status, _ = sendJSON(t, tApp, "GET", url, nil, nil)
+
+	if status != fiber.StatusNotFound {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status, fiber.StatusNotFound)
+	}
+}
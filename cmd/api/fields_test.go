@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestSparseFieldsetsScenario(t *testing.T) {
+	tApp := runTestServer()
+
+	id := "9c3d4e5f-6a7b-4c8d-8e9f-1a2b3c4d5e6f"
+
+	createUserWithPassword(t, tApp, "fields@example.com", id)
+
+	url := fmt.Sprintf("/api/v1/users/%v?fields=name", id)
+
+	resp, err := tApp.Test(httptest.NewRequest("GET", url, nil), -1)
+
+	if err != nil {
+		t.Fatalf("Failed when trying to execute fiber.Test: %v", err)
+	}
+
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", resp.StatusCode, fiber.StatusOK)
+	}
+
+	if resp.Header.Get("ETag") != "\"2;name\"" {
+		t.Fatalf("The ETag is different from expected. Result: %v. Expected: %v", resp.Header.Get("ETag"), "\"2;name\"")
+	}
+
+	var body map[string]map[string]interface{}
+
+	err = json.NewDecoder(resp.Body).Decode(&body)
+
+	if err != nil {
+		t.Fatalf("Failed to decode the body: %v", err)
+	}
+
+	if len(body["user"]) != 1 || body["user"]["name"] != "session user" {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", body["user"], map[string]interface{}{"name": "session user"})
+	}
+
+	status, rBody := sendJSON(t, tApp, "GET", fmt.Sprintf("/api/v1/users/%v?fields=name,password", id), nil, nil)
+
+	if status != fiber.StatusBadRequest {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusBadRequest, string(rBody))
+	}
+}
+
+func TestListUsersFieldsScenario(t *testing.T) {
+	tApp := runTestServer()
+
+	createUserWithPassword(t, tApp, "list_admin@example.com", "0d4e5f6a-7b8c-4d9e-9f0a-2b3c4d5e6f70")
+
+	promoteToAdmin(t, "list_admin@example.com")
+
+	tokens := login(t, tApp, "list_admin@example.com", TEST_PASSWORD)
+
+	status, _ := sendJSON(t, tApp, "GET", "/api/v1/users", nil, map[string]string{"Authorization": fmt.Sprintf("Bearer %v", tokens["access_token"])})
+
+	if status != fiber.StatusForbidden {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status, fiber.StatusForbidden)
+	}
+
+	secret, _ := enableTOTP(t, tApp, tokens["access_token"])
+
+	status, admin := loginWithMFA(t, tApp, "list_admin@example.com", nextTOTPCode(t, secret))
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Failed to login with mfa. Status: %v", status)
+	}
+
+	headers := map[string]string{"Authorization": fmt.Sprintf("Bearer %v", admin["access_token"])}
+
+	status, rBody := sendJSON(t, tApp, "GET", "/api/v1/users?fields=id,email&limit=1&email=list_admin@example.com", nil, headers)
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusOK, string(rBody))
+	}
+
+	var body struct {
+		Users      []map[string]interface{} `json:"users"`
+		NextCursor string                   `json:"next_cursor"`
+	}
+
+	err := json.Unmarshal(rBody, &body)
+
+	if err != nil {
+		t.Fatalf("Failed to unmarshal the body: %v", err)
+	}
+
+	expected := map[string]interface{}{"id": "0d4e5f6a-7b8c-4d9e-9f0a-2b3c4d5e6f70", "email": "list_admin@example.com"}
+
+	if len(body.Users) != 1 || len(body.Users[0]) != 2 || body.Users[0]["id"] != expected["id"] || body.Users[0]["email"] != expected["email"] || body.NextCursor != "" {
+		t.Fatalf("Result is different from expected. Result: %+v. Expected: %v", body, expected)
+	}
+
+	status, _ = sendJSON(t, tApp, "GET", "/api/v1/users?limit=1000", nil, headers)
+
+	if status != fiber.StatusBadRequest {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status, fiber.StatusBadRequest)
+	}
+}
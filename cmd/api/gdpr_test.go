@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/LucasAndFlores/user_api/database"
+	"github.com/LucasAndFlores/user_api/internal/audit"
+	"github.com/LucasAndFlores/user_api/internal/dto"
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestDataSubjectExportAndErasureScenario(t *testing.T) {
+	tApp, admin := runTestServerWithAdmin(t, "gdpr_admin@example.com", "6b0c1d2e-3f4a-4b5c-9d6e-8f9a0b1c2d7f")
+
+	id := "7c1d2e3f-4a5b-4c6d-8e7f-9a0b1c2d3e8a"
+
+	creation := map[string]interface{}{
+		"name":          "session user",
+		"email":         "gdpr_user@example.com",
+		"id":            id,
+		"date_of_birth": "1990-01-01T00:00:00Z",
+		"password":      TEST_PASSWORD,
+	}
+
+	idempotent := map[string]string{"Idempotency-Key": "gdpr-user-creation"}
+
+	status, body := sendJSON(t, tApp, "POST", "/api/v1/users", creation, idempotent)
+
+	if status != fiber.StatusCreated {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusCreated, string(body))
+	}
+
+	verifyEmail(t, tApp, "gdpr_user@example.com")
+
+	owner := map[string]string{"Authorization": fmt.Sprintf("Bearer %v", login(t, tApp, "gdpr_user@example.com", TEST_PASSWORD)["access_token"])}
+
+	status, body = sendJSON(t, tApp, "PUT", "/api/v1/users/"+id, map[string]interface{}{
+		"name":          "data subject",
+		"email":         "gdpr_user@example.com",
+		"date_of_birth": "1990-01-01T00:00:00Z",
+	}, owner)
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusOK, string(body))
+	}
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/v1/users/%v/export", id), nil)
+
+	req.Header.Set("Authorization", owner["Authorization"])
+
+	res, err := tApp.Test(req, -1)
+
+	if err != nil {
+		t.Fatalf("Failed when trying to execute fiber.Test: %v", err)
+	}
+
+	body, _ = io.ReadAll(res.Body)
+
+	var export dto.DataSubjectExportDTO
+
+	json.Unmarshal(body, &export)
+
+	if res.StatusCode != fiber.StatusOK || !strings.HasPrefix(res.Header.Get(fiber.HeaderContentDisposition), "attachment") {
+		t.Fatalf("Result is different from expected. Result: %v %v. Expected: %v", res.StatusCode, res.Header.Get(fiber.HeaderContentDisposition), fiber.StatusOK)
+	}
+
+	if export.User == nil || export.User.Name != "data subject" || len(export.Versions) != 3 || len(export.AuditRecords) != 3 || len(export.Sessions) == 0 || len(export.Events) < 2 {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: the user with its versions, audit records, sessions and events", string(body))
+	}
+
+	// the export holds every field
+	status, body = sendJSON(t, tApp, "GET", fmt.Sprintf("/api/v1/users/%v/export?fields=id", id), nil, owner)
+
+	if status != fiber.StatusBadRequest {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusBadRequest, string(body))
+	}
+
+	status, body = sendJSON(t, tApp, "POST", fmt.Sprintf("/api/v1/users/%v/erase", id), nil, owner)
+
+	if status != fiber.StatusForbidden {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusForbidden, string(body))
+	}
+
+	// erasing twice succeeds twice
+	for i := 0; i < 2; i++ {
+		status, body = sendJSON(t, tApp, "POST", fmt.Sprintf("/api/v1/users/%v/erase", id), nil, admin)
+
+		if status != fiber.StatusOK {
+			t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusOK, string(body))
+		}
+	}
+
+	status, body = sendJSON(t, tApp, "GET", fmt.Sprintf("/api/v1/users/%v/export", id), nil, admin)
+
+	if status != fiber.StatusGone {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusGone, string(body))
+	}
+
+	status, body = sendJSON(t, tApp, "GET", fmt.Sprintf("/api/v1/users/%v/versions", id), nil, admin)
+
+	if status != fiber.StatusNotFound {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusNotFound, string(body))
+	}
+
+	trail := fetchAuditTrail(t, tApp, fmt.Sprintf("/api/v1/users/%v/audit", id), admin)
+
+	if len(trail.Records) != 4 || trail.Records[3].Action != model.AUDIT_ACTION_ERASE {
+		t.Fatalf("Result is different from expected. Result: %+v. Expected: the records followed by the erasure", trail.Records)
+	}
+
+	if change := findChange(trail.Records[2], "name"); change == nil || change.Before != audit.ERASED || change.After != audit.ERASED {
+		t.Fatalf("Result is different from expected. Result: %+v. Expected: %v", change, audit.ERASED)
+	}
+
+	db, err := database.ConnectDatabase()
+
+	if err != nil {
+		t.Fatalf("An error occurred when tried to connect to database: %v", err)
+	}
+
+	var events []model.OutboxEvent
+
+	db.Where("aggregate_id = ?", id).Find(&events)
+
+	for _, event := range events {
+		if strings.Contains(string(event.Payload), "gdpr_user@example.com") {
+			t.Fatalf("The event %v still has the email: %v", event.Id, string(event.Payload))
+		}
+	}
+
+	// the stored creation is not replayed anymore
+	status, body = sendJSON(t, tApp, "POST", "/api/v1/users", creation, idempotent)
+
+	if status != fiber.StatusConflict {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusConflict, string(body))
+	}
+
+	// the tombstone keeps the id from being registered again
+	status, body = sendJSON(t, tApp, "POST", "/api/save", map[string]interface{}{
+		"name":          "data subject",
+		"email":         "gdpr_user@example.com",
+		"id":            id,
+		"date_of_birth": "1990-01-01T00:00:00Z",
+	}, nil)
+
+	if status != fiber.StatusConflict {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusConflict, string(body))
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type graphQLResponse struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func sendGraphQL(t *testing.T, app *fiber.App, query string, variables map[string]interface{}, headers map[string]string) (int, graphQLResponse) {
+	t.Helper()
+
+	status, body := sendJSON(t, app, "POST", "/graphql", map[string]interface{}{"query": query, "variables": variables}, headers)
+
+	var response graphQLResponse
+
+	err := json.Unmarshal(body, &response)
+
+	if err != nil {
+		t.Fatalf("Failed to unmarshal the body: %v. Body: %v", err, string(body))
+	}
+
+	return status, response
+}
+
+func TestGraphQLScenario(t *testing.T) {
+	tApp := runTestServer()
+
+	createUser := `mutation($input: CreateUserInput!) { createUser(input: $input) { id name version } }`
+
+	for i, id := range []string{"7a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d", "7a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4e"} {
+		status, response := sendGraphQL(t, tApp, createUser, map[string]interface{}{
+			"input": map[string]interface{}{
+				"id":          id,
+				"name":        "graphql user",
+				"email":       fmt.Sprintf("graphql_%v@example.com", i),
+				"dateOfBirth": "1990-01-02T00:00:00Z",
+			},
+		}, nil)
+
+		if status != fiber.StatusOK || len(response.Errors) != 0 {
+			t.Fatalf("Failed to create the user. Status: %v. Errors: %+v", status, response.Errors)
+		}
+	}
+
+	status, response := sendGraphQL(t, tApp, createUser, map[string]interface{}{
+		"input": map[string]interface{}{
+			"id":          "not-a-uuid",
+			"name":        "graphql user",
+			"email":       "graphql_invalid@example.com",
+			"dateOfBirth": "1990-01-02T00:00:00Z",
+		},
+	}, nil)
+
+	if status != fiber.StatusOK || len(response.Errors) != 1 || response.Errors[0].Extensions["code"] != "BAD_USER_INPUT" {
+		t.Fatalf("Result is different from expected. Status: %v. Errors: %+v", status, response.Errors)
+	}
+
+	status, response = sendGraphQL(t, tApp, `{
+		first: user(id: "7a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d") { email }
+		second: user(id: "7a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4e") { email }
+		missing: user(id: "8269b23f-1417-4f9d-9662-83b609a4e6dd") { email }
+	}`, nil, nil)
+
+	if status != fiber.StatusOK || len(response.Errors) != 0 {
+		t.Fatalf("Result is different from expected. Status: %v. Errors: %+v", status, response.Errors)
+	}
+
+	second, ok := response.Data["second"].(map[string]interface{})
+
+	if !ok || second["email"] != "g***@example.com" || response.Data["missing"] != nil {
+		t.Fatalf("Result is different from expected. Result: %v", response.Data)
+	}
+
+	status, response = sendGraphQL(t, tApp, `{ users(first: 1) { edges { node { id } } } }`, nil, nil)
+
+	if status != fiber.StatusOK || len(response.Errors) != 1 || response.Errors[0].Extensions["code"] != "FORBIDDEN" {
+		t.Fatalf("Result is different from expected. Status: %v. Errors: %+v", status, response.Errors)
+	}
+}
+
+func TestGraphQLUsersConnectionScenario(t *testing.T) {
+	tApp := runTestServer()
+
+	createUserWithPassword(t, tApp, "graphql_admin@example.com", "8b2c3d4e-5f6a-4b7c-9d8e-0f1a2b3c4d5e")
+
+	promoteToAdmin(t, "graphql_admin@example.com")
+
+	tokens := login(t, tApp, "graphql_admin@example.com", TEST_PASSWORD)
+
+	secret, _ := enableTOTP(t, tApp, tokens["access_token"])
+
+	status, admin := loginWithMFA(t, tApp, "graphql_admin@example.com", nextTOTPCode(t, secret))
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Failed to login with mfa. Status: %v", status)
+	}
+
+	headers := map[string]string{"Authorization": fmt.Sprintf("Bearer %v", admin["access_token"])}
+
+	query := `query($after: String) {
+		users(first: 1, after: $after, filter: {email: "GRAPHQL_ADMIN@example.com"}) {
+			edges { cursor node { email } }
+			pageInfo { hasNextPage endCursor }
+		}
+	}`
+
+	status, response := sendGraphQL(t, tApp, query, nil, headers)
+
+	if status != fiber.StatusOK || len(response.Errors) != 0 {
+		t.Fatalf("Result is different from expected. Status: %v. Errors: %+v", status, response.Errors)
+	}
+
+	users := response.Data["users"].(map[string]interface{})
+
+	edges := users["edges"].([]interface{})
+
+	if len(edges) != 1 || edges[0].(map[string]interface{})["node"].(map[string]interface{})["email"] != "graphql_admin@example.com" {
+		t.Fatalf("Result is different from expected. Result: %v", users)
+	}
+
+	pageInfo := users["pageInfo"].(map[string]interface{})
+
+	if pageInfo["hasNextPage"] != false {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", pageInfo["hasNextPage"], false)
+	}
+
+	status, response = sendGraphQL(t, tApp, query, map[string]interface{}{"after": pageInfo["endCursor"]}, headers)
+
+	if status != fiber.StatusOK || len(response.Data["users"].(map[string]interface{})["edges"].([]interface{})) != 0 {
+		t.Fatalf("Result is different from expected. Status: %v. Result: %v", status, response.Data)
+	}
+}
+
+func TestGraphQLLimitsScenario(t *testing.T) {
+	t.Setenv("GRAPHQL_MAX_DEPTH", "3")
+	t.Setenv("GRAPHQL_MAX_COMPLEXITY", "50")
+
+	tApp := runTestServer()
+
+	testCases := []struct {
+		description string
+		query       string
+	}{
+		{
+			description: "too deep",
+			query:       `{ users { edges { node { id } } } }`,
+		},
+		{
+			description: "too complex",
+			query:       `{ a: user(id: "8269b23f-1417-4f9d-9662-83b609a4e6dd") { id } users(first: 100) { pageInfo { hasNextPage } } }`,
+		},
+	}
+
+	for _, value := range testCases {
+		t.Run(value.description, func(t *testing.T) {
+			status, response := sendGraphQL(t, tApp, value.query, nil, nil)
+
+			if status != fiber.StatusBadRequest || len(response.Errors) != 1 || response.Data != nil {
+				t.Fatalf("Result is different from expected. Status: %v. Errors: %+v", status, response.Errors)
+			}
+		})
+	}
+}
+
+func TestGraphQLCreateUserLimitsScenario(t *testing.T) {
+	t.Setenv("RATE_LIMIT_CREATE_USER", "2/1h")
+
+	tApp := runTestServer()
+
+	createUser := `mutation($input: CreateUserInput!) { createUser(input: $input) { id version } }`
+
+	variables := map[string]interface{}{
+		"input": map[string]interface{}{
+			"id":          "6c2d3e4f-5a6b-4c7d-8e9f-0a1b2c3d4e5f",
+			"name":        "graphql idempotent user",
+			"email":       "graphql_idempotent@example.com",
+			"dateOfBirth": "1990-01-02",
+		},
+	}
+
+	headers := map[string]string{"Idempotency-Key": "graphql-create-idempotent-user"}
+
+	// the retry gets the created user instead of a conflict
+	for i := 0; i < 2; i++ {
+		status, response := sendGraphQL(t, tApp, createUser, variables, headers)
+
+		if status != fiber.StatusOK || len(response.Errors) != 0 {
+			t.Fatalf("Result is different from expected. Status: %v. Errors: %+v. Attempt: %v", status, response.Errors, i)
+		}
+
+		user := response.Data["createUser"].(map[string]interface{})
+
+		if user["id"] != "6c2d3e4f-5a6b-4c7d-8e9f-0a1b2c3d4e5f" {
+			t.Fatalf("Result is different from expected. Result: %v. Attempt: %v", user["id"], i)
+		}
+	}
+
+	// the mutation takes from the bucket of the POST /users routes
+	status, _ := sendJSON(t, tApp, "POST", "/api/v1/users", map[string]interface{}{
+		"id":            "6c2d3e4f-5a6b-4c7d-8e9f-0a1b2c3d4e60",
+		"name":          "graphql limited user",
+		"email":         "graphql_limited@example.com",
+		"date_of_birth": "1990-01-02",
+	}, nil)
+
+	if status != fiber.StatusTooManyRequests {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status, fiber.StatusTooManyRequests)
+	}
+
+	status, response := sendGraphQL(t, tApp, createUser, variables, nil)
+
+	if status != fiber.StatusOK || len(response.Errors) != 1 || response.Errors[0].Extensions["code"] != "TOO_MANY_REQUESTS" {
+		t.Fatalf("Result is different from expected. Status: %v. Errors: %+v", status, response.Errors)
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"github.com/LucasAndFlores/user_api/config"
+	"github.com/LucasAndFlores/user_api/internal/rpc"
+	userv1 "github.com/LucasAndFlores/user_api/proto/user/v1"
+	"github.com/LucasAndFlores/user_api/routes"
+	"google.golang.org/grpc"
+	"gorm.io/gorm"
+)
+
+func SetupGRPC(db *gorm.DB, cfg *config.Config) (*grpc.Server, error) {
+	deps, err := routes.NewDependencies(cfg, db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	server := grpc.NewServer(grpc.UnaryInterceptor(rpc.TokenAuth(cfg.GRPC.Token)))
+
+	userv1.RegisterUserServiceServer(server, rpc.NewUserServer(routes.NewUserService(db, deps), deps.Validator))
+
+	return server, nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/config"
+	"github.com/LucasAndFlores/user_api/database"
+	userv1 "github.com/LucasAndFlores/user_api/proto/user/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const GRPC_AUTH_TOKEN = "grpc-token-with-at-least-32-bytes!!"
+
+func runTestGRPCServer(t *testing.T) userv1.UserServiceClient {
+	t.Helper()
+
+	runTestServer()
+
+	t.Setenv("GRPC_AUTH_TOKEN", GRPC_AUTH_TOKEN)
+
+	cfg, err := config.Load()
+
+	if err != nil {
+		t.Fatalf("Failed to load the configuration: %v", err)
+	}
+
+	db, err := database.ConnectDatabase()
+
+	if err != nil {
+		t.Fatalf("Failed to connect to the database: %v", err)
+	}
+
+	server, err := SetupGRPC(db, cfg)
+
+	if err != nil {
+		t.Fatalf("Failed to setup the gRPC server: %v", err)
+	}
+
+	listener := bufconn.Listen(1024 * 1024)
+
+	go server.Serve(listener)
+
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+
+	if err != nil {
+		t.Fatalf("Failed to dial the gRPC server: %v", err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	return userv1.NewUserServiceClient(conn)
+}
+
+func authenticated() context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+GRPC_AUTH_TOKEN)
+}
+
+func TestGRPCUserScenario(t *testing.T) {
+	client := runTestGRPCServer(t)
+
+	ctx := authenticated()
+
+	_, err := client.Get(context.Background(), &userv1.GetRequest{Id: "8269b23f-1417-4f9d-9662-83b609a4e6dd"})
+
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status.Code(err), codes.Unauthenticated)
+	}
+
+	created, err := client.Create(ctx, &userv1.CreateRequest{
+		Id:          "6d3f9a2b-8c4e-4f70-a1b2-d3e4f5a6b7c8",
+		Name:        "grpc user",
+		Email:       "grpc@example.com",
+		DateOfBirth: "1990-01-02T00:00:00Z",
+	})
+
+	if err != nil {
+		t.Fatalf("Failed to create the user: %v", err)
+	}
+
+	if created.GetUser().GetVersion() != 1 {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", created.GetUser().GetVersion(), 1)
+	}
+
+	_, err = client.Create(ctx, &userv1.CreateRequest{
+		Id:          "6d3f9a2b-8c4e-4f70-a1b2-d3e4f5a6b7c8",
+		Name:        "grpc user",
+		Email:       "grpc@example.com",
+		DateOfBirth: "1990-01-02T00:00:00Z",
+	})
+
+	if status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status.Code(err), codes.AlreadyExists)
+	}
+
+	stale := int64(7)
+
+	_, err = client.Update(ctx, &userv1.UpdateRequest{
+		Id:              created.GetUser().GetId(),
+		Name:            "renamed grpc user",
+		Email:           "grpc@example.com",
+		DateOfBirth:     "1990-01-02T00:00:00Z",
+		ExpectedVersion: &stale,
+	})
+
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status.Code(err), codes.FailedPrecondition)
+	}
+
+	updated, err := client.Update(ctx, &userv1.UpdateRequest{
+		Id:          created.GetUser().GetId(),
+		Name:        "renamed grpc user",
+		Email:       "grpc@example.com",
+		DateOfBirth: "1990-01-02T00:00:00Z",
+	})
+
+	if err != nil {
+		t.Fatalf("Failed to update the user: %v", err)
+	}
+
+	if updated.GetUser().GetName() != "renamed grpc user" {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", updated.GetUser().GetName(), "renamed grpc user")
+	}
+
+	list, err := client.List(ctx, &userv1.ListRequest{PageSize: 1})
+
+	if err != nil {
+		t.Fatalf("Failed to list the users: %v", err)
+	}
+
+	if len(list.GetUsers()) != 1 {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", len(list.GetUsers()), 1)
+	}
+
+	if !updated.GetUser().GetUpdatedAt().AsTime().After(created.GetUser().GetCreatedAt().AsTime()) {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: after %v", updated.GetUser().GetUpdatedAt(), created.GetUser().GetCreatedAt())
+	}
+
+	synced, err := client.List(ctx, &userv1.ListRequest{PageSize: 100, UpdatedSince: updated.GetUser().GetUpdatedAt()})
+
+	if err != nil {
+		t.Fatalf("Failed to list the users: %v", err)
+	}
+
+	found := false
+
+	for _, user := range synced.GetUsers() {
+		found = found || (user.GetId() == created.GetUser().GetId() && user.GetVersion() == 2)
+	}
+
+	if !found {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: the updated user", synced.GetUsers())
+	}
+
+	synced, err = client.List(ctx, &userv1.ListRequest{UpdatedSince: timestamppb.New(time.Now().Add(time.Hour))})
+
+	if err != nil || len(synced.GetUsers()) != 0 {
+		t.Fatalf("Result is different from expected. Result: %v %v. Expected: no user", synced.GetUsers(), err)
+	}
+
+	_, err = client.Delete(ctx, &userv1.DeleteRequest{Id: created.GetUser().GetId()})
+
+	if err != nil {
+		t.Fatalf("Failed to delete the user: %v", err)
+	}
+
+	_, err = client.Get(ctx, &userv1.GetRequest{Id: created.GetUser().GetId()})
+
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status.Code(err), codes.NotFound)
+	}
+}
+
+func TestGRPCFieldViolationsScenario(t *testing.T) {
+	client := runTestGRPCServer(t)
+
+	_, err := client.Create(authenticated(), &userv1.CreateRequest{
+		Id:          "not-a-uuid",
+		Name:        "grpc user",
+		Email:       "not an email",
+		DateOfBirth: "1990-01-02T00:00:00Z",
+	})
+
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status.Code(err), codes.InvalidArgument)
+	}
+
+	var fields []string
+
+	for _, detail := range status.Convert(err).Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, violation := range badRequest.GetFieldViolations() {
+				fields = append(fields, violation.GetField())
+			}
+		}
+	}
+
+	if len(fields) != 2 || fields[0] != "email" || fields[1] != "id" {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", fields, []string{"email", "id"})
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/LucasAndFlores/user_api/database"
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestIdempotentCreateUserScenario(t *testing.T) {
+	tApp := runTestServer()
+
+	body := map[string]interface{}{
+		"name":          "idempotent user",
+		"email":         "idempotent@example.com",
+		"id":            "0e9b5c6d-2f1a-4bd8-a3c4-9fa0b1c2d3e0",
+		"date_of_birth": "1990-01-01T00:00:00Z",
+	}
+
+	headers := map[string]string{"Idempotency-Key": "create-idempotent-user"}
+
+	for i := 0; i < 2; i++ {
+		request, err := json.Marshal(body)
+
+		if err != nil {
+			t.Fatalf("Failed to marshal paylod to JSON: %v", err)
+		}
+
+		req := httptest.NewRequest("POST", "/api/save", bytes.NewReader(request))
+
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("Idempotency-Key", headers["Idempotency-Key"])
+
+		resp, err := tApp.Test(req, -1)
+
+		if err != nil {
+			t.Fatalf("Failed when trying to execute fiber.Test: %v", err)
+		}
+
+		value, err := io.ReadAll(resp.Body)
+
+		if err != nil {
+			t.Fatalf("Failed when trying to execute io.ReadAll: %v", err)
+		}
+
+		if resp.StatusCode != fiber.StatusCreated {
+			t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Attempt: %v", resp.StatusCode, fiber.StatusCreated, i)
+		}
+
+		expectedBody := "{\"message\":\"user successfully created\"}"
+
+		if string(value) != expectedBody {
+			t.Fatalf("The body result is different from expected. Result: %v. Expected: %v. Attempt: %v", string(value), expectedBody, i)
+		}
+
+		if i == 1 && resp.Header.Get("Idempotent-Replayed") != "true" {
+			t.Fatalf("The retry was not replayed from the stored response")
+		}
+	}
+
+	body["name"] = "another name"
+
+	status, rBody := sendJSON(t, tApp, "POST", "/api/save", body, headers)
+
+	expectedBody := "{\"message\":\"idempotency key was already used with a different payload\"}"
+
+	if status != fiber.StatusUnprocessableEntity || string(rBody) != expectedBody {
+		t.Fatalf("Result is different from expected. Result: %v %v", status, string(rBody))
+	}
+
+	// without the header the duplicate is still a conflict
+	body["name"] = "idempotent user"
+
+	status, _ = sendJSON(t, tApp, "POST", "/api/save", body, nil)
+
+	if status != fiber.StatusConflict {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status, fiber.StatusConflict)
+	}
+}
+
+func TestIdempotencyLeaseScenario(t *testing.T) {
+	tApp := runTestServer()
+
+	body := map[string]interface{}{
+		"name":          "leased user",
+		"email":         "leased@example.com",
+		"id":            "1fac6d7e-3a2b-4ce9-b4d5-0a1b2c3d4e5f",
+		"date_of_birth": "1990-01-01T00:00:00Z",
+	}
+
+	headers := map[string]string{"Idempotency-Key": "create-leased-user"}
+
+	status, rBody := sendJSON(t, tApp, "POST", "/api/save", body, headers)
+
+	if status != fiber.StatusCreated {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusCreated, string(rBody))
+	}
+
+	db, err := database.ConnectDatabase()
+
+	if err != nil {
+		t.Fatalf("An error occurred when tried to connect to database: %v", err)
+	}
+
+	// as if the instance handling the request had crashed before storing it
+	for _, lease := range []struct {
+		lockedUntil  string
+		expectedBody string
+	}{
+		{"now() + interval '1 hour'", "{\"message\":\"a request with the same idempotency key is in progress\"}"},
+		{"now() - interval '1 second'", "{\"message\":\"user already exists\"}"},
+	} {
+		err = db.Exec("UPDATE idempotency_keys SET status = ?, locked_until = "+lease.lockedUntil+" WHERE key = ?", model.IDEMPOTENCY_PROCESSING, headers["Idempotency-Key"]).Error
+
+		if err != nil {
+			t.Fatalf("Failed to update the idempotency key: %v", err)
+		}
+
+		status, rBody = sendJSON(t, tApp, "POST", "/api/save", body, headers)
+
+		if status != fiber.StatusConflict || string(rBody) != lease.expectedBody {
+			t.Fatalf("Result is different from expected. Result: %v %v. Expected: %v", status, string(rBody), lease.expectedBody)
+		}
+	}
+}
@@ -1,9 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
-	"os"
+	"net"
 
 	"github.com/LucasAndFlores/user_api/config"
 	"github.com/LucasAndFlores/user_api/database"
@@ -12,8 +13,6 @@ import (
 	"gorm.io/gorm"
 )
 
-var PORT = fmt.Sprintf(":%v", os.Getenv("PORT"))
-
 func main() {
 	err := config.LoadEnvVariables()
 
@@ -21,24 +20,86 @@ func main() {
 		log.Fatalf("An error occurred when tried to load env variables: %v", err)
 	}
 
+	cfg, err := config.Load()
+
+	if err != nil {
+		log.Fatalf("An error occurred when tried to load the configuration: %v", err)
+	}
+
 	db, err := database.ConnectDatabase()
 
 	if err != nil {
 		log.Fatalf("An error occurred when tried to connect to database: %v", err)
 	}
 
-	app := SetupApp(db)
+	app, err := SetupApp(db, cfg)
+
+	if err != nil {
+		log.Fatalf("An error occurred when tried to setup the app: %v", err)
+	}
+
+	if cfg.GRPC.Port != "" {
+		grpcServer, err := SetupGRPC(db, cfg)
+
+		if err != nil {
+			log.Fatalf("An error occurred when tried to setup the gRPC server: %v", err)
+		}
+
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%v", cfg.GRPC.Port))
+
+		if err != nil {
+			log.Fatalf("An error occurred when tried to listen on the gRPC port: %v", err)
+		}
+
+		go func() {
+			err := grpcServer.Serve(listener)
+
+			if err != nil {
+				log.Fatalf("An error occurred when tried to serve gRPC: %v", err)
+			}
+		}()
+	}
+
+	if cfg.Outbox.RelayEnabled {
+		relay, err := routes.NewRelay(db, cfg.Outbox)
+
+		if err != nil {
+			log.Fatalf("An error occurred when tried to setup the outbox relay: %v", err)
+		}
+
+		go relay.Run(context.Background())
+	}
+
+	if cfg.Webhook.DispatcherEnabled {
+		go routes.NewDispatcher(db, cfg.Webhook).Run(context.Background())
+	}
+
+	if cfg.PII.ReencryptEnabled {
+		go routes.NewReencryptor(db, cfg.PII).Run(context.Background())
+	}
 
-	app.Listen(PORT)
+	app.Listen(fmt.Sprintf(":%v", cfg.Port))
 }
 
-func SetupApp(db *gorm.DB) *fiber.App {
+func SetupApp(db *gorm.DB, cfg *config.Config) (*fiber.App, error) {
+	deps, err := routes.NewDependencies(cfg, db)
 
-	app := fiber.New()
+	if err != nil {
+		return nil, err
+	}
 
-	router := app.Group("/api")
+	app := fiber.New(fiber.Config{
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          cfg.HTTP.TrustedProxies,
+		ProxyHeader:             cfg.HTTP.ProxyHeader,
+		EnableIPValidation:      true,
+	})
 
-	routes.SetupUserRoutes(router, db)
+	err = routes.Setup(app, db, deps)
+
+	if err != nil {
+		return nil, err
+	}
 
-	return app
+	return app, nil
 }
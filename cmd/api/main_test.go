@@ -8,9 +8,13 @@ import (
 	"log"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/LucasAndFlores/user_api/config"
 	"github.com/LucasAndFlores/user_api/database"
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/LucasAndFlores/user_api/internal/pii"
 	"github.com/gofiber/fiber/v2"
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
@@ -22,10 +26,16 @@ const (
 	POSTGRES_DB   = "my_db_test"
 	POSTGRES_USER = "postgres"
 	DB_HOST       = "localhost"
+
+	AUTH_TOKEN_SECRET = "test-secret-with-at-least-32-bytes!!"
 )
 
 var DB_PORT string
 
+var MAIL_FILE_DIR = filepath.Join(os.TempDir(), "user_api_test_mail")
+
+var PII_KEYRING_FILE = filepath.Join(os.TempDir(), "user_api_test_keyring.json")
+
 func TestMain(t *testing.M) {
 	pool, err := dockertest.NewPool("")
 
@@ -77,6 +87,12 @@ func TestMain(t *testing.M) {
 		panic("Could not connect to postgres: " + err.Error())
 	}
 
+	err = writeKeyring(PII_KEYRING_FILE)
+
+	if err != nil {
+		log.Fatalf("Could not write the keyring: %s", err)
+	}
+
 	code := t.Run()
 
 	os.Exit(code)
@@ -87,6 +103,13 @@ func runTestServer() *fiber.App {
 	os.Setenv("POSTGRES_USER", POSTGRES_USER)
 	os.Setenv("POSTGRES_DB", POSTGRES_DB)
 	os.Setenv("DB_PORT", DB_PORT)
+	os.Setenv("AUTH_TOKEN_SECRET", AUTH_TOKEN_SECRET)
+	os.Setenv("AUTH_BCRYPT_COST", "4")
+	os.Setenv("MAIL_DRIVER", "file")
+	os.Setenv("MAIL_FILE_DIR", MAIL_FILE_DIR)
+	os.Setenv("RATE_LIMIT_CREATE_USER", "1000/1m")
+	os.Setenv("OPENAPI_VALIDATE_RESPONSES", "true")
+	os.Setenv("PII_KEYRING_FILE", PII_KEYRING_FILE)
 
 	db, err := database.ConnectDatabase()
 
@@ -94,7 +117,35 @@ func runTestServer() *fiber.App {
 		log.Fatalf("An error occurred when tried to connect to database: %v", err)
 	}
 
-	return SetupApp(db)
+	cfg, err := config.Load()
+
+	if err != nil {
+		log.Fatalf("An error occurred when tried to load the configuration: %v", err)
+	}
+
+	app, err := SetupApp(db, cfg)
+
+	if err != nil {
+		log.Fatalf("An error occurred when tried to setup the app: %v", err)
+	}
+
+	return app
+}
+
+func writeKeyring(path string) error {
+	keyring, err := pii.GenerateKeyring()
+
+	if err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(keyring)
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, content, 0600)
 }
 
 func TestCreateUserSuccessfulScenario(t *testing.T) {
@@ -271,17 +322,37 @@ func TestCreateUserInvalidRequestScenario(t *testing.T) {
 				"date_of_birth": "",
 			},
 			expectedStatusCode: fiber.StatusUnprocessableEntity,
-			expectedBody:       "[{\"Field\":\"DateOfBirth\",\"Tag\":\"required\",\"Value\":\"invalid date format\"},{\"Field\":\"DateOfBirth\",\"Tag\":\"required\",\"Value\":\"\"}]",
+			expectedBody:       "[{\"Field\":\"DateOfBirth\",\"Tag\":\"required\",\"Value\":\"\"}]",
+		},
+		{
+			request: map[string]interface{}{
+				"name":          "test user",
+				"email":         "user2@example.com",
+				"id":            "3a47386e-56d4-4bd8-a015-c2b8bdf646f8",
+				"date_of_birth": "01/01/1990",
+			},
+			expectedStatusCode: fiber.StatusUnprocessableEntity,
+			expectedBody:       "[{\"Field\":\"DateOfBirth\",\"Tag\":\"date\",\"Value\":\"\"}]",
+		},
+		{
+			request: map[string]interface{}{
+				"name":          "test user",
+				"email":         "user2@example.com",
+				"id":            "3a47386e-56d4-4bd8-a015-c2b8bdf646f8",
+				"date_of_birth": "2999-01-01",
+			},
+			expectedStatusCode: fiber.StatusUnprocessableEntity,
+			expectedBody:       "[{\"Field\":\"DateOfBirth\",\"Tag\":\"date_of_birth\",\"Value\":\"\"}]",
 		},
 		{
 			request: map[string]interface{}{
 				"name":          "test user",
 				"email":         "user2@example.com",
 				"id":            "3a47386e-56d4-4bd8-a015-c2b8bdf646f8",
-				"date_of_birth": "1990-01-01",
+				"date_of_birth": "1800-01-01",
 			},
 			expectedStatusCode: fiber.StatusUnprocessableEntity,
-			expectedBody:       "[{\"Field\":\"DateOfBirth\",\"Tag\":\"required\",\"Value\":\"invalid date format\"}]",
+			expectedBody:       "[{\"Field\":\"DateOfBirth\",\"Tag\":\"date_of_birth\",\"Value\":\"\"}]",
 		},
 	}
 
@@ -321,6 +392,34 @@ func TestCreateUserInvalidRequestScenario(t *testing.T) {
 
 }
 
+func TestCreateUserMalformedBodyScenario(t *testing.T) {
+	ts := runTestServer()
+
+	for _, url := range []string{"/api/save", "/api/v1/users"} {
+		req := httptest.NewRequest("POST", url, bytes.NewReader([]byte(`{"name": "test user",`)))
+
+		req.Header.Set("content-type", "application/json")
+
+		resp, err := ts.Test(req, -1)
+
+		if err != nil {
+			t.Fatalf("Failed when trying to execute fiber.Test: %v", err)
+		}
+
+		rBody, err := io.ReadAll(resp.Body)
+
+		if err != nil {
+			t.Fatalf("Failed when trying to execute io.ReadAll: %v", err)
+		}
+
+		expectedBody := "{\"message\":\"unable to parse the body\"}"
+
+		if resp.StatusCode != fiber.StatusBadRequest || string(rBody) != expectedBody {
+			t.Fatalf("Result is different from expected. Result: %v %v. Expected: %v %v", resp.StatusCode, string(rBody), fiber.StatusBadRequest, expectedBody)
+		}
+	}
+}
+
 func TestFindUserByIdSuccessfulScenario(t *testing.T) {
 	tApp := runTestServer()
 
@@ -367,7 +466,24 @@ func TestFindUserByIdSuccessfulScenario(t *testing.T) {
 		t.Fatalf("The result is different from expected. Result: %v. Expected: %v", res.StatusCode, fiber.StatusOK)
 	}
 
-	expectedBody := "{\"user\":{\"name\":\"test user\",\"email\":\"test_create@example.com\",\"id\":\"d553a9de-eff6-4b3d-9c70-8c9266692782\",\"date_of_birth\":\"1990-01-01 01:00:00 +0100 CET\"}}"
+	db, err := database.ConnectDatabase()
+
+	if err != nil {
+		t.Fatalf("An error occurred when tried to connect to database: %v", err)
+	}
+
+	var stored model.User
+
+	err = db.Where("external_id = ?", createdUser["id"]).Take(&stored).Error
+
+	if err != nil {
+		t.Fatalf("Failed to read the stored user: %v", err)
+	}
+
+	createdAt, _ := json.Marshal(stored.CreatedAt)
+	updatedAt, _ := json.Marshal(stored.UpdatedAt)
+
+	expectedBody := fmt.Sprintf("{\"user\":{\"name\":\"test user\",\"email\":\"t***@example.com\",\"id\":\"d553a9de-eff6-4b3d-9c70-8c9266692782\",\"date_of_birth\":\"1990\",\"version\":1,\"created_at\":%s,\"updated_at\":%s}}", createdAt, updatedAt)
 
 	if string(value) != expectedBody {
 		t.Fatalf("The body result is different from expected. Result: %v. Expected: %v", string(value), expectedBody)
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/database"
+	"github.com/LucasAndFlores/user_api/internal/auth"
+	"github.com/LucasAndFlores/user_api/internal/pii"
+	"github.com/gofiber/fiber/v2"
+)
+
+// nextTOTPCode returns the code of the next time step, which is still inside
+// the accepted skew but newer than any step used so far in the test.
+func nextTOTPCode(t *testing.T, secret string) string {
+	t.Helper()
+
+	code, err := auth.TOTPCode(secret, auth.TOTPStep(time.Now())+1)
+
+	if err != nil {
+		t.Fatalf("Failed to generate the totp code: %v", err)
+	}
+
+	return code
+}
+
+func enableTOTP(t *testing.T, app *fiber.App, accessToken interface{}) (string, []interface{}) {
+	t.Helper()
+
+	headers := map[string]string{"Authorization": fmt.Sprintf("Bearer %v", accessToken)}
+
+	status, body := sendJSON(t, app, "POST", "/api/auth/mfa/totp/enroll", nil, headers)
+
+	if status != fiber.StatusCreated {
+		t.Fatalf("Failed to enroll totp. Status: %v. Body: %v", status, string(body))
+	}
+
+	var enrollment map[string]interface{}
+
+	json.Unmarshal(body, &enrollment)
+
+	secret := enrollment["secret"].(string)
+
+	if !strings.HasPrefix(enrollment["provisioning_uri"].(string), "otpauth://totp/") {
+		t.Fatalf("Unexpected provisioning uri: %v", enrollment["provisioning_uri"])
+	}
+
+	code, err := auth.TOTPCode(secret, auth.TOTPStep(time.Now()))
+
+	if err != nil {
+		t.Fatalf("Failed to generate the totp code: %v", err)
+	}
+
+	status, body = sendJSON(t, app, "POST", "/api/auth/mfa/totp/confirm", map[string]interface{}{"code": code}, headers)
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Failed to confirm totp. Status: %v. Body: %v", status, string(body))
+	}
+
+	var confirmation map[string]interface{}
+
+	json.Unmarshal(body, &confirmation)
+
+	return secret, confirmation["recovery_codes"].([]interface{})
+}
+
+func loginWithMFA(t *testing.T, app *fiber.App, email string, code string) (int, map[string]interface{}) {
+	t.Helper()
+
+	status, body := sendJSON(t, app, "POST", "/api/auth/login", map[string]interface{}{"email": email, "password": TEST_PASSWORD}, nil)
+
+	var challenge map[string]interface{}
+
+	json.Unmarshal(body, &challenge)
+
+	if status != fiber.StatusOK || challenge["mfa_required"] != true {
+		t.Fatalf("The login did not ask for mfa. Status: %v. Body: %v", status, string(body))
+	}
+
+	status, body = sendJSON(t, app, "POST", "/api/auth/login/mfa", map[string]interface{}{"mfa_token": challenge["mfa_token"], "code": code}, nil)
+
+	var tokens map[string]interface{}
+
+	json.Unmarshal(body, &tokens)
+
+	return status, tokens
+}
+
+func TestTOTPLoginScenario(t *testing.T) {
+	tApp := runTestServer()
+
+	createUserWithPassword(t, tApp, "totp@example.com", "9d8a4b5c-1e0f-4ac7-92b3-8e9fa0b1c2d9")
+
+	tokens := login(t, tApp, "totp@example.com", TEST_PASSWORD)
+
+	secret, recoveryCodes := enableTOTP(t, tApp, tokens["access_token"])
+
+	if len(recoveryCodes) != 10 {
+		t.Fatalf("Unexpected number of recovery codes: %v", len(recoveryCodes))
+	}
+
+	status, _ := loginWithMFA(t, tApp, "totp@example.com", "000000")
+
+	if status != fiber.StatusUnauthorized {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status, fiber.StatusUnauthorized)
+	}
+
+	code := nextTOTPCode(t, secret)
+
+	status, mfaTokens := loginWithMFA(t, tApp, "totp@example.com", code)
+
+	if status != fiber.StatusOK || mfaTokens["access_token"] == nil {
+		t.Fatalf("Result is different from expected. Result: %v. Body: %v", status, mfaTokens)
+	}
+
+	// the same code can't be replayed
+	status, _ = loginWithMFA(t, tApp, "totp@example.com", code)
+
+	if status != fiber.StatusUnauthorized {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status, fiber.StatusUnauthorized)
+	}
+
+	recoveryCode := recoveryCodes[0].(string)
+
+	status, _ = loginWithMFA(t, tApp, "totp@example.com", recoveryCode)
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status, fiber.StatusOK)
+	}
+
+	status, _ = loginWithMFA(t, tApp, "totp@example.com", recoveryCode)
+
+	if status != fiber.StatusUnauthorized {
+		t.Fatalf("A recovery code must be single use. Result: %v", status)
+	}
+}
+
+func TestMFALoginAttemptsScenario(t *testing.T) {
+	t.Setenv("RATE_LIMIT_MFA_LOGIN", "6/1h")
+
+	tApp := runTestServer()
+
+	id := "0e1f2a3b-4c5d-4e6f-8a7b-9c0d1e2f3a4b"
+
+	createUserWithPassword(t, tApp, "totp_attempts@example.com", id)
+
+	secret, _ := enableTOTP(t, tApp, login(t, tApp, "totp_attempts@example.com", TEST_PASSWORD)["access_token"])
+
+	db, err := database.ConnectDatabase()
+
+	if err != nil {
+		t.Fatalf("An error occurred when tried to connect to database: %v", err)
+	}
+
+	var stored string
+
+	db.Raw("SELECT secret FROM totp_credentials JOIN users ON users.id = totp_credentials.user_id WHERE users.external_id = ?", id).Scan(&stored)
+
+	if !strings.HasPrefix(stored, pii.KeyPrefix(pii.CurrentKeyId())) || strings.Contains(stored, secret) {
+		t.Fatalf("The totp secret is not encrypted with the current key: %v", stored)
+	}
+
+	_, body := sendJSON(t, tApp, "POST", "/api/auth/login", map[string]interface{}{"email": "totp_attempts@example.com", "password": TEST_PASSWORD}, nil)
+
+	var challenge map[string]interface{}
+
+	json.Unmarshal(body, &challenge)
+
+	for i := 0; i < 5; i++ {
+		status, _ := sendJSON(t, tApp, "POST", "/api/auth/login/mfa", map[string]interface{}{"mfa_token": challenge["mfa_token"], "code": "000000"}, nil)
+
+		if status != fiber.StatusUnauthorized {
+			t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Attempt: %v", status, fiber.StatusUnauthorized, i)
+		}
+	}
+
+	// the token is spent, even with the right code
+	status, body := sendJSON(t, tApp, "POST", "/api/auth/login/mfa", map[string]interface{}{"mfa_token": challenge["mfa_token"], "code": nextTOTPCode(t, secret)}, nil)
+
+	expectedBody := "{\"message\":\"too many attempts, log in again\"}"
+
+	if status != fiber.StatusUnauthorized || string(body) != expectedBody {
+		t.Fatalf("Result is different from expected. Result: %v %v. Expected: %v %v", status, string(body), fiber.StatusUnauthorized, expectedBody)
+	}
+
+	// a new token doesn't get around the limit of the user
+	status, _ = loginWithMFA(t, tApp, "totp_attempts@example.com", nextTOTPCode(t, secret))
+
+	if status != fiber.StatusTooManyRequests {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status, fiber.StatusTooManyRequests)
+	}
+}
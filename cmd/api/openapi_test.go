@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/LucasAndFlores/user_api/internal/openapi"
+	"github.com/gofiber/fiber/v2"
+)
+
+// TestOpenAPISpecMatchesRoutes fails when a v1 route is added, removed or
+// renamed without updating routes.NewDocument.
+func TestOpenAPISpecMatchesRoutes(t *testing.T) {
+	tApp := runTestServer()
+
+	status, body := sendJSON(t, tApp, "GET", "/openapi.json", nil, nil)
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status, fiber.StatusOK)
+	}
+
+	var doc openapi.Document
+
+	err := json.Unmarshal(body, &doc)
+
+	if err != nil {
+		t.Fatalf("Failed to unmarshal the document: %v", err)
+	}
+
+	documented := map[string]bool{}
+
+	for path, item := range doc.Paths {
+		for method := range item {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	registered := map[string]bool{}
+
+	for _, route := range tApp.GetRoutes(true) {
+		// HEAD is registered along with every GET
+		if !strings.HasPrefix(route.Path, "/api/v1/") || route.Method == fiber.MethodHead {
+			continue
+		}
+
+		registered[route.Method+" "+openapi.Path(strings.TrimSuffix(route.Path, "/"))] = true
+	}
+
+	var drift []string
+
+	for route := range registered {
+		if !documented[route] {
+			drift = append(drift, "undocumented route: "+route)
+		}
+	}
+
+	for route := range documented {
+		if !registered[route] {
+			drift = append(drift, "documented route is not registered: "+route)
+		}
+	}
+
+	sort.Strings(drift)
+
+	if len(drift) != 0 {
+		t.Fatalf("The OpenAPI document drifted from the routes:\n%v", strings.Join(drift, "\n"))
+	}
+
+	status, body = sendJSON(t, tApp, "GET", "/docs", nil, nil)
+
+	// the assets of Swagger UI are served by the API, not by a CDN
+	if status != fiber.StatusOK || strings.Contains(string(body), "https://") {
+		t.Fatalf("Result is different from expected. Result: %v. Body: %v", status, string(body))
+	}
+
+	for _, asset := range []string{"/docs/assets/swagger-ui.css", "/docs/assets/swagger-ui-bundle.js"} {
+		status, body = sendJSON(t, tApp, "GET", asset, nil, nil)
+
+		if status != fiber.StatusOK || len(body) == 0 {
+			t.Fatalf("The asset %v is missing, run make swagger-ui. Result: %v", asset, status)
+		}
+	}
+}
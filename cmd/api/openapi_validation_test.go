@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/LucasAndFlores/user_api/internal/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestOpenAPIRequestValidationScenario(t *testing.T) {
+	t.Setenv("OPENAPI_VALIDATE_REQUESTS", "true")
+
+	tApp := runTestServer()
+
+	testCases := []struct {
+		description    string
+		method         string
+		url            string
+		body           interface{}
+		expectedErrors []middleware.RequestBodyError
+	}{
+		{
+			description: "invalid user",
+			method:      "POST",
+			url:         "/api/v1/users",
+			body: map[string]interface{}{
+				"name":          "J",
+				"email":         "not an email",
+				"id":            "8269b23f-1417-4f9d-9662-83b609a4e6dd",
+				"date_of_birth": "1990-01-01T00:00:00Z",
+			},
+			expectedErrors: []middleware.RequestBodyError{
+				{Field: "email", Tag: "format", Value: "email"},
+				{Field: "name", Tag: "minLength", Value: "2"},
+			},
+		},
+		{
+			description: "missing field",
+			method:      "POST",
+			url:         "/api/v1/auth/login",
+			body:        map[string]interface{}{"email": "openapi@example.com"},
+			expectedErrors: []middleware.RequestBodyError{
+				{Field: "password", Tag: "required", Value: ""},
+			},
+		},
+		{
+			description: "missing query parameter",
+			method:      "GET",
+			url:         "/api/v1/auth/verify-email",
+			expectedErrors: []middleware.RequestBodyError{
+				{Field: "token", Tag: "required", Value: ""},
+			},
+		},
+	}
+
+	for _, value := range testCases {
+		t.Run(value.description, func(t *testing.T) {
+			status, body := sendJSON(t, tApp, value.method, value.url, value.body, nil)
+
+			if status != fiber.StatusUnprocessableEntity {
+				t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status, fiber.StatusUnprocessableEntity)
+			}
+
+			var errors []middleware.RequestBodyError
+
+			err := json.Unmarshal(body, &errors)
+
+			if err != nil {
+				t.Fatalf("Failed to unmarshal the body: %v", err)
+			}
+
+			if len(errors) != len(value.expectedErrors) {
+				t.Fatalf("Result is different from expected. Result: %+v. Expected: %+v", errors, value.expectedErrors)
+			}
+
+			for i, expected := range value.expectedErrors {
+				if errors[i] != expected {
+					t.Fatalf("Result is different from expected. Result: %+v. Expected: %+v", errors, value.expectedErrors)
+				}
+			}
+		})
+	}
+}
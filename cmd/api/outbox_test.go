@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/database"
+	"github.com/LucasAndFlores/user_api/internal/events"
+	"github.com/LucasAndFlores/user_api/internal/repository"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// flakyPublisher fails the first attempts of the events of some users.
+type flakyPublisher struct {
+	failures  map[uuid.UUID]int
+	published []events.Event
+}
+
+func (p *flakyPublisher) Publish(ctx context.Context, event events.Event) error {
+	if p.failures[event.AggregateId] > 0 {
+		p.failures[event.AggregateId]--
+
+		return errors.New("broker unavailable")
+	}
+
+	p.published = append(p.published, event)
+
+	return nil
+}
+
+func TestOutboxRelayScenario(t *testing.T) {
+	tApp := runTestServer()
+
+	id := "6d0e1f2a-3b4c-4d5e-9f6a-8b9c0d1e2fd5"
+
+	createUserWithPassword(t, tApp, "outbox@example.com", id)
+
+	owner := map[string]string{"Authorization": fmt.Sprintf("Bearer %v", login(t, tApp, "outbox@example.com", TEST_PASSWORD)["access_token"])}
+
+	url := fmt.Sprintf("/api/v1/users/%v", id)
+
+	status, body := sendJSON(t, tApp, "PUT", url, map[string]interface{}{
+		"name":          "outbox user",
+		"email":         "outbox@example.com",
+		"date_of_birth": "1991-02-03T00:00:00Z",
+	}, owner)
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusOK, string(body))
+	}
+
+	status, body = sendJSON(t, tApp, "DELETE", url, nil, owner)
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusOK, string(body))
+	}
+
+	db, err := database.ConnectDatabase()
+
+	if err != nil {
+		t.Fatalf("An error occurred when tried to connect to database: %v", err)
+	}
+
+	aggregate := uuid.MustParse(id)
+
+	publisher := &flakyPublisher{failures: map[uuid.UUID]int{aggregate: 2}}
+
+	relay := events.NewRelay(repository.NewOutboxRepository(db), repository.NewTransactor(db), publisher, events.RelayOptions{
+		Interval:   time.Millisecond,
+		BatchSize:  100,
+		MaxBackoff: time.Millisecond,
+		Retention:  time.Hour,
+	})
+
+	typesOf := func() []string {
+		var types []string
+
+		for _, event := range publisher.published {
+			if event.AggregateId == aggregate {
+				types = append(types, event.Type)
+			}
+		}
+
+		return types
+	}
+
+	// the outbox also holds the events of the other tests
+	for attempt := 0; attempt < 100 && len(typesOf()) < 4; attempt++ {
+		_, err := relay.RunOnce(context.Background())
+
+		if err != nil {
+			t.Fatalf("Failed to relay the outbox: %v", err)
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	types := typesOf()
+
+	// the verification of the email is the first update
+	expected := []string{events.USER_CREATED, events.USER_UPDATED, events.USER_UPDATED, events.USER_DELETED}
+
+	if fmt.Sprint(types) != fmt.Sprint(expected) {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", types, expected)
+	}
+
+	if publisher.failures[aggregate] != 0 {
+		t.Fatalf("The failing attempts were not retried. Remaining failures: %v", publisher.failures[aggregate])
+	}
+
+	// published events are not sent again
+	_, err = relay.RunOnce(context.Background())
+
+	if err != nil {
+		t.Fatalf("Failed to relay the outbox: %v", err)
+	}
+
+	if len(typesOf()) != len(expected) {
+		t.Fatalf("Published events were sent again. Result: %v. Expected: %v", typesOf(), expected)
+	}
+}
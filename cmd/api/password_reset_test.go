@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestPasswordResetScenario(t *testing.T) {
+	tApp := runTestServer()
+
+	createUserWithPassword(t, tApp, "reset@example.com", "8c7f3a4b-0d9e-4fb6-81a2-7d8e9fa0b1c8")
+
+	tokens := login(t, tApp, "reset@example.com", TEST_PASSWORD)
+
+	sent := len(mailsTo(t, "reset@example.com"))
+
+	status, body := sendJSON(t, tApp, "POST", "/api/auth/password/forgot", map[string]interface{}{"email": "reset@example.com"}, nil)
+
+	if status != fiber.StatusAccepted {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusAccepted, string(body))
+	}
+
+	resetToken := awaitMailToken(t, "reset@example.com", sent)
+
+	newPassword := "N3w-Str0ng-Passw0rd"
+
+	status, body = sendJSON(t, tApp, "POST", "/api/auth/password/reset", map[string]interface{}{"token": resetToken, "password": newPassword}, nil)
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusOK, string(body))
+	}
+
+	status, _ = sendJSON(t, tApp, "POST", "/api/auth/password/reset", map[string]interface{}{"token": resetToken, "password": newPassword}, nil)
+
+	if status != fiber.StatusBadRequest {
+		t.Fatalf("A reset token must be single use. Result: %v", status)
+	}
+
+	status, _ = sendJSON(t, tApp, "POST", "/api/auth/refresh", map[string]interface{}{"refresh_token": tokens["refresh_token"]}, nil)
+
+	if status != fiber.StatusUnauthorized {
+		t.Fatalf("The sessions were not revoked by the reset. Result: %v", status)
+	}
+
+	owner := map[string]string{"Authorization": fmt.Sprintf("Bearer %v", login(t, tApp, "reset@example.com", newPassword)["access_token"])}
+
+	// the creation, the verification of the email and the reset
+	status, body = sendJSON(t, tApp, "GET", "/api/v1/users/8c7f3a4b-0d9e-4fb6-81a2-7d8e9fa0b1c8/versions", nil, owner)
+
+	var page userVersions
+
+	json.Unmarshal(body, &page)
+
+	if status != fiber.StatusOK || len(page.Versions) != 3 || page.Versions[2].Version != 3 {
+		t.Fatalf("Result is different from expected. Result: %v %v. Expected: a version recorded by the reset", status, string(body))
+	}
+}
+
+func TestForgotPasswordScenario(t *testing.T) {
+	tApp := runTestServer()
+
+	expectedBody := "{\"message\":\"if the email is registered, a password reset email was sent\"}"
+
+	for i := 0; i < 3; i++ {
+		status, body := sendJSON(t, tApp, "POST", "/api/auth/password/forgot", map[string]interface{}{"email": "unknown_reset@example.com"}, nil)
+
+		if status != fiber.StatusAccepted || string(body) != expectedBody {
+			t.Fatalf("Result is different from expected. Result: %v %v. Attempt: %v", status, string(body), i)
+		}
+	}
+
+	status, _ := sendJSON(t, tApp, "POST", "/api/auth/password/forgot", map[string]interface{}{"email": "unknown_reset@example.com"}, nil)
+
+	if status != fiber.StatusTooManyRequests {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status, fiber.StatusTooManyRequests)
+	}
+}
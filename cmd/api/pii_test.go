@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/config"
+	"github.com/LucasAndFlores/user_api/database"
+	"github.com/LucasAndFlores/user_api/internal/pii"
+	"github.com/LucasAndFlores/user_api/routes"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type storedPII struct {
+	Email       string
+	EmailIndex  string
+	DateOfBirth string
+}
+
+func findStoredPII(t *testing.T, db *gorm.DB, id string) storedPII {
+	t.Helper()
+
+	var stored storedPII
+
+	err := db.Raw("SELECT email, email_index, date_of_birth FROM users WHERE external_id = ?", id).Scan(&stored).Error
+
+	if err != nil {
+		t.Fatalf("Failed to read the stored user: %v", err)
+	}
+
+	return stored
+}
+
+func rotateKeyring(t *testing.T) {
+	t.Helper()
+
+	content, err := os.ReadFile(PII_KEYRING_FILE)
+
+	if err != nil {
+		t.Fatalf("Failed to read the keyring: %v", err)
+	}
+
+	var keyring pii.Keyring
+
+	json.Unmarshal(content, &keyring)
+
+	// the ids of the keys have a precision of a second
+	time.Sleep(time.Second)
+
+	err = keyring.Rotate()
+
+	if err != nil {
+		t.Fatalf("Failed to rotate the keyring: %v", err)
+	}
+
+	content, _ = json.Marshal(keyring)
+
+	err = os.WriteFile(PII_KEYRING_FILE, content, 0600)
+
+	if err != nil {
+		t.Fatalf("Failed to write the keyring: %v", err)
+	}
+}
+
+func TestPIIEncryptionScenario(t *testing.T) {
+	tApp, admin := runTestServerWithAdmin(t, "pii_admin@example.com", "5c6d7e8f-9a0b-4c1d-8e2f-3a4b5c6d7e81")
+
+	id := "6d7e8f9a-0b1c-4d2e-9f3a-4b5c6d7e8f92"
+
+	createUserWithPassword(t, tApp, "pii_user@example.com", id)
+
+	db, err := database.ConnectDatabase()
+
+	if err != nil {
+		t.Fatalf("An error occurred when tried to connect to database: %v", err)
+	}
+
+	stored := findStoredPII(t, db, id)
+
+	for _, value := range []string{stored.Email, stored.DateOfBirth} {
+		if !strings.HasPrefix(value, pii.KeyPrefix(pii.CurrentKeyId())) || strings.Contains(value, "pii_user") || strings.Contains(value, "1990") {
+			t.Fatalf("The value is not encrypted with the current key: %v", value)
+		}
+	}
+
+	var payload string
+
+	err = db.Raw("SELECT payload FROM outbox_events WHERE aggregate_id = ? ORDER BY id LIMIT 1", id).Scan(&payload).Error
+
+	if err != nil || payload == "" || strings.Contains(payload, "pii_user") || strings.Contains(payload, "1990") {
+		t.Fatalf("The payload of the event is not encrypted: %v %v", payload, err)
+	}
+
+	if stored.EmailIndex != pii.BlindIndex("pii_user@example.com") {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", stored.EmailIndex, pii.BlindIndex("pii_user@example.com"))
+	}
+
+	// the blind index finds the email regardless of case
+	status, body := sendJSON(t, tApp, "GET", "/api/v1/users?email=PII_USER@example.com", nil, admin)
+
+	var list struct {
+		Users []map[string]interface{} `json:"users"`
+	}
+
+	json.Unmarshal(body, &list)
+
+	if status != fiber.StatusOK || len(list.Users) != 1 || list.Users[0]["email"] != "pii_user@example.com" {
+		t.Fatalf("Result is different from expected. Result: %v. Body: %v", status, string(body))
+	}
+
+	status, body = sendJSON(t, tApp, "POST", "/api/v1/users", map[string]interface{}{
+		"name":          "pii duplicate",
+		"email":         "Pii_User@example.com",
+		"id":            "7e8f9a0b-1c2d-4e3f-8a4b-5c6d7e8f9aa3",
+		"date_of_birth": "1990-01-01T00:00:00Z",
+	}, nil)
+
+	if status != fiber.StatusConflict {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusConflict, string(body))
+	}
+
+	previousKeyId := pii.CurrentKeyId()
+
+	rotateKeyring(t)
+
+	tApp = runTestServer()
+
+	db, err = database.ConnectDatabase()
+
+	if err != nil {
+		t.Fatalf("An error occurred when tried to connect to database: %v", err)
+	}
+
+	if pii.CurrentKeyId() == previousKeyId {
+		t.Fatalf("The keyring was not rotated: %v", previousKeyId)
+	}
+
+	reencryptor := routes.NewReencryptor(db, config.PIIConfig{ReencryptInterval: time.Millisecond, ReencryptBatchSize: 100})
+
+	for attempt := 0; attempt < 100; attempt++ {
+		rewritten, err := reencryptor.RunOnce(context.Background())
+
+		if err != nil {
+			t.Fatalf("Failed to re-encrypt the pii: %v", err)
+		}
+
+		if rewritten == 0 {
+			break
+		}
+	}
+
+	rotated := findStoredPII(t, db, id)
+
+	if !strings.HasPrefix(rotated.Email, pii.KeyPrefix(pii.CurrentKeyId())) || rotated.Email == stored.Email || rotated.EmailIndex != stored.EmailIndex {
+		t.Fatalf("The email was not re-encrypted with the new key: %v", rotated)
+	}
+
+	status, body = sendJSON(t, tApp, "GET", "/api/v1/users/"+id, nil, admin)
+
+	var found struct {
+		User map[string]interface{} `json:"user"`
+	}
+
+	json.Unmarshal(body, &found)
+
+	if status != fiber.StatusOK || found.User["email"] != "pii_user@example.com" || found.User["date_of_birth"] != "1990-01-01" {
+		t.Fatalf("Result is different from expected. Result: %v. Body: %v", status, string(body))
+	}
+
+	login(t, tApp, "pii_user@example.com", TEST_PASSWORD)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/LucasAndFlores/user_api/config"
+	"github.com/LucasAndFlores/user_api/internal/auth"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRateLimitScenario(t *testing.T) {
+	for _, store := range []string{"memory", "postgres"} {
+		t.Run(store, func(t *testing.T) {
+			// different API keys per store, so the shared postgres buckets
+			// of other runs don't interfere
+			issued := "rate-limit-test-" + store
+			another := "another-client-" + store
+
+			t.Setenv("RATE_LIMIT_STORE", store)
+			t.Setenv("RATE_LIMIT_GET_USER", "2/1h")
+			t.Setenv("RATE_LIMIT_API_KEYS", auth.HashOpaqueToken(issued)+","+auth.HashOpaqueToken(another))
+
+			tApp := runTestServer()
+
+			headers := map[string]string{"X-API-Key": issued}
+
+			for i := 0; i < 2; i++ {
+				status, _ := sendJSON(t, tApp, "GET", "/api/8269b23f-1417-4f9d-9662-83b609a4e6dd", nil, headers)
+
+				if status != fiber.StatusNotFound {
+					t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Attempt: %v", status, fiber.StatusNotFound, i)
+				}
+			}
+
+			req := httptest.NewRequest("GET", "/api/8269b23f-1417-4f9d-9662-83b609a4e6dd", nil)
+
+			req.Header.Set("X-API-Key", issued)
+
+			resp, err := tApp.Test(req, -1)
+
+			if err != nil {
+				t.Fatalf("Failed when trying to execute fiber.Test: %v", err)
+			}
+
+			if resp.StatusCode != fiber.StatusTooManyRequests {
+				t.Fatalf("Result is different from expected. Result: %v. Expected: %v", resp.StatusCode, fiber.StatusTooManyRequests)
+			}
+
+			if resp.Header.Get("Retry-After") == "" || resp.Header.Get("RateLimit-Remaining") != "0" || resp.Header.Get("RateLimit-Limit") != "2" {
+				t.Fatalf("Missing rate limit headers: %v", resp.Header)
+			}
+
+			// other clients have their own bucket
+			status, _ := sendJSON(t, tApp, "GET", "/api/8269b23f-1417-4f9d-9662-83b609a4e6dd", nil, map[string]string{"X-API-Key": another})
+
+			if status != fiber.StatusNotFound {
+				t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status, fiber.StatusNotFound)
+			}
+
+			// made up keys don't get a bucket of their own, they share the
+			// one of their IP
+			for i := 0; i < 3; i++ {
+				status, _ = sendJSON(t, tApp, "GET", "/api/8269b23f-1417-4f9d-9662-83b609a4e6dd", nil, map[string]string{"X-API-Key": fmt.Sprintf("made-up-%v-%v", store, i)})
+			}
+
+			if status != fiber.StatusTooManyRequests {
+				t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status, fiber.StatusTooManyRequests)
+			}
+		})
+	}
+}
+
+func TestMalformedConfigScenario(t *testing.T) {
+	for key, value := range map[string]string{
+		"RATE_LIMIT_STORE":            "postgress",
+		"RATE_LIMIT_GET_USER":         "60",
+		"SSE_POLL_INTERVAL":           "1",
+		"WEBHOOK_MAX_ATTEMPTS":        "ten",
+		"AUTH_REQUIRE_VERIFIED_EMAIL": "no",
+	} {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, value)
+
+			_, err := config.Load()
+
+			if err == nil {
+				t.Fatalf("The config was loaded with %v=%v", key, value)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/LucasAndFlores/user_api/internal/dto"
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestPIIRedactionScenario(t *testing.T) {
+	tApp, admin := runTestServerWithAdmin(t, "redact_admin@example.com", "8f9a0b1c-2d3e-4f4a-9b5c-6d7e8f9a0bb4")
+
+	createUserWithPassword(t, tApp, "redact_support@example.com", "9a0b1c2d-3e4f-4a5b-8c6d-7e8f9a0b1cc5")
+
+	promoteTo(t, "redact_support@example.com", model.ROLE_SUPPORT)
+
+	support := map[string]string{"Authorization": fmt.Sprintf("Bearer %v", login(t, tApp, "redact_support@example.com", TEST_PASSWORD)["access_token"])}
+
+	id := "0b1c2d3e-4f5a-4b6c-9d7e-8f9a0b1c2dd6"
+
+	createUserWithPassword(t, tApp, "redact_user@example.com", id)
+
+	owner := map[string]string{"Authorization": fmt.Sprintf("Bearer %v", login(t, tApp, "redact_user@example.com", TEST_PASSWORD)["access_token"])}
+
+	status, body := sendJSON(t, tApp, "PUT", "/api/v1/users/"+id, map[string]interface{}{
+		"name":          "redacted user",
+		"email":         "redact_user@example.com",
+		"date_of_birth": "1990-01-01T00:00:00Z",
+	}, owner)
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusOK, string(body))
+	}
+
+	dateOfBirth := "1990-01-01"
+
+	expected := []struct {
+		headers     map[string]string
+		email       string
+		dateOfBirth string
+	}{
+		{support, "r***@example.com", "1990"},
+		{admin, "redact_user@example.com", dateOfBirth},
+		{owner, "redact_user@example.com", dateOfBirth},
+	}
+
+	for _, e := range expected {
+		status, body = sendJSON(t, tApp, "GET", "/api/v1/users/"+id, nil, e.headers)
+
+		var found struct {
+			User map[string]interface{} `json:"user"`
+		}
+
+		json.Unmarshal(body, &found)
+
+		if status != fiber.StatusOK || found.User["email"] != e.email || found.User["date_of_birth"] != e.dateOfBirth {
+			t.Fatalf("Result is different from expected. Result: %v. Expected: %v %v. Body: %v", status, e.email, e.dateOfBirth, string(body))
+		}
+	}
+
+	status, body = sendJSON(t, tApp, "GET", "/api/v1/users?email=redact_user@example.com", nil, support)
+
+	var list struct {
+		Users []map[string]interface{} `json:"users"`
+	}
+
+	json.Unmarshal(body, &list)
+
+	if status != fiber.StatusOK || len(list.Users) != 1 || list.Users[0]["email"] != "r***@example.com" || list.Users[0]["date_of_birth"] != "1990" {
+		t.Fatalf("Result is different from expected. Result: %v. Body: %v", status, string(body))
+	}
+
+	trail := fetchAuditTrail(t, tApp, fmt.Sprintf("/api/v1/users/%v/audit", id), support)
+
+	for _, record := range trail.Records {
+		if change := findChange(record, "email"); change != nil && change.After != nil && change.After != "r***@example.com" {
+			t.Fatalf("The audit record %v has the email unmasked: %+v", record.Id, change)
+		}
+	}
+
+	status, body = sendJSON(t, tApp, "GET", fmt.Sprintf("/api/v1/users/%v/export", id), nil, support)
+
+	var export dto.DataSubjectExportDTO
+
+	json.Unmarshal(body, &export)
+
+	if status != fiber.StatusOK || export.User == nil || export.User.Email != "r***@example.com" || export.User.DateOfBirth != "1990" {
+		t.Fatalf("Result is different from expected. Result: %v. Body: %v", status, string(body))
+	}
+
+	for _, version := range export.Versions {
+		if version.Email != "r***@example.com" {
+			t.Fatalf("The version %v has the email unmasked: %v", version.Version, version.Email)
+		}
+	}
+
+	// support can't change the users
+	status, body = sendJSON(t, tApp, "PUT", "/api/v1/users/"+id, map[string]interface{}{
+		"name":          "changed by support",
+		"email":         "redact_user@example.com",
+		"date_of_birth": "1990-01-01T00:00:00Z",
+	}, support)
+
+	if status != fiber.StatusForbidden {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusForbidden, string(body))
+	}
+}
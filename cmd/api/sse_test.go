@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/events"
+	"github.com/gofiber/fiber/v2"
+)
+
+type sseMessage struct {
+	Id      string
+	Event   string
+	Data    string
+	Comment string
+}
+
+// openEventStream connects to the stream of a server listening for real,
+// as fiber.Test waits for the end of the body.
+func openEventStream(t *testing.T, ctx context.Context, base string, query string, headers map[string]string) *bufio.Scanner {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", base+"/api/v1/users/events"+query, nil)
+
+	if err != nil {
+		t.Fatalf("Failed to build the request: %v", err)
+	}
+
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		t.Fatalf("Failed to open the event stream: %v", err)
+	}
+
+	t.Cleanup(func() { resp.Body.Close() })
+
+	if resp.StatusCode != fiber.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Result is different from expected. Result: %v %v. Expected: %v", resp.StatusCode, resp.Header.Get("Content-Type"), fiber.StatusOK)
+	}
+
+	return bufio.NewScanner(resp.Body)
+}
+
+func nextSSEMessage(t *testing.T, scanner *bufio.Scanner) sseMessage {
+	t.Helper()
+
+	var message sseMessage
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if line == "" {
+			return message
+		}
+
+		if strings.HasPrefix(line, ":") {
+			message.Comment = strings.TrimSpace(line[1:])
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ": ")
+
+		switch field {
+		case "id":
+			message.Id = value
+		case "event":
+			message.Event = value
+		case "data":
+			message.Data = value
+		}
+	}
+
+	t.Fatalf("The event stream ended: %v", scanner.Err())
+
+	return message
+}
+
+// nextUserEvent skips the heartbeats and the events of other users.
+func nextUserEvent(t *testing.T, scanner *bufio.Scanner, id string) (sseMessage, events.Event) {
+	t.Helper()
+
+	for {
+		message := nextSSEMessage(t, scanner)
+
+		if message.Data == "" {
+			continue
+		}
+
+		var event events.Event
+
+		err := json.Unmarshal([]byte(message.Data), &event)
+
+		if err != nil {
+			t.Fatalf("Failed to decode the event: %v", err)
+		}
+
+		if event.AggregateId.String() == id {
+			return message, event
+		}
+	}
+}
+
+func TestUserEventStreamScenario(t *testing.T) {
+	t.Setenv("SSE_POLL_INTERVAL", "20ms")
+	t.Setenv("SSE_HEARTBEAT_INTERVAL", "50ms")
+
+	tApp, admin := runTestServerWithAdmin(t, "sse_admin@example.com", "1c5d6e7f-8a9b-4c0d-8e1f-3a4b5c6d7e2a")
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	go tApp.Listener(listener)
+
+	defer tApp.ShutdownWithTimeout(time.Second)
+
+	base := "http://" + listener.Addr().String()
+
+	status, body := sendJSON(t, tApp, "GET", "/api/v1/users/events?types=user.renamed", nil, admin)
+
+	if status != fiber.StatusBadRequest {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusBadRequest, string(body))
+	}
+
+	id := "2d6e7f8a-9b0c-4d1e-9f2a-4b5c6d7e8f3b"
+
+	createUserWithPassword(t, tApp, "sse_user@example.com", id)
+
+	owner := map[string]string{"Authorization": fmt.Sprintf("Bearer %v", login(t, tApp, "sse_user@example.com", TEST_PASSWORD)["access_token"])}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+
+	defer cancel()
+
+	// without Last-Event-ID, only the events to come are sent
+	stream := openEventStream(t, ctx, base, "?types=user.updated,user.deleted", admin)
+
+	url := fmt.Sprintf("/api/v1/users/%v", id)
+
+	status, body = sendJSON(t, tApp, "PUT", url, map[string]interface{}{
+		"name":          "streamed user",
+		"email":         "sse_user@example.com",
+		"date_of_birth": "1991-02-03T00:00:00Z",
+	}, owner)
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusOK, string(body))
+	}
+
+	status, body = sendJSON(t, tApp, "DELETE", url, nil, owner)
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusOK, string(body))
+	}
+
+	updated, event := nextUserEvent(t, stream, id)
+
+	if updated.Event != events.USER_UPDATED || updated.Id != fmt.Sprint(event.Id) {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", updated, events.USER_UPDATED)
+	}
+
+	deleted, _ := nextUserEvent(t, stream, id)
+
+	if deleted.Event != events.USER_DELETED {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", deleted.Event, events.USER_DELETED)
+	}
+
+	for message := nextSSEMessage(t, stream); message.Comment != "heartbeat"; message = nextSSEMessage(t, stream) {
+	}
+
+	// resuming after the update only sends what followed it
+	resumed := openEventStream(t, ctx, base, "?types=user.created,user.deleted", map[string]string{
+		"Authorization": admin["Authorization"],
+		"Last-Event-ID": updated.Id,
+	})
+
+	message, _ := nextUserEvent(t, resumed, id)
+
+	if message.Id != deleted.Id || message.Event != events.USER_DELETED {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", message, deleted)
+	}
+
+	// the events following a cleaned up event may be gone too
+	status, body = sendJSON(t, tApp, "GET", "/api/v1/users/events", nil, map[string]string{
+		"Authorization": admin["Authorization"],
+		"Last-Event-ID": "2147483647",
+	})
+
+	if status != fiber.StatusGone {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusGone, string(body))
+	}
+}
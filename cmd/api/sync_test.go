@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/dto"
+	"github.com/gofiber/fiber/v2"
+)
+
+func findUserAs(t *testing.T, app *fiber.App, id string, headers map[string]string) dto.UserDTO {
+	t.Helper()
+
+	status, body := sendJSON(t, app, "GET", "/api/v1/users/"+id, nil, headers)
+
+	var found struct {
+		User dto.UserDTO `json:"user"`
+	}
+
+	err := json.Unmarshal(body, &found)
+
+	if status != fiber.StatusOK || err != nil {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusOK, string(body))
+	}
+
+	return found.User
+}
+
+func TestIncrementalSyncScenario(t *testing.T) {
+	tApp, admin := runTestServerWithAdmin(t, "sync_admin@example.com", "1c2d3e4f-5a6b-4c7d-8e9f-0a1b2c3d4ee7")
+
+	id := "2d3e4f5a-6b7c-4d8e-9f0a-1b2c3d4e5ff8"
+
+	before := time.Now()
+
+	// the timestamps and the version are read-only
+	status, body := sendJSON(t, tApp, "POST", "/api/v1/users", map[string]interface{}{
+		"name":          "sync user",
+		"email":         "sync_user@example.com",
+		"id":            id,
+		"date_of_birth": "1990-01-01T00:00:00Z",
+		"version":       9,
+		"created_at":    "2000-01-01T00:00:00Z",
+		"updated_at":    "2000-01-01T00:00:00Z",
+	}, nil)
+
+	if status != fiber.StatusCreated {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusCreated, string(body))
+	}
+
+	created := findUserAs(t, tApp, id, admin)
+
+	if created.Version != 1 || created.CreatedAt.Before(before.Truncate(time.Microsecond)) || !created.UpdatedAt.Equal(created.CreatedAt) {
+		t.Fatalf("Result is different from expected. Result: %+v. Expected: version 1, created and updated now", created)
+	}
+
+	since := time.Now()
+
+	status, body = sendJSON(t, tApp, "PUT", "/api/v1/users/"+id, map[string]interface{}{
+		"name":          "synced user",
+		"email":         "sync_user@example.com",
+		"date_of_birth": "1990-01-01T00:00:00Z",
+	}, admin)
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusOK, string(body))
+	}
+
+	updated := findUserAs(t, tApp, id, admin)
+
+	if updated.Version != 2 || !updated.CreatedAt.Equal(created.CreatedAt) || !updated.UpdatedAt.After(created.UpdatedAt) {
+		t.Fatalf("Result is different from expected. Result: %+v. Expected: version 2, updated after %v", updated, created.UpdatedAt)
+	}
+
+	status, body = sendJSON(t, tApp, "GET", "/api/v1/users?fields=id,version,updated_at&name=synced&updated_since="+since.UTC().Format(time.RFC3339Nano), nil, admin)
+
+	var list struct {
+		Users []map[string]interface{} `json:"users"`
+	}
+
+	json.Unmarshal(body, &list)
+
+	if status != fiber.StatusOK || len(list.Users) != 1 || list.Users[0]["id"] != id || list.Users[0]["version"] != float64(2) {
+		t.Fatalf("Result is different from expected. Result: %v. Body: %v", status, string(body))
+	}
+
+	// a sync starting after the change still lists it, as it may have
+	// committed late
+	status, body = sendJSON(t, tApp, "GET", "/api/v1/users?fields=id&name=synced&updated_since="+time.Now().UTC().Format(time.RFC3339Nano), nil, admin)
+
+	list.Users = nil
+
+	json.Unmarshal(body, &list)
+
+	if status != fiber.StatusOK || len(list.Users) != 1 || list.Users[0]["id"] != id {
+		t.Fatalf("Result is different from expected. Result: %v. Body: %v", status, string(body))
+	}
+
+	status, body = sendJSON(t, tApp, "GET", "/api/v1/users?updated_since="+time.Now().Add(time.Hour).UTC().Format(time.RFC3339), nil, admin)
+
+	json.Unmarshal(body, &list)
+
+	if status != fiber.StatusOK || len(list.Users) != 0 {
+		t.Fatalf("Result is different from expected. Result: %v. Body: %v", status, string(body))
+	}
+
+	status, body = sendJSON(t, tApp, "GET", "/api/v1/users?updated_since=yesterday", nil, admin)
+
+	if status != fiber.StatusBadRequest {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusBadRequest, string(body))
+	}
+}
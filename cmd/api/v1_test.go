@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestVersionedUserRoutesScenario(t *testing.T) {
+	tApp := runTestServer()
+
+	id := "5c2e8f1a-7b3d-4e6f-9a0b-c1d2e3f4a5b6"
+
+	request, err := json.Marshal(map[string]interface{}{
+		"name":          "versioned user",
+		"email":         "versioned@example.com",
+		"id":            id,
+		"date_of_birth": "1990-01-02T00:00:00Z",
+		"password":      TEST_PASSWORD,
+	})
+
+	if err != nil {
+		t.Fatalf("Failed to marshal paylod to JSON: %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "/api/v1/users", bytes.NewReader(request))
+
+	req.Header.Set("content-type", "application/json")
+
+	resp, err := tApp.Test(req, -1)
+
+	if err != nil {
+		t.Fatalf("Failed when trying to execute fiber.Test: %v", err)
+	}
+
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", resp.StatusCode, fiber.StatusCreated)
+	}
+
+	location := fmt.Sprintf("/api/v1/users/%v", id)
+
+	if resp.Header.Get("Location") != location {
+		t.Fatalf("The Location is different from expected. Result: %v. Expected: %v", resp.Header.Get("Location"), location)
+	}
+
+	resp, err = tApp.Test(httptest.NewRequest("GET", location, nil), -1)
+
+	if err != nil {
+		t.Fatalf("Failed when trying to execute fiber.Test: %v", err)
+	}
+
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", resp.StatusCode, fiber.StatusOK)
+	}
+
+	if resp.Header.Get("Deprecation") != "" {
+		t.Fatalf("Versioned routes must not be deprecated. Result: %v", resp.Header.Get("Deprecation"))
+	}
+}
+
+func TestLegacyRoutesDeprecationScenario(t *testing.T) {
+	tApp := runTestServer()
+
+	testCases := []struct {
+		description string
+		method      string
+		url         string
+		successor   string
+	}{
+		{
+			description: "legacy user route",
+			method:      "GET",
+			url:         "/api/8269b23f-1417-4f9d-9662-83b609a4e6dd",
+			successor:   `</api/v1/users>; rel="successor-version"`,
+		},
+		{
+			description: "legacy auth route",
+			method:      "POST",
+			url:         "/api/auth/login",
+			successor:   `</api/v1/auth>; rel="successor-version"`,
+		},
+	}
+
+	for _, value := range testCases {
+		t.Run(value.description, func(t *testing.T) {
+			resp, err := tApp.Test(httptest.NewRequest(value.method, value.url, nil), -1)
+
+			if err != nil {
+				t.Fatalf("Failed when trying to execute fiber.Test: %v", err)
+			}
+
+			if resp.Header.Get("Deprecation") == "" || resp.Header.Get("Sunset") == "" {
+				t.Fatalf("Expected Deprecation and Sunset headers. Result: %v", resp.Header)
+			}
+
+			if resp.Header.Get("Link") != value.successor {
+				t.Fatalf("The Link is different from expected. Result: %v. Expected: %v", resp.Header.Get("Link"), value.successor)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var tokenPattern = regexp.MustCompile(`token=([^\s]+)`)
+
+func mailsTo(t *testing.T, email string) []string {
+	t.Helper()
+
+	recipient := strings.ReplaceAll(email, "@", "_at_")
+
+	files, err := filepath.Glob(filepath.Join(MAIL_FILE_DIR, fmt.Sprintf("*-%s.eml", recipient)))
+
+	if err != nil {
+		t.Fatalf("Failed to list the mails: %v", err)
+	}
+
+	sort.Strings(files)
+
+	return files
+}
+
+// awaitMailToken waits for a mail to the given recipient beyond the sent
+// ones, for the mails sent after answering, and returns its token.
+func awaitMailToken(t *testing.T, email string, sent int) string {
+	t.Helper()
+
+	for i := 0; i < 50 && len(mailsTo(t, email)) <= sent; i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return latestMailToken(t, email)
+}
+
+// latestMailToken returns the token of the newest mail written by the file
+// mailer for the given recipient.
+func latestMailToken(t *testing.T, email string) string {
+	t.Helper()
+
+	files := mailsTo(t, email)
+
+	if len(files) == 0 {
+		t.Fatalf("No mail was sent to %v", email)
+	}
+
+	content, err := os.ReadFile(files[len(files)-1])
+
+	if err != nil {
+		t.Fatalf("Failed to read the mail: %v", err)
+	}
+
+	match := tokenPattern.FindStringSubmatch(string(content))
+
+	if match == nil {
+		t.Fatalf("The mail to %v has no token", email)
+	}
+
+	token, err := url.QueryUnescape(match[1])
+
+	if err != nil {
+		t.Fatalf("Failed to unescape the token: %v", err)
+	}
+
+	return token
+}
+
+func verifyEmail(t *testing.T, app *fiber.App, email string) {
+	t.Helper()
+
+	token := latestMailToken(t, email)
+
+	status, body := sendJSON(t, app, "GET", fmt.Sprintf("/api/auth/verify-email?token=%v", url.QueryEscape(token)), nil, nil)
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Failed to verify the email. Status: %v. Body: %v", status, string(body))
+	}
+}
+
+func TestEmailVerificationScenario(t *testing.T) {
+	tApp := runTestServer()
+
+	sendJSON(t, tApp, "POST", "/api/save", map[string]interface{}{
+		"name":          "verified user",
+		"email":         "verify@example.com",
+		"id":            "6a5d1e2f-8b7c-4d94-afe0-5b6c7d8e9fa6",
+		"date_of_birth": "1990-01-01T00:00:00Z",
+	}, nil)
+
+	token := latestMailToken(t, "verify@example.com")
+
+	verifyUrl := fmt.Sprintf("/api/auth/verify-email?token=%v", url.QueryEscape(token))
+
+	status, body := sendJSON(t, tApp, "GET", verifyUrl, nil, nil)
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusOK, string(body))
+	}
+
+	status, body = sendJSON(t, tApp, "GET", verifyUrl, nil, nil)
+
+	expectedBody := "{\"message\":\"invalid or expired verification token\"}"
+
+	if status != fiber.StatusBadRequest || string(body) != expectedBody {
+		t.Fatalf("A verification token must be single use. Result: %v %v", status, string(body))
+	}
+
+	status, _ = sendJSON(t, tApp, "GET", "/api/auth/verify-email?token=not-a-token", nil, nil)
+
+	if status != fiber.StatusBadRequest {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status, fiber.StatusBadRequest)
+	}
+}
+
+func TestResendVerificationScenario(t *testing.T) {
+	tApp := runTestServer()
+
+	sendJSON(t, tApp, "POST", "/api/save", map[string]interface{}{
+		"name":          "resend user",
+		"email":         "resend@example.com",
+		"id":            "7b6e2f3a-9c8d-4ea5-b0f1-6c7d8e9fa0b7",
+		"date_of_birth": "1990-01-01T00:00:00Z",
+	}, nil)
+
+	first := latestMailToken(t, "resend@example.com")
+
+	for _, email := range []string{"resend@example.com", "nobody@example.com"} {
+		status, body := sendJSON(t, tApp, "POST", "/api/auth/verify-email/resend", map[string]interface{}{"email": email}, nil)
+
+		expectedBody := "{\"message\":\"if the email belongs to an unverified user, a new verification email was sent\"}"
+
+		if status != fiber.StatusAccepted || string(body) != expectedBody {
+			t.Fatalf("Result is different from expected. Result: %v %v", status, string(body))
+		}
+	}
+
+	// the first email is too recent, so the resend is throttled
+	if latestMailToken(t, "resend@example.com") != first {
+		t.Fatalf("The resend was not throttled")
+	}
+}
+
+func TestVerificationAfterEmailChangeScenario(t *testing.T) {
+	tApp, admin := runTestServerWithAdmin(t, "changed_admin@example.com", "8c7f3a4b-0d9e-4fb6-81a2-7d8e9fa0b1c8")
+
+	id := "9d8a4b5c-1e0f-4ac7-92b3-8e9fa0b1c2d9"
+
+	sendJSON(t, tApp, "POST", "/api/save", map[string]interface{}{
+		"name":          "changed user",
+		"email":         "before_change@example.com",
+		"id":            id,
+		"date_of_birth": "1990-01-01T00:00:00Z",
+	}, nil)
+
+	stale := latestMailToken(t, "before_change@example.com")
+
+	sent := len(mailsTo(t, "after_change@example.com"))
+
+	status, body := sendJSON(t, tApp, "PUT", "/api/v1/users/"+id, map[string]interface{}{
+		"name":          "changed user",
+		"email":         "after_change@example.com",
+		"date_of_birth": "1990-01-01T00:00:00Z",
+	}, admin)
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusOK, string(body))
+	}
+
+	// the link sent to the previous email doesn't prove the new one
+	status, body = sendJSON(t, tApp, "GET", fmt.Sprintf("/api/v1/auth/verify-email?token=%v", url.QueryEscape(stale)), nil, nil)
+
+	if status != fiber.StatusBadRequest {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusBadRequest, string(body))
+	}
+
+	// a verification would have recorded a version
+	if user := findUserAs(t, tApp, id, admin); user.Version != 2 {
+		t.Fatalf("The new email was verified by the previous link: %+v", user)
+	}
+
+	fresh := awaitMailToken(t, "after_change@example.com", sent)
+
+	status, body = sendJSON(t, tApp, "GET", fmt.Sprintf("/api/v1/auth/verify-email?token=%v", url.QueryEscape(fresh)), nil, nil)
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusOK, string(body))
+	}
+}
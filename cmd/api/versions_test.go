@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/dto"
+	"github.com/gofiber/fiber/v2"
+)
+
+type userVersions struct {
+	Versions   []dto.UserVersionDTO `json:"versions"`
+	NextCursor string               `json:"next_cursor"`
+}
+
+func renameUser(t *testing.T, app *fiber.App, id string, name string, headers map[string]string) {
+	t.Helper()
+
+	status, body := sendJSON(t, app, "PUT", fmt.Sprintf("/api/v1/users/%v", id), map[string]interface{}{
+		"name":          name,
+		"email":         "versioned_user@example.com",
+		"date_of_birth": "1990-01-01T00:00:00Z",
+	}, headers)
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusOK, string(body))
+	}
+}
+
+func findUserAsOf(t *testing.T, app *fiber.App, id string, at time.Time, headers map[string]string) (int, map[string]interface{}) {
+	t.Helper()
+
+	status, body := sendJSON(t, app, "GET", fmt.Sprintf("/api/v1/users/%v?as_of=%v", id, url.QueryEscape(at.UTC().Format(time.RFC3339Nano))), nil, headers)
+
+	var response map[string]interface{}
+
+	json.Unmarshal(body, &response)
+
+	user, _ := response["user"].(map[string]interface{})
+
+	return status, user
+}
+
+func TestUserVersionsScenario(t *testing.T) {
+	tApp := runTestServer()
+
+	id := "5a9b0c1d-2e3f-4a4b-8c5d-7e8f9a0b1c6e"
+
+	before := time.Now()
+
+	createUserWithPassword(t, tApp, "versioned_user@example.com", id)
+
+	owner := map[string]string{"Authorization": fmt.Sprintf("Bearer %v", login(t, tApp, "versioned_user@example.com", TEST_PASSWORD)["access_token"])}
+
+	created := time.Now()
+
+	renameUser(t, tApp, id, "second name", owner)
+
+	renamed := time.Now()
+
+	renameUser(t, tApp, id, "third name", owner)
+
+	status, user := findUserAsOf(t, tApp, id, created, owner)
+
+	if status != fiber.StatusOK || user["name"] != "session user" {
+		t.Fatalf("Result is different from expected. Result: %v %v. Expected: the first version", status, user)
+	}
+
+	status, user = findUserAsOf(t, tApp, id, renamed, owner)
+
+	if status != fiber.StatusOK || user["name"] != "second name" {
+		t.Fatalf("Result is different from expected. Result: %v %v. Expected: the second version", status, user)
+	}
+
+	status, _ = findUserAsOf(t, tApp, id, before, owner)
+
+	if status != fiber.StatusNotFound {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status, fiber.StatusNotFound)
+	}
+
+	status, _ = findUserAsOf(t, tApp, id, created, nil)
+
+	if status != fiber.StatusForbidden {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", status, fiber.StatusForbidden)
+	}
+
+	// the legacy path guards the history the same way
+	status, body := sendJSON(t, tApp, "GET", fmt.Sprintf("/api/%v?as_of=%v", id, url.QueryEscape(created.UTC().Format(time.RFC3339Nano))), nil, nil)
+
+	if status != fiber.StatusForbidden {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusForbidden, string(body))
+	}
+
+	// a past version has its own ETag, which can't update the user
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/v1/users/%v?as_of=%v", id, url.QueryEscape(renamed.UTC().Format(time.RFC3339Nano))), nil)
+
+	req.Header.Set("Authorization", owner["Authorization"])
+
+	resp, err := tApp.Test(req, -1)
+
+	if err != nil {
+		t.Fatalf("Failed when trying to execute fiber.Test: %v", err)
+	}
+
+	pastETag := resp.Header.Get("ETag")
+
+	if !strings.HasPrefix(pastETag, "W/\"3") || !strings.HasSuffix(pastETag, ";as_of\"") {
+		t.Fatalf("The ETag is different from expected. Result: %v. Expected: a weak as_of ETag of the version 3", pastETag)
+	}
+
+	for _, ifMatch := range []string{pastETag, strings.TrimPrefix(pastETag, "W/")} {
+		status, body = sendJSON(t, tApp, "PUT", fmt.Sprintf("/api/v1/users/%v", id), map[string]interface{}{
+			"name":          "stale name",
+			"email":         "versioned_user@example.com",
+			"date_of_birth": "1990-01-01T00:00:00Z",
+		}, map[string]string{
+			"Authorization":     owner["Authorization"],
+			fiber.HeaderIfMatch: ifMatch,
+		})
+
+		if status != fiber.StatusPreconditionFailed {
+			t.Fatalf("Result is different from expected. Result: %v. Expected: %v. If-Match: %v. Body: %v", status, fiber.StatusPreconditionFailed, ifMatch, string(body))
+		}
+	}
+
+	versionsUrl := fmt.Sprintf("/api/v1/users/%v/versions", id)
+
+	status, body = sendJSON(t, tApp, "GET", versionsUrl+"?limit=2", nil, owner)
+
+	var page userVersions
+
+	json.Unmarshal(body, &page)
+
+	if status != fiber.StatusOK || len(page.Versions) != 2 || page.Versions[0].Version != 1 || page.NextCursor == "" {
+		t.Fatalf("Result is different from expected. Result: %v %v. Expected: the creation and the verification of the email", status, string(body))
+	}
+
+	status, body = sendJSON(t, tApp, "POST", versionsUrl+"/1/revert", nil, map[string]string{
+		"Authorization":     owner["Authorization"],
+		fiber.HeaderIfMatch: "\"4\"",
+	})
+
+	var reverted map[string]map[string]interface{}
+
+	json.Unmarshal(body, &reverted)
+
+	if status != fiber.StatusOK || reverted["user"]["name"] != "session user" {
+		t.Fatalf("Result is different from expected. Result: %v %v. Expected: the first version restored", status, string(body))
+	}
+
+	status, body = sendJSON(t, tApp, "POST", versionsUrl+"/99/revert", nil, owner)
+
+	if status != fiber.StatusNotFound {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusNotFound, string(body))
+	}
+
+	status, body = sendJSON(t, tApp, "DELETE", fmt.Sprintf("/api/v1/users/%v", id), nil, owner)
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusOK, string(body))
+	}
+
+	// the history outlives the user
+	status, user = findUserAsOf(t, tApp, id, renamed, owner)
+
+	if status != fiber.StatusOK || user["name"] != "second name" {
+		t.Fatalf("Result is different from expected. Result: %v %v. Expected: the second version", status, user)
+	}
+
+	status, body = sendJSON(t, tApp, "GET", versionsUrl+"?cursor="+page.NextCursor, nil, owner)
+
+	page = userVersions{}
+
+	json.Unmarshal(body, &page)
+
+	if status != fiber.StatusOK || len(page.Versions) != 4 || !page.Versions[3].Deleted || page.Versions[3].Version != 6 {
+		t.Fatalf("Result is different from expected. Result: %v %v. Expected: versions 3 to 6, the renames, the revert then the deletion", status, string(body))
+	}
+}
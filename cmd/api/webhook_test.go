@@ -0,0 +1,437 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/config"
+	"github.com/LucasAndFlores/user_api/database"
+	"github.com/LucasAndFlores/user_api/internal/events"
+	"github.com/LucasAndFlores/user_api/internal/pii"
+	"github.com/LucasAndFlores/user_api/internal/webhook"
+	"github.com/LucasAndFlores/user_api/routes"
+	"github.com/gofiber/fiber/v2"
+)
+
+type receivedWebhook struct {
+	Header http.Header
+	Body   []byte
+	Event  events.Event
+}
+
+// webhookReceiver records the deliveries of the given user, and answers
+// with a 500 the first time failFirst is set.
+type webhookReceiver struct {
+	mu        sync.Mutex
+	aggregate string
+	failFirst bool
+	received  []receivedWebhook
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	var event events.Event
+
+	json.Unmarshal(body, &event)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if event.AggregateId.String() != r.aggregate {
+		return
+	}
+
+	r.received = append(r.received, receivedWebhook{Header: req.Header, Body: body, Event: event})
+
+	if r.failFirst && len(r.received) == 1 {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (r *webhookReceiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.received)
+}
+
+func TestWebhookDeliveryScenario(t *testing.T) {
+	// the receivers run on the loopback interface
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_TARGETS", "true")
+
+	tApp, admin := runTestServerWithAdmin(t, "webhook_admin@example.com", "7e1f2a3b-4c5d-4e6f-8a7b-9c0d1e2f3ae6")
+
+	id := "8f2a3b4c-5d6e-4f7a-9b8c-0d1e2f3a4bf7"
+
+	receiver := &webhookReceiver{aggregate: id, failFirst: true}
+
+	server := httptest.NewServer(receiver)
+
+	defer server.Close()
+
+	status, body := sendJSON(t, tApp, "POST", "/api/v1/webhooks", map[string]interface{}{"url": server.URL, "event_types": []string{"user.unknown"}}, admin)
+
+	if status != fiber.StatusUnprocessableEntity {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusUnprocessableEntity, string(body))
+	}
+
+	status, body = sendJSON(t, tApp, "POST", "/api/v1/webhooks", map[string]interface{}{"url": server.URL, "event_types": []string{events.USER_CREATED}}, admin)
+
+	if status != fiber.StatusCreated {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusCreated, string(body))
+	}
+
+	var created struct {
+		Webhook struct {
+			Id     string `json:"id"`
+			Secret string `json:"secret"`
+		} `json:"webhook"`
+	}
+
+	json.Unmarshal(body, &created)
+
+	if created.Webhook.Secret == "" {
+		t.Fatalf("The secret is missing from the created webhook: %v", string(body))
+	}
+
+	status, body = sendJSON(t, tApp, "POST", "/api/v1/users", map[string]interface{}{
+		"name":          "webhook user",
+		"email":         "webhook_user@example.com",
+		"id":            id,
+		"date_of_birth": "1990-01-01T00:00:00Z",
+	}, nil)
+
+	if status != fiber.StatusCreated {
+		t.Fatalf("Failed to create user. Status: %v. Body: %v", status, string(body))
+	}
+
+	db, err := database.ConnectDatabase()
+
+	if err != nil {
+		t.Fatalf("An error occurred when tried to connect to database: %v", err)
+	}
+
+	relay, err := routes.NewRelay(db, config.OutboxConfig{Publisher: "log", Interval: time.Millisecond, BatchSize: 100, MaxBackoff: time.Millisecond, Retention: time.Hour})
+
+	if err != nil {
+		t.Fatalf("Failed to setup the relay: %v", err)
+	}
+
+	dispatcher := routes.NewDispatcher(db, config.WebhookConfig{
+		Interval:     time.Millisecond,
+		BatchSize:    100,
+		Timeout:      5 * time.Second,
+		MaxAttempts:  3,
+		MaxBackoff:   time.Millisecond,
+		DisableAfter: 100,
+
+		AllowPrivateTargets: true,
+	})
+
+	deliver := func(expected int) {
+		t.Helper()
+
+		for attempt := 0; attempt < 100 && receiver.count() < expected; attempt++ {
+			_, err := relay.RunOnce(context.Background())
+
+			if err != nil {
+				t.Fatalf("Failed to relay the outbox: %v", err)
+			}
+
+			_, err = dispatcher.RunOnce(context.Background())
+
+			if err != nil {
+				t.Fatalf("Failed to dispatch the webhooks: %v", err)
+			}
+
+			time.Sleep(5 * time.Millisecond)
+		}
+
+		if receiver.count() != expected {
+			t.Fatalf("Result is different from expected. Result: %v. Expected: %v", receiver.count(), expected)
+		}
+	}
+
+	// the first attempt fails and is retried
+	deliver(2)
+
+	last := receiver.received[1]
+
+	if last.Event.Type != events.USER_CREATED || last.Header.Get(webhook.EVENT_HEADER) != events.USER_CREATED {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", last.Event.Type, events.USER_CREATED)
+	}
+
+	err = webhook.Verify(created.Webhook.Secret, last.Header.Get(webhook.SIGNATURE_HEADER), last.Body, webhook.DEFAULT_TOLERANCE, time.Now())
+
+	if err != nil {
+		t.Fatalf("Failed to verify the signature: %v", err)
+	}
+
+	err = webhook.Verify("another-secret", last.Header.Get(webhook.SIGNATURE_HEADER), last.Body, webhook.DEFAULT_TOLERANCE, time.Now())
+
+	if err != webhook.ErrInvalidSignature {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", err, webhook.ErrInvalidSignature)
+	}
+
+	err = webhook.Verify(created.Webhook.Secret, last.Header.Get(webhook.SIGNATURE_HEADER), last.Body, webhook.DEFAULT_TOLERANCE, time.Now().Add(time.Hour))
+
+	if err != webhook.ErrExpiredSignature {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v", err, webhook.ErrExpiredSignature)
+	}
+
+	url := fmt.Sprintf("/api/v1/webhooks/%v/deliveries", created.Webhook.Id)
+
+	status, body = sendJSON(t, tApp, "GET", url, nil, admin)
+
+	if status != fiber.StatusOK {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusOK, string(body))
+	}
+
+	var log struct {
+		Deliveries []map[string]interface{} `json:"deliveries"`
+	}
+
+	json.Unmarshal(body, &log)
+
+	var delivery map[string]interface{}
+
+	for _, d := range log.Deliveries {
+		if d["id"] == last.Header.Get(webhook.ID_HEADER) {
+			delivery = d
+		}
+	}
+
+	if delivery["status"] != "succeeded" || delivery["attempts"] != float64(2) || delivery["response_status"] != float64(fiber.StatusOK) {
+		t.Fatalf("Result is different from expected. Result: %v", delivery)
+	}
+
+	status, body = sendJSON(t, tApp, "POST", fmt.Sprintf("%v/%v/redeliver", url, delivery["id"]), nil, admin)
+
+	if status != fiber.StatusAccepted {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusAccepted, string(body))
+	}
+
+	deliver(3)
+
+	if receiver.received[2].Event.Id != last.Event.Id {
+		t.Fatalf("The redelivered event is different from expected. Result: %v. Expected: %v", receiver.received[2].Event.Id, last.Event.Id)
+	}
+}
+
+func TestWebhookAutoDisableScenario(t *testing.T) {
+	// the receivers run on the loopback interface
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_TARGETS", "true")
+
+	tApp, admin := runTestServerWithAdmin(t, "webhook_disable@example.com", "9a3b4c5d-6e7f-4a8b-8c9d-1e2f3a4b5c08")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+
+	defer server.Close()
+
+	status, body := sendJSON(t, tApp, "POST", "/api/v1/webhooks", map[string]interface{}{
+		"url":         server.URL,
+		"event_types": []string{events.USER_CREATED, events.USER_UPDATED, events.USER_DELETED},
+	}, admin)
+
+	if status != fiber.StatusCreated {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusCreated, string(body))
+	}
+
+	var created struct {
+		Webhook struct {
+			Id string `json:"id"`
+		} `json:"webhook"`
+	}
+
+	json.Unmarshal(body, &created)
+
+	createUserWithPassword(t, tApp, "webhook_disable_user@example.com", "0b4c5d6e-7f8a-4b9c-9d0e-2f3a4b5c6d19")
+
+	db, err := database.ConnectDatabase()
+
+	if err != nil {
+		t.Fatalf("An error occurred when tried to connect to database: %v", err)
+	}
+
+	relay, err := routes.NewRelay(db, config.OutboxConfig{Publisher: "log", Interval: time.Millisecond, BatchSize: 100, MaxBackoff: time.Millisecond, Retention: time.Hour})
+
+	if err != nil {
+		t.Fatalf("Failed to setup the relay: %v", err)
+	}
+
+	dispatcher := routes.NewDispatcher(db, config.WebhookConfig{
+		Interval:     time.Millisecond,
+		BatchSize:    100,
+		Timeout:      5 * time.Second,
+		MaxAttempts:  10,
+		MaxBackoff:   time.Millisecond,
+		DisableAfter: 3,
+
+		AllowPrivateTargets: true,
+	})
+
+	url := fmt.Sprintf("/api/v1/webhooks/%v", created.Webhook.Id)
+
+	var found struct {
+		Webhook struct {
+			Enabled bool `json:"enabled"`
+		} `json:"webhook"`
+	}
+
+	found.Webhook.Enabled = true
+
+	for attempt := 0; attempt < 100 && found.Webhook.Enabled; attempt++ {
+		_, err := relay.RunOnce(context.Background())
+
+		if err != nil {
+			t.Fatalf("Failed to relay the outbox: %v", err)
+		}
+
+		_, err = dispatcher.RunOnce(context.Background())
+
+		if err != nil {
+			t.Fatalf("Failed to dispatch the webhooks: %v", err)
+		}
+
+		_, body = sendJSON(t, tApp, "GET", url, nil, admin)
+
+		json.Unmarshal(body, &found)
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if found.Webhook.Enabled {
+		t.Fatalf("The failing webhook was not disabled: %v", string(body))
+	}
+
+	status, body = sendJSON(t, tApp, "POST", url+"/enable", nil, admin)
+
+	json.Unmarshal(body, &found)
+
+	if status != fiber.StatusOK || !found.Webhook.Enabled {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusOK, string(body))
+	}
+}
+
+func TestWebhookPrivateTargetsScenario(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_TARGETS", "false")
+
+	tApp, admin := runTestServerWithAdmin(t, "webhook_private_admin@example.com", "2b3c4d5e-6f7a-4b8c-9d0e-1f2a3b4c5d6e")
+
+	id := "3c4d5e6f-7a8b-4c9d-8e0f-2a3b4c5d6e7f"
+
+	receiver := &webhookReceiver{aggregate: id}
+
+	server := httptest.NewServer(receiver)
+
+	defer server.Close()
+
+	for _, url := range []string{server.URL, "http://localhost:8080/hook", "http://169.254.169.254/latest/meta-data", "http://10.0.0.1/hook"} {
+		status, body := sendJSON(t, tApp, "POST", "/api/v1/webhooks", map[string]interface{}{"url": url, "event_types": []string{events.USER_CREATED}}, admin)
+
+		if status != fiber.StatusUnprocessableEntity || !strings.Contains(string(body), "public_url") {
+			t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v. URL: %v", status, fiber.StatusUnprocessableEntity, string(body), url)
+		}
+	}
+
+	// a host can resolve to other addresses once subscribed, which the
+	// dispatcher checks again when connecting
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_TARGETS", "true")
+
+	tApp = runTestServer()
+
+	status, body := sendJSON(t, tApp, "POST", "/api/v1/webhooks", map[string]interface{}{"url": server.URL, "event_types": []string{events.USER_CREATED}}, admin)
+
+	if status != fiber.StatusCreated {
+		t.Fatalf("Result is different from expected. Result: %v. Expected: %v. Body: %v", status, fiber.StatusCreated, string(body))
+	}
+
+	var created struct {
+		Webhook struct {
+			Id string `json:"id"`
+		} `json:"webhook"`
+	}
+
+	json.Unmarshal(body, &created)
+
+	status, body = sendJSON(t, tApp, "POST", "/api/v1/users", map[string]interface{}{
+		"name":          "webhook private user",
+		"email":         "webhook_private_user@example.com",
+		"id":            id,
+		"date_of_birth": "1990-01-01",
+	}, nil)
+
+	if status != fiber.StatusCreated {
+		t.Fatalf("Failed to create user. Status: %v. Body: %v", status, string(body))
+	}
+
+	db, err := database.ConnectDatabase()
+
+	if err != nil {
+		t.Fatalf("An error occurred when tried to connect to database: %v", err)
+	}
+
+	var secret string
+
+	err = db.Raw("SELECT secret FROM webhook_subscriptions WHERE external_id = ?", created.Webhook.Id).Scan(&secret).Error
+
+	if err != nil || !strings.HasPrefix(secret, pii.KeyPrefix(pii.CurrentKeyId())) {
+		t.Fatalf("The secret of the webhook is not encrypted: %v %v", secret, err)
+	}
+
+	relay, err := routes.NewRelay(db, config.OutboxConfig{Publisher: "log", Interval: time.Millisecond, BatchSize: 100, MaxBackoff: time.Millisecond, Retention: time.Hour})
+
+	if err != nil {
+		t.Fatalf("Failed to setup the relay: %v", err)
+	}
+
+	dispatcher := routes.NewDispatcher(db, config.WebhookConfig{
+		Interval:     time.Millisecond,
+		BatchSize:    100,
+		Timeout:      5 * time.Second,
+		MaxAttempts:  1,
+		MaxBackoff:   time.Millisecond,
+		DisableAfter: 100,
+	})
+
+	url := fmt.Sprintf("/api/v1/webhooks/%v/deliveries", created.Webhook.Id)
+
+	var log struct {
+		Deliveries []map[string]interface{} `json:"deliveries"`
+	}
+
+	for attempt := 0; attempt < 100 && (len(log.Deliveries) == 0 || log.Deliveries[0]["status"] == "pending"); attempt++ {
+		_, err := relay.RunOnce(context.Background())
+
+		if err != nil {
+			t.Fatalf("Failed to relay the outbox: %v", err)
+		}
+
+		_, err = dispatcher.RunOnce(context.Background())
+
+		if err != nil {
+			t.Fatalf("Failed to dispatch the webhooks: %v", err)
+		}
+
+		_, body = sendJSON(t, tApp, "GET", url, nil, admin)
+
+		json.Unmarshal(body, &log)
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if receiver.count() != 0 || len(log.Deliveries) != 1 || log.Deliveries[0]["status"] != "failed" || !strings.Contains(log.Deliveries[0]["last_error"].(string), webhook.ErrPrivateTarget.Error()) {
+		t.Fatalf("Result is different from expected. Received: %v. Deliveries: %v", receiver.count(), log.Deliveries)
+	}
+}
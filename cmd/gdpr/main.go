@@ -0,0 +1,100 @@
+// Command gdpr exports or erases the data of a user, for the requests of
+// data subjects handled outside of the API.
+//
+//	gdpr export <user id> [file]
+//	gdpr erase <user id>
+//
+// The export is written to the file, or to the standard output.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/LucasAndFlores/user_api/config"
+	"github.com/LucasAndFlores/user_api/database"
+	"github.com/LucasAndFlores/user_api/internal/audit"
+	"github.com/LucasAndFlores/user_api/routes"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// AUDIT_PRINCIPAL is the actor of the erasures made with the command.
+const AUDIT_PRINCIPAL = "cli:gdpr"
+
+const USAGE = "usage: gdpr export <user id> [file] | gdpr erase <user id>"
+
+func main() {
+	if len(os.Args) < 3 {
+		log.Fatal(USAGE)
+	}
+
+	id, err := uuid.Parse(os.Args[2])
+
+	if err != nil {
+		log.Fatalf("Unable to parse the user id: %v", err)
+	}
+
+	// the variables may come from the environment only
+	config.LoadEnvVariables()
+
+	db, err := database.ConnectDatabase()
+
+	if err != nil {
+		log.Fatalf("An error occurred when tried to connect to database: %v", err)
+	}
+
+	ctx := audit.WithActor(context.Background(), audit.Actor{Principal: AUDIT_PRINCIPAL})
+
+	subjects := routes.NewDataSubjectService(db)
+
+	switch os.Args[1] {
+	case "export":
+		status, body := subjects.Export(ctx, id)
+
+		if status != fiber.StatusOK {
+			log.Fatalf("The export failed: %v", body["message"])
+		}
+
+		err = write(body["export"], os.Args[3:])
+
+		if err != nil {
+			log.Fatalf("An error occurred when tried to write the export: %v", err)
+		}
+	case "erase":
+		status, body := subjects.Erase(ctx, id)
+
+		if status != fiber.StatusOK {
+			log.Fatalf("The erasure failed: %v", body["message"])
+		}
+
+		fmt.Println(body["message"])
+	default:
+		log.Fatal(USAGE)
+	}
+}
+
+func write(export interface{}, args []string) error {
+	out := os.Stdout
+
+	if len(args) > 0 {
+		file, err := os.OpenFile(args[0], os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+
+		if err != nil {
+			return err
+		}
+
+		defer file.Close()
+
+		out = file
+	}
+
+	encoder := json.NewEncoder(out)
+
+	encoder.SetIndent("", "\t")
+
+	return encoder.Encode(export)
+}
@@ -0,0 +1,125 @@
+// Command keyring manages the keyring file encrypting the pii.
+//
+//	keyring init <file>
+//	keyring rotate <file>
+//
+// init creates a keyring with a single key, rotate adds a new key and makes
+// it the current one. Both print the id of the current key. Once the API
+// restarted with the rotated keyring, its background worker re-encrypts the
+// pii with the new key.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/LucasAndFlores/user_api/internal/pii"
+)
+
+const USAGE = "usage: keyring init <file> | keyring rotate <file>"
+
+func main() {
+	if len(os.Args) != 3 {
+		log.Fatal(USAGE)
+	}
+
+	path := os.Args[2]
+
+	switch os.Args[1] {
+	case "init":
+		keyring, err := pii.GenerateKeyring()
+
+		if err != nil {
+			log.Fatalf("An error occurred when tried to generate the keyring: %v", err)
+		}
+
+		// a link fails rather than replace an existing keyring
+		err = write(path, keyring, os.Link)
+
+		if err != nil {
+			log.Fatalf("An error occurred when tried to write the keyring: %v", err)
+		}
+
+		fmt.Println(keyring.CurrentKeyId)
+	case "rotate":
+		content, err := os.ReadFile(path)
+
+		if err != nil {
+			log.Fatalf("An error occurred when tried to read the keyring: %v", err)
+		}
+
+		var keyring pii.Keyring
+
+		err = json.Unmarshal(content, &keyring)
+
+		if err != nil {
+			log.Fatalf("Unable to parse the keyring: %v", err)
+		}
+
+		err = keyring.Rotate()
+
+		if err != nil {
+			log.Fatalf("An error occurred when tried to rotate the keyring: %v", err)
+		}
+
+		err = write(path, keyring, os.Rename)
+
+		if err != nil {
+			log.Fatalf("An error occurred when tried to write the keyring: %v", err)
+		}
+
+		fmt.Println(keyring.CurrentKeyId)
+	default:
+		log.Fatal(USAGE)
+	}
+}
+
+// write stores the keyring in a temporary file next to path, synced to
+// disk, and only then publishes it at path, so a crash or a full disk leaves
+// the previous keyring intact.
+func write(path string, keyring pii.Keyring, publish func(oldpath string, newpath string) error) error {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(file.Name())
+
+	encoder := json.NewEncoder(file)
+
+	encoder.SetIndent("", "\t")
+
+	err = encoder.Encode(keyring)
+
+	if err == nil {
+		err = file.Sync()
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = publish(file.Name(), path)
+
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.Open(filepath.Dir(path))
+
+	if err != nil {
+		return err
+	}
+
+	defer dir.Close()
+
+	return dir.Sync()
+}
@@ -1,6 +1,166 @@
 package config
 
-import "github.com/joho/godotenv"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/joho/godotenv"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type Config struct {
+	Port        string
+	BaseURL     string
+	HTTP        HTTPConfig
+	Auth        AuthConfig
+	Mail        MailConfig
+	RateLimit   RateLimitConfig
+	Legacy      LegacyConfig
+	OpenAPI     OpenAPIConfig
+	GRPC        GRPCConfig
+	GraphQL     GraphQLConfig
+	Outbox      OutboxConfig
+	Webhook     WebhookConfig
+	SSE         SSEConfig
+	PII         PIIConfig
+	DateOfBirth DateOfBirthConfig
+	// IdempotencyTTL is how long responses to requests with an
+	// Idempotency-Key header are kept for replay. IdempotencyLease is how
+	// long a request holds its key before a retry may run it again; it must
+	// exceed the longest request.
+	IdempotencyTTL   time.Duration
+	IdempotencyLease time.Duration
+}
+
+type HTTPConfig struct {
+	// ProxyHeader is only honoured for requests coming from TrustedProxies.
+	ProxyHeader    string
+	TrustedProxies []string
+}
+
+type AuthConfig struct {
+	TokenSecret          []byte
+	AccessTokenTTL       time.Duration
+	RefreshTokenTTL      time.Duration
+	BcryptCost           int
+	RequireVerifiedEmail bool
+	VerificationTTL      time.Duration
+	VerificationResend   time.Duration
+	ResetTTL             time.Duration
+	ResetEmailLimit      int
+	ResetIPLimit         int
+	ResetLimitWindow     time.Duration
+	TOTPIssuer           string
+	RequireAdminMFA      bool
+}
+
+type RateLimitConfig struct {
+	Store string
+	// APIKeys are the SHA-256 hashes, in hex, of the API keys issued to the
+	// clients, which get their own buckets.
+	APIKeys    []string
+	CreateUser RateLimit
+	GetUser    RateLimit
+	GraphQL    RateLimit
+	// MFALogin applies to the second step of the logins of each user.
+	MFALogin RateLimit
+}
+
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// GRPCConfig enables the gRPC API when Port is set. Callers authenticate
+// with Token, which is shared between the internal services.
+type GRPCConfig struct {
+	Port  string
+	Token string
+}
+
+// GraphQLConfig bounds the cost of a query before it is executed.
+type GraphQLConfig struct {
+	MaxDepth      int
+	MaxComplexity int
+}
+
+// OutboxConfig drives the relay publishing the user events written in the
+// outbox table. Publisher is log or file.
+type OutboxConfig struct {
+	RelayEnabled bool
+	Publisher    string
+	FileDir      string
+	Interval     time.Duration
+	BatchSize    int
+	MaxBackoff   time.Duration
+	Retention    time.Duration
+}
+
+// WebhookConfig drives the dispatcher sending the webhook deliveries.
+type WebhookConfig struct {
+	DispatcherEnabled bool
+	Interval          time.Duration
+	BatchSize         int
+	Timeout           time.Duration
+	MaxAttempts       int
+	MaxBackoff        time.Duration
+	// DisableAfter is the number of failed attempts in a row after which a
+	// subscription is disabled.
+	DisableAfter int
+	// AllowPrivateTargets lets subscriptions and deliveries reach addresses
+	// that aren't public.
+	AllowPrivateTargets bool
+}
+
+// SSEConfig drives the stream of user events.
+type SSEConfig struct {
+	PollInterval time.Duration
+	Heartbeat    time.Duration
+}
+
+// PIIConfig drives the worker re-encrypting the pii after a rotation of
+// the keyring. The keyring itself is read by the database package, from
+// the file named by PII_KEYRING_FILE.
+type PIIConfig struct {
+	ReencryptEnabled   bool
+	ReencryptInterval  time.Duration
+	ReencryptBatchSize int
+}
+
+// DateOfBirthConfig bounds the ages, in years, the dates of birth are
+// accepted for. Dates in the future are always rejected.
+type DateOfBirthConfig struct {
+	MinAge int
+	MaxAge int
+}
+
+// OpenAPIConfig enables checking the traffic of the versioned routes against
+// the OpenAPI document.
+type OpenAPIConfig struct {
+	ValidateRequests  bool
+	ValidateResponses bool
+}
+
+// LegacyConfig dates the unversioned routes. Both dates are announced in
+// the Deprecation and Sunset headers of every legacy response.
+type LegacyConfig struct {
+	DeprecatedAt time.Time
+	Sunset       time.Time
+}
+
+type MailConfig struct {
+	Driver       string
+	From         string
+	FileDir      string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+}
 
 func LoadEnvVariables() error {
 	err := godotenv.Load()
@@ -11,3 +171,153 @@ func LoadEnvVariables() error {
 
 	return nil
 }
+
+func Load() (*Config, error) {
+	p := &parser{}
+
+	cfg := &Config{
+		Port:    getString("PORT", "3000"),
+		BaseURL: getString("BASE_URL", "http://localhost:3000"),
+		HTTP: HTTPConfig{
+			ProxyHeader:    getString("HTTP_PROXY_HEADER", "X-Forwarded-For"),
+			TrustedProxies: getList("HTTP_TRUSTED_PROXIES"),
+		},
+		Auth: AuthConfig{
+			TokenSecret:          []byte(getString("AUTH_TOKEN_SECRET", "")),
+			AccessTokenTTL:       p.duration("AUTH_ACCESS_TOKEN_TTL", 15*time.Minute),
+			RefreshTokenTTL:      p.duration("AUTH_REFRESH_TOKEN_TTL", 30*24*time.Hour),
+			BcryptCost:           p.int("AUTH_BCRYPT_COST", 12),
+			RequireVerifiedEmail: p.bool("AUTH_REQUIRE_VERIFIED_EMAIL", true),
+			VerificationTTL:      p.duration("AUTH_EMAIL_VERIFICATION_TTL", 24*time.Hour),
+			VerificationResend:   p.duration("AUTH_EMAIL_VERIFICATION_RESEND_INTERVAL", time.Minute),
+			ResetTTL:             p.duration("AUTH_PASSWORD_RESET_TTL", 30*time.Minute),
+			ResetEmailLimit:      p.int("AUTH_PASSWORD_RESET_EMAIL_LIMIT", 3),
+			ResetIPLimit:         p.int("AUTH_PASSWORD_RESET_IP_LIMIT", 10),
+			ResetLimitWindow:     p.duration("AUTH_PASSWORD_RESET_LIMIT_WINDOW", time.Hour),
+			TOTPIssuer:           getString("AUTH_TOTP_ISSUER", "user_api"),
+			RequireAdminMFA:      p.bool("AUTH_REQUIRE_ADMIN_MFA", true),
+		},
+		Mail: MailConfig{
+			Driver:       getString("MAIL_DRIVER", "log"),
+			From:         getString("MAIL_FROM", "no-reply@localhost"),
+			FileDir:      getString("MAIL_FILE_DIR", "mail"),
+			SMTPHost:     getString("SMTP_HOST", ""),
+			SMTPPort:     p.int("SMTP_PORT", 587),
+			SMTPUsername: getString("SMTP_USERNAME", ""),
+			SMTPPassword: getString("SMTP_PASSWORD", ""),
+		},
+		RateLimit: RateLimitConfig{
+			Store:   getString("RATE_LIMIT_STORE", "memory"),
+			APIKeys: getList("RATE_LIMIT_API_KEYS"),
+		},
+		OpenAPI: OpenAPIConfig{
+			ValidateRequests:  p.bool("OPENAPI_VALIDATE_REQUESTS", false),
+			ValidateResponses: p.bool("OPENAPI_VALIDATE_RESPONSES", false),
+		},
+		GRPC: GRPCConfig{
+			Port:  getString("GRPC_PORT", ""),
+			Token: getString("GRPC_AUTH_TOKEN", ""),
+		},
+		GraphQL: GraphQLConfig{
+			MaxDepth:      p.int("GRAPHQL_MAX_DEPTH", 10),
+			MaxComplexity: p.int("GRAPHQL_MAX_COMPLEXITY", 1000),
+		},
+		Outbox: OutboxConfig{
+			RelayEnabled: p.bool("OUTBOX_RELAY_ENABLED", true),
+			Publisher:    getString("OUTBOX_PUBLISHER", "log"),
+			FileDir:      getString("OUTBOX_FILE_DIR", "events"),
+			Interval:     p.duration("OUTBOX_RELAY_INTERVAL", time.Second),
+			BatchSize:    p.int("OUTBOX_RELAY_BATCH_SIZE", 100),
+			MaxBackoff:   p.duration("OUTBOX_MAX_BACKOFF", 5*time.Minute),
+			Retention:    p.duration("OUTBOX_RETENTION", 7*24*time.Hour),
+		},
+		Webhook: WebhookConfig{
+			DispatcherEnabled:   p.bool("WEBHOOK_DISPATCHER_ENABLED", true),
+			Interval:            p.duration("WEBHOOK_DISPATCH_INTERVAL", time.Second),
+			BatchSize:           p.int("WEBHOOK_DISPATCH_BATCH_SIZE", 50),
+			Timeout:             p.duration("WEBHOOK_TIMEOUT", 10*time.Second),
+			MaxAttempts:         p.int("WEBHOOK_MAX_ATTEMPTS", 10),
+			MaxBackoff:          p.duration("WEBHOOK_MAX_BACKOFF", time.Hour),
+			DisableAfter:        p.int("WEBHOOK_DISABLE_AFTER", 20),
+			AllowPrivateTargets: p.bool("WEBHOOK_ALLOW_PRIVATE_TARGETS", false),
+		},
+		SSE: SSEConfig{
+			PollInterval: p.duration("SSE_POLL_INTERVAL", time.Second),
+			Heartbeat:    p.duration("SSE_HEARTBEAT_INTERVAL", 15*time.Second),
+		},
+		PII: PIIConfig{
+			ReencryptEnabled:   p.bool("PII_REENCRYPT_ENABLED", true),
+			ReencryptInterval:  p.duration("PII_REENCRYPT_INTERVAL", time.Minute),
+			ReencryptBatchSize: p.int("PII_REENCRYPT_BATCH_SIZE", 100),
+		},
+		DateOfBirth: DateOfBirthConfig{
+			MinAge: p.int("DATE_OF_BIRTH_MIN_AGE", 0),
+			MaxAge: p.int("DATE_OF_BIRTH_MAX_AGE", 130),
+		},
+		IdempotencyTTL:   p.duration("IDEMPOTENCY_TTL", 24*time.Hour),
+		IdempotencyLease: p.duration("IDEMPOTENCY_LEASE", time.Minute),
+	}
+
+	cfg.Legacy.DeprecatedAt = p.time("LEGACY_ROUTES_DEPRECATED_AT", time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC))
+	cfg.Legacy.Sunset = p.time("LEGACY_ROUTES_SUNSET", time.Date(2027, time.April, 19, 0, 0, 0, 0, time.UTC))
+	cfg.RateLimit.CreateUser = p.rateLimit("RATE_LIMIT_CREATE_USER", RateLimit{Requests: 10, Period: time.Minute})
+	cfg.RateLimit.GetUser = p.rateLimit("RATE_LIMIT_GET_USER", RateLimit{Requests: 60, Period: time.Minute})
+	cfg.RateLimit.GraphQL = p.rateLimit("RATE_LIMIT_GRAPHQL", RateLimit{Requests: 60, Period: time.Minute})
+	cfg.RateLimit.MFALogin = p.rateLimit("RATE_LIMIT_MFA_LOGIN", RateLimit{Requests: 10, Period: 15 * time.Minute})
+
+	if err := p.err(); err != nil {
+		return nil, err
+	}
+
+	for _, hash := range cfg.RateLimit.APIKeys {
+		if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("RATE_LIMIT_API_KEYS must hold SHA-256 hashes in hex, got %q", hash)
+		}
+	}
+
+	if len(cfg.Auth.TokenSecret) < 32 {
+		return nil, errors.New("AUTH_TOKEN_SECRET must be at least 32 bytes long")
+	}
+
+	if cfg.Auth.BcryptCost < bcrypt.MinCost || cfg.Auth.BcryptCost > bcrypt.MaxCost {
+		return nil, errors.New("AUTH_BCRYPT_COST is out of the range supported by bcrypt")
+	}
+
+	if cfg.DateOfBirth.MinAge < 0 || cfg.DateOfBirth.MaxAge < cfg.DateOfBirth.MinAge {
+		return nil, errors.New("DATE_OF_BIRTH_MIN_AGE must be positive and at most DATE_OF_BIRTH_MAX_AGE")
+	}
+
+	if cfg.GRPC.Port != "" && len(cfg.GRPC.Token) < 32 {
+		return nil, errors.New("GRPC_AUTH_TOKEN must be at least 32 bytes long when GRPC_PORT is set")
+	}
+
+	if cfg.Outbox.Interval <= 0 || cfg.Outbox.BatchSize <= 0 || cfg.Outbox.MaxBackoff <= 0 {
+		return nil, errors.New("OUTBOX_RELAY_INTERVAL, OUTBOX_RELAY_BATCH_SIZE and OUTBOX_MAX_BACKOFF must be positive")
+	}
+
+	if cfg.Webhook.Interval <= 0 || cfg.Webhook.BatchSize <= 0 || cfg.Webhook.MaxAttempts <= 0 || cfg.Webhook.MaxBackoff <= 0 || cfg.Webhook.DisableAfter <= 0 {
+		return nil, errors.New("WEBHOOK_DISPATCH_INTERVAL, WEBHOOK_DISPATCH_BATCH_SIZE, WEBHOOK_MAX_ATTEMPTS, WEBHOOK_MAX_BACKOFF and WEBHOOK_DISABLE_AFTER must be positive")
+	}
+
+	if cfg.SSE.PollInterval <= 0 || cfg.SSE.Heartbeat <= 0 {
+		return nil, errors.New("SSE_POLL_INTERVAL and SSE_HEARTBEAT_INTERVAL must be positive")
+	}
+
+	if cfg.RateLimit.Store != "memory" && cfg.RateLimit.Store != "postgres" {
+		return nil, fmt.Errorf("RATE_LIMIT_STORE must be memory or postgres, got %q", cfg.RateLimit.Store)
+	}
+
+	if cfg.Outbox.Publisher != "log" && cfg.Outbox.Publisher != "file" {
+		return nil, fmt.Errorf("OUTBOX_PUBLISHER must be log or file, got %q", cfg.Outbox.Publisher)
+	}
+
+	if cfg.Mail.Driver != "smtp" && cfg.Mail.Driver != "file" && cfg.Mail.Driver != "log" {
+		return nil, fmt.Errorf("MAIL_DRIVER must be smtp, file or log, got %q", cfg.Mail.Driver)
+	}
+
+	if cfg.Mail.Driver == "smtp" && cfg.Mail.SMTPHost == "" {
+		return nil, errors.New("SMTP_HOST is required when MAIL_DRIVER is smtp")
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func getString(key, fallback string) string {
+	value, ok := os.LookupEnv(key)
+
+	if !ok || value == "" {
+		return fallback
+	}
+
+	return value
+}
+
+func getInt(key string, fallback int) (int, error) {
+	value := os.Getenv(key)
+
+	if value == "" {
+		return fallback, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer, got %q", key, value)
+	}
+
+	return parsed, nil
+}
+
+func getDuration(key string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+
+	if value == "" {
+		return fallback, nil
+	}
+
+	parsed, err := time.ParseDuration(value)
+
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a duration such as 30s or 1h, got %q", key, value)
+	}
+
+	return parsed, nil
+}
+
+func getBool(key string, fallback bool) (bool, error) {
+	value := os.Getenv(key)
+
+	if value == "" {
+		return fallback, nil
+	}
+
+	parsed, err := strconv.ParseBool(value)
+
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false, got %q", key, value)
+	}
+
+	return parsed, nil
+}
+
+func getTime(key string, fallback time.Time) (time.Time, error) {
+	value := os.Getenv(key)
+
+	if value == "" {
+		return fallback, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 timestamp, got %q", key, value)
+	}
+
+	return parsed, nil
+}
+
+func getList(key string) []string {
+	var values []string
+
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+// getRateLimit parses "<requests>/<period>" with an optional ",<burst>",
+// e.g. "10/1m" or "10/1m,20".
+func getRateLimit(key string, fallback RateLimit) (RateLimit, error) {
+	value := os.Getenv(key)
+
+	if value == "" {
+		return fallback, nil
+	}
+
+	invalid := fmt.Errorf("%s must look like <requests>/<period>[,<burst>], got %q", key, value)
+
+	spec, burst, hasBurst := strings.Cut(value, ",")
+
+	requests, period, found := strings.Cut(spec, "/")
+
+	if !found {
+		return RateLimit{}, invalid
+	}
+
+	var limit RateLimit
+	var err error
+
+	limit.Requests, err = strconv.Atoi(strings.TrimSpace(requests))
+
+	if err != nil || limit.Requests <= 0 {
+		return RateLimit{}, invalid
+	}
+
+	limit.Period, err = time.ParseDuration(strings.TrimSpace(period))
+
+	if err != nil || limit.Period <= 0 {
+		return RateLimit{}, invalid
+	}
+
+	if hasBurst {
+		limit.Burst, err = strconv.Atoi(strings.TrimSpace(burst))
+
+		if err != nil || limit.Burst <= 0 {
+			return RateLimit{}, invalid
+		}
+	}
+
+	return limit, nil
+}
+
+// parser reads the variables of the config, and remembers the malformed
+// ones, so Load reports them all at once instead of falling back to the
+// defaults.
+type parser struct {
+	errs []error
+}
+
+func (p *parser) check(err error) {
+	if err != nil {
+		p.errs = append(p.errs, err)
+	}
+}
+
+func (p *parser) int(key string, fallback int) int {
+	value, err := getInt(key, fallback)
+
+	p.check(err)
+
+	return value
+}
+
+func (p *parser) duration(key string, fallback time.Duration) time.Duration {
+	value, err := getDuration(key, fallback)
+
+	p.check(err)
+
+	return value
+}
+
+func (p *parser) bool(key string, fallback bool) bool {
+	value, err := getBool(key, fallback)
+
+	p.check(err)
+
+	return value
+}
+
+func (p *parser) time(key string, fallback time.Time) time.Time {
+	value, err := getTime(key, fallback)
+
+	p.check(err)
+
+	return value
+}
+
+func (p *parser) rateLimit(key string, fallback RateLimit) RateLimit {
+	value, err := getRateLimit(key, fallback)
+
+	p.check(err)
+
+	return value
+}
+
+func (p *parser) err() error {
+	return errors.Join(p.errs...)
+}
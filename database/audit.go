@@ -0,0 +1,45 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/LucasAndFlores/user_api/internal/repository"
+	"gorm.io/gorm"
+)
+
+// auditTriggerStatements make audit_records append-only: the rows can be
+// inserted, but updating, deleting or truncating them fails. The only
+// exception is the erasure of a user's data, which may rewrite the changes
+// of the records, and nothing else, once it enabled the erasure setting of
+// its transaction.
+var auditTriggerStatements = []string{
+	fmt.Sprintf(`CREATE OR REPLACE FUNCTION audit_records_append_only() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP = 'UPDATE' AND current_setting('%s', true) = 'on'
+		AND (NEW.id, NEW.user_external_id, NEW.actor, NEW.request_id, NEW.action, NEW.created_at)
+			IS NOT DISTINCT FROM (OLD.id, OLD.user_external_id, OLD.actor, OLD.request_id, OLD.action, OLD.created_at) THEN
+		RETURN NEW;
+	END IF;
+
+	RAISE EXCEPTION 'audit_records is append-only';
+END;
+$$ LANGUAGE plpgsql`, repository.AUDIT_ERASURE_SETTING),
+	`DROP TRIGGER IF EXISTS audit_records_append_only ON audit_records`,
+	`CREATE TRIGGER audit_records_append_only BEFORE UPDATE OR DELETE ON audit_records FOR EACH ROW EXECUTE FUNCTION audit_records_append_only()`,
+	`DROP TRIGGER IF EXISTS audit_records_no_truncate ON audit_records`,
+	`CREATE TRIGGER audit_records_no_truncate BEFORE TRUNCATE ON audit_records FOR EACH STATEMENT EXECUTE FUNCTION audit_records_append_only()`,
+}
+
+func protectAuditRecords(database *gorm.DB) error {
+	return database.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range auditTriggerStatements {
+			err := tx.Exec(statement).Error
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
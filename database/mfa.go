@@ -0,0 +1,30 @@
+package database
+
+import (
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/LucasAndFlores/user_api/internal/pii"
+	"gorm.io/gorm"
+)
+
+// encryptTOTPSecrets encrypts the TOTP secrets stored before they were pii.
+func encryptTOTPSecrets(database *gorm.DB) error {
+	return runOnce(database, "encrypt_totp_secrets", func(tx *gorm.DB) error {
+		for {
+			var credentials []model.TOTPCredential
+
+			err := tx.Where("secret NOT LIKE ?", pii.FORMAT_VERSION+pii.SEPARATOR+"%").Order("id").Limit(ENCRYPTION_BATCH_SIZE).Find(&credentials).Error
+
+			if err != nil || len(credentials) == 0 {
+				return err
+			}
+
+			for i := range credentials {
+				err = tx.Model(&credentials[i]).Select("secret").UpdateColumns(&credentials[i]).Error
+
+				if err != nil {
+					return err
+				}
+			}
+		}
+	})
+}
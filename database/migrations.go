@@ -0,0 +1,25 @@
+package database
+
+import "gorm.io/gorm"
+
+// runOnce applies the data migration identified by name unless it already
+// ran, as recorded in schema_migrations. The record and the migration share
+// a transaction, so a replica starting at the same time waits for it and
+// then skips it.
+func runOnce(database *gorm.DB, name string, migrate func(tx *gorm.DB) error) error {
+	err := database.Exec("CREATE TABLE IF NOT EXISTS schema_migrations (name text PRIMARY KEY, applied_at timestamp with time zone NOT NULL)").Error
+
+	if err != nil {
+		return err
+	}
+
+	return database.Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec("INSERT INTO schema_migrations (name, applied_at) VALUES (?, now()) ON CONFLICT (name) DO NOTHING", name)
+
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		return migrate(tx)
+	})
+}
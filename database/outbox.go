@@ -0,0 +1,16 @@
+package database
+
+import "gorm.io/gorm"
+
+// addOutboxTransactionIds records the transaction writing each outbox event,
+// which orders the event stream (see repository.StreamPosition). Postgres
+// fills it in; the events stored before it get the id of the migration.
+func addOutboxTransactionIds(database *gorm.DB) error {
+	err := database.Exec("ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS transaction_id xid8 NOT NULL DEFAULT pg_current_xact_id()").Error
+
+	if err != nil {
+		return err
+	}
+
+	return database.Exec("CREATE INDEX IF NOT EXISTS idx_outbox_events_stream ON outbox_events (transaction_id, id)").Error
+}
@@ -0,0 +1,182 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/LucasAndFlores/user_api/internal/pii"
+	"gorm.io/gorm"
+)
+
+const ENCRYPTION_BATCH_SIZE = 100
+
+// loadKeyring makes the keys of the keyring file encrypt the pii columns.
+func loadKeyring() error {
+	path := os.Getenv("PII_KEYRING_FILE")
+
+	if path == "" {
+		return errors.New("PII_KEYRING_FILE must be set")
+	}
+
+	keys, err := pii.NewFileKeyring(path)
+
+	if err != nil {
+		return err
+	}
+
+	pii.Use(pii.NewCipher(keys))
+
+	return nil
+}
+
+// encryptPlaintextPII encrypts the pii stored before the encryption
+// existed, and computes the blind indexes of the emails. It runs once,
+// before the migration of the tables, as the unique index on the blind
+// indexes requires them. It fails, changing nothing, when emails only differ
+// by case, as the index would then not be unique.
+func encryptPlaintextPII(database *gorm.DB) error {
+	if !database.Migrator().HasTable(&model.User{}) || database.Migrator().HasColumn(&model.User{}, "email_index") {
+		return nil
+	}
+
+	return database.Transaction(func(tx *gorm.DB) error {
+		var collisions int64
+
+		// the old unique constraint told the cases apart, the blind index
+		// doesn't
+		err := tx.Raw("SELECT count(*) FROM (SELECT lower(email) FROM users GROUP BY lower(email) HAVING count(*) > 1) AS collisions").Scan(&collisions).Error
+
+		if err != nil {
+			return err
+		}
+
+		if collisions != 0 {
+			return fmt.Errorf("%d emails are registered more than once with different cases, which the encryption of the emails can't keep unique: merge or change these users, listed by SELECT lower(email) FROM users GROUP BY lower(email) HAVING count(*) > 1, and restart", collisions)
+		}
+
+		err = tx.Exec("ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key").Error
+
+		if err != nil {
+			return err
+		}
+
+		err = tx.Exec("ALTER TABLE users ADD COLUMN email_index text").Error
+
+		if err != nil {
+			return err
+		}
+
+		for _, table := range []string{"users", "user_versions"} {
+			if !tx.Migrator().HasTable(table) {
+				continue
+			}
+
+			// the serializer reads the plaintext times as RFC 3339
+			err = tx.Exec(`ALTER TABLE ` + table + ` ALTER COLUMN date_of_birth TYPE text
+USING to_char(date_of_birth AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"')`).Error
+
+			if err != nil {
+				return err
+			}
+		}
+
+		for {
+			var users []model.User
+
+			err = tx.Where("email_index IS NULL").Order("id").Limit(ENCRYPTION_BATCH_SIZE).Find(&users).Error
+
+			if err != nil || len(users) == 0 {
+				break
+			}
+
+			for i := range users {
+				users[i].EmailIndex = pii.BlindIndex(users[i].Email)
+
+				err = tx.Model(&users[i]).Select("email", "email_index", "date_of_birth").UpdateColumns(&users[i]).Error
+
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if err != nil || !tx.Migrator().HasTable(&model.UserVersion{}) {
+			return err
+		}
+
+		for {
+			var versions []model.UserVersion
+
+			err = tx.Where("email NOT LIKE ?", pii.FORMAT_VERSION+pii.SEPARATOR+"%").Order("id").Limit(ENCRYPTION_BATCH_SIZE).Find(&versions).Error
+
+			if err != nil || len(versions) == 0 {
+				return err
+			}
+
+			for i := range versions {
+				err = tx.Model(&versions[i]).Select("email", "date_of_birth").UpdateColumns(&versions[i]).Error
+
+				if err != nil {
+					return err
+				}
+			}
+		}
+	})
+}
+
+// rewriteDatesOfBirth writes as YYYY-MM-DD the dates of birth that
+// encryptPlaintextPII stored as RFC 3339 times. Those times are UTC, so
+// their date is the UTC day the old conversion kept.
+func rewriteDatesOfBirth(database *gorm.DB) error {
+	return runOnce(database, "rewrite_dates_of_birth", func(tx *gorm.DB) error {
+		var lastId int
+
+		for {
+			var users []model.User
+
+			err := tx.Where("id > ?", lastId).Order("id").Limit(ENCRYPTION_BATCH_SIZE).Find(&users).Error
+
+			if err != nil {
+				return err
+			}
+
+			if len(users) == 0 {
+				break
+			}
+
+			for i := range users {
+				err = tx.Model(&users[i]).Select("date_of_birth").UpdateColumns(&users[i]).Error
+
+				if err != nil {
+					return err
+				}
+			}
+
+			lastId = users[len(users)-1].Id
+		}
+
+		lastId = 0
+
+		for {
+			var versions []model.UserVersion
+
+			err := tx.Where("id > ?", lastId).Order("id").Limit(ENCRYPTION_BATCH_SIZE).Find(&versions).Error
+
+			if err != nil || len(versions) == 0 {
+				return err
+			}
+
+			for i := range versions {
+				err = tx.Model(&versions[i]).Select("date_of_birth").UpdateColumns(&versions[i]).Error
+
+				if err != nil {
+					return err
+				}
+			}
+
+			lastId = versions[len(versions)-1].Id
+		}
+	})
+}
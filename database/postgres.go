@@ -3,6 +3,7 @@ package database
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/LucasAndFlores/user_api/internal/model"
 	"gorm.io/driver/postgres"
@@ -18,13 +19,75 @@ func ConnectDatabase() (*gorm.DB, error) {
 		os.Getenv("DB_PORT"),
 	)
 
-	database, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	database, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		// Postgres keeps microseconds, so the timestamps GORM sets read back
+		// the same
+		NowFunc: func() time.Time { return time.Now().Truncate(time.Microsecond) },
+	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	database.AutoMigrate(&model.User{})
+	err = loadKeyring()
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = encryptPlaintextPII(database)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = addUserTimestamps(database)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.AutoMigrate(&model.User{}, &model.RefreshToken{}, &model.EmailVerification{}, &model.PasswordReset{}, &model.TOTPCredential{}, &model.RecoveryCode{}, &model.RateLimitBucket{}, &model.IdempotencyKey{}, &model.OutboxEvent{}, &model.WebhookSubscription{}, &model.WebhookDelivery{}, &model.AuditRecord{}, &model.UserVersion{}, &model.ErasedUser{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = addOutboxTransactionIds(database)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = protectAuditRecords(database)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = backfillUserVersions(database)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = encryptTOTPSecrets(database)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = rewriteDatesOfBirth(database)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = encryptWebhookSecrets(database)
+
+	if err != nil {
+		return nil, err
+	}
 
 	return database, nil
 }
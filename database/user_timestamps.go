@@ -0,0 +1,35 @@
+package database
+
+import (
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"gorm.io/gorm"
+)
+
+// addUserTimestamps adds the timestamps of the users stored before they
+// existed, before the migration of the tables makes them required. They are
+// taken from the audit trail when it has the user, and are the time of the
+// migration otherwise.
+func addUserTimestamps(database *gorm.DB) error {
+	if !database.Migrator().HasTable(&model.User{}) || database.Migrator().HasColumn(&model.User{}, "created_at") {
+		return nil
+	}
+
+	return database.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("ALTER TABLE users ADD COLUMN created_at timestamp with time zone, ADD COLUMN updated_at timestamp with time zone").Error
+
+		if err != nil {
+			return err
+		}
+
+		err = tx.Exec("UPDATE users SET created_at = now(), updated_at = now()").Error
+
+		if err != nil || !tx.Migrator().HasTable(&model.AuditRecord{}) {
+			return err
+		}
+
+		return tx.Exec(`UPDATE users SET created_at = COALESCE(trail.created_at, users.created_at), updated_at = trail.updated_at
+FROM (SELECT user_external_id, MIN(created_at) FILTER (WHERE action = ?) AS created_at, MAX(created_at) AS updated_at
+FROM audit_records GROUP BY user_external_id) AS trail
+WHERE trail.user_external_id = users.external_id`, model.AUDIT_ACTION_CREATE).Error
+	})
+}
@@ -0,0 +1,14 @@
+package database
+
+import "gorm.io/gorm"
+
+// backfillUserVersions records the current version of the users created
+// before the history existed. Their past versions are unknown, so they only
+// have a history from the time of the backfill.
+func backfillUserVersions(database *gorm.DB) error {
+	return runOnce(database, "backfill_user_versions", func(tx *gorm.DB) error {
+		return tx.Exec(`INSERT INTO user_versions (user_external_id, version, name, email, date_of_birth, role, email_verified_at, deleted, recorded_at)
+SELECT external_id, version, name, email, date_of_birth, role, email_verified_at, false, now() FROM users
+WHERE NOT EXISTS (SELECT 1 FROM user_versions WHERE user_versions.user_external_id = users.external_id)`).Error
+	})
+}
@@ -0,0 +1,31 @@
+package database
+
+import (
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/LucasAndFlores/user_api/internal/pii"
+	"gorm.io/gorm"
+)
+
+// encryptWebhookSecrets encrypts the signing secrets of the webhooks stored
+// before they were encrypted.
+func encryptWebhookSecrets(database *gorm.DB) error {
+	return runOnce(database, "encrypt_webhook_secrets", func(tx *gorm.DB) error {
+		for {
+			var subscriptions []model.WebhookSubscription
+
+			err := tx.Where("secret NOT LIKE ?", pii.FORMAT_VERSION+pii.SEPARATOR+"%").Order("id").Limit(ENCRYPTION_BATCH_SIZE).Find(&subscriptions).Error
+
+			if err != nil || len(subscriptions) == 0 {
+				return err
+			}
+
+			for i := range subscriptions {
+				err = tx.Model(&subscriptions[i]).Select("secret").UpdateColumns(&subscriptions[i]).Error
+
+				if err != nil {
+					return err
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	// ANONYMOUS is the actor of unauthenticated requests, such as a sign up
+	// or a password reset.
+	ANONYMOUS = "anonymous"
+	// SYSTEM is the actor of changes made outside of a request.
+	SYSTEM = "system"
+)
+
+// Actor is who made a change, and in which request.
+type Actor struct {
+	Principal string
+	RequestId string
+}
+
+type actorKey struct{}
+
+// UserPrincipal names an authenticated user with their role, as the role
+// they had when acting may change later.
+func UserPrincipal(role string, externalId uuid.UUID) string {
+	return fmt.Sprintf("%v:%v", role, externalId)
+}
+
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Bind stores the actor in the locals of the request. Fiber keeps the locals
+// in the fasthttp request context, so the services called with fi.Context()
+// find it with ActorFrom.
+func Bind(fi *fiber.Ctx, actor Actor) {
+	fi.Locals(actorKey{}, actor)
+}
+
+// ActorFrom returns the actor stored with WithActor or Bind, or SYSTEM.
+func ActorFrom(ctx context.Context) Actor {
+	actor, ok := ctx.Value(actorKey{}).(Actor)
+
+	if !ok {
+		return Actor{Principal: SYSTEM}
+	}
+
+	return actor
+}
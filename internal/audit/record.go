@@ -0,0 +1,125 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/google/uuid"
+)
+
+// ERASED replaces every value of the records of a user whose data was
+// erased.
+const ERASED = "[erased]"
+
+// MASKED replaces the values of the sensitive fields, which only tells that
+// they changed.
+const MASKED = "[masked]"
+
+type field struct {
+	name      string
+	sensitive bool
+	value     func(*model.User) interface{}
+}
+
+// fields are the audited fields of a user. The version is left out, as
+// every change bumps it. The records can't be rewritten, so the personal
+// data encrypted on the user is masked: its history is in the versions.
+var fields = []field{
+	{name: "name", value: func(u *model.User) interface{} { return u.Name }},
+	{name: "email", sensitive: true, value: func(u *model.User) interface{} { return u.Email }},
+	{name: "date_of_birth", sensitive: true, value: func(u *model.User) interface{} { return u.DateOfBirth.String() }},
+	{name: "password_hash", sensitive: true, value: func(u *model.User) interface{} { return u.PasswordHash }},
+	{name: "role", value: func(u *model.User) interface{} { return u.Role }},
+	{name: "email_verified_at", value: func(u *model.User) interface{} { return formatTime(u.EmailVerifiedAt) }},
+}
+
+// formatTime makes equal instants compare equal, whatever their location.
+func formatTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// Diff lists the fields that differ between before and after. A nil user,
+// before a creation or after a deletion, has no value for any field.
+func Diff(before *model.User, after *model.User) []model.AuditChange {
+	changes := []model.AuditChange{}
+
+	for _, f := range fields {
+		var previous, current interface{}
+
+		if before != nil {
+			previous = f.value(before)
+		}
+
+		if after != nil {
+			current = f.value(after)
+		}
+
+		if previous == current {
+			continue
+		}
+
+		if f.sensitive {
+			previous, current = mask(previous), mask(current)
+		}
+
+		changes = append(changes, model.AuditChange{Field: f.name, Before: previous, After: current})
+	}
+
+	return changes
+}
+
+func mask(value interface{}) interface{} {
+	if value == nil || value == "" {
+		return value
+	}
+
+	return MASKED
+}
+
+// NewRecord describes the change of a user from before to after, made by the
+// actor of ctx.
+func NewRecord(ctx context.Context, action string, before *model.User, after *model.User) (*model.AuditRecord, error) {
+	changes, err := json.Marshal(Diff(before, after))
+
+	if err != nil {
+		return nil, err
+	}
+
+	user := after
+
+	if user == nil {
+		user = before
+	}
+
+	actor := ActorFrom(ctx)
+
+	return &model.AuditRecord{
+		UserExternalId: user.ExternalId,
+		Actor:          actor.Principal,
+		RequestId:      actor.RequestId,
+		Action:         action,
+		Changes:        changes,
+		CreatedAt:      time.Now(),
+	}, nil
+}
+
+// NewErasureRecord describes the erasure of a user's data, which records no
+// change so that none of the data remains.
+func NewErasureRecord(ctx context.Context, externalId uuid.UUID) *model.AuditRecord {
+	actor := ActorFrom(ctx)
+
+	return &model.AuditRecord{
+		UserExternalId: externalId,
+		Actor:          actor.Principal,
+		RequestId:      actor.RequestId,
+		Action:         model.AUDIT_ACTION_ERASE,
+		Changes:        []byte("[]"),
+		CreatedAt:      time.Now(),
+	}
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const MIN_PASSWORD_LENGTH = 10
+
+type PasswordHasher interface {
+	Hash(string) (string, error)
+	Compare(hash string, password string) bool
+}
+
+type BcryptHasher struct {
+	cost int
+	// dummyHash is compared against when there is no stored hash, so that a
+	// login for an unknown email costs the same as one with a wrong password.
+	dummyHash []byte
+}
+
+func NewBcryptHasher(cost int) (PasswordHasher, error) {
+	dummy, err := bcrypt.GenerateFromPassword([]byte("dummy-password"), cost)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &BcryptHasher{cost: cost, dummyHash: dummy}, nil
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+func (h *BcryptHasher) Compare(hash string, password string) bool {
+	if hash == "" {
+		bcrypt.CompareHashAndPassword(h.dummyHash, []byte(password))
+		return false
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// IsStrongPassword requires a minimum length and at least three of the four
+// character classes (lowercase, uppercase, digits and symbols).
+func IsStrongPassword(password string) bool {
+	if len([]rune(password)) < MIN_PASSWORD_LENGTH {
+		return false
+	}
+
+	// bcrypt silently truncates anything beyond 72 bytes
+	if len(password) > 72 {
+		return false
+	}
+
+	var lower, upper, digit, symbol bool
+
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	classes := 0
+
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+
+	return classes >= 3
+}
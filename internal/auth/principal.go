@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const PRINCIPAL_KEY = "principal"
+
+// Principal is the authenticated caller of a request, taken from the claims
+// of its access token.
+type Principal struct {
+	ExternalId uuid.UUID
+	Role       string
+	// MFA tells whether the session was opened with a second factor.
+	MFA    bool
+	Scopes []string
+}
+
+func (c *AccessClaims) Principal() (*Principal, error) {
+	id, err := uuid.Parse(c.Subject)
+
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	return &Principal{ExternalId: id, Role: c.Role, MFA: c.MFA, Scopes: strings.Fields(c.Scope)}, nil
+}
+
+// PrincipalFrom returns the principal stored by the authentication
+// middleware, or nil for anonymous requests.
+func PrincipalFrom(fi *fiber.Ctx) *Principal {
+	principal, _ := fi.Locals(PRINCIPAL_KEY).(*Principal)
+
+	return principal
+}
@@ -0,0 +1,28 @@
+package auth
+
+import "github.com/LucasAndFlores/user_api/internal/model"
+
+const (
+	// SCOPE_READ_EMAIL and SCOPE_READ_DATE_OF_BIRTH reveal the pii of other
+	// users, which is masked otherwise.
+	SCOPE_READ_EMAIL         = "users:read_email"
+	SCOPE_READ_DATE_OF_BIRTH = "users:read_date_of_birth"
+)
+
+// ROLE_SCOPES are the scopes granted to the access tokens of each role.
+// Support staff read the users without their pii.
+var ROLE_SCOPES = map[string][]string{
+	model.ROLE_ADMIN:   {SCOPE_READ_EMAIL, SCOPE_READ_DATE_OF_BIRTH},
+	model.ROLE_SUPPORT: {},
+}
+
+// HasScope tells whether the principal was granted the scope.
+func (p *Principal) HasScope(scope string) bool {
+	for _, granted := range p.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const ACCESS_TOKEN_TYPE = "access"
+
+var ErrInvalidToken = errors.New("invalid token")
+
+type AccessClaims struct {
+	Type string `json:"typ"`
+	Role string `json:"role"`
+	MFA  bool   `json:"mfa"`
+	// Scope is the space separated list of the scopes of the role.
+	Scope string `json:"scope,omitempty"`
+	jwt.RegisteredClaims
+}
+
+type TokenIssuer struct {
+	secret    []byte
+	accessTTL time.Duration
+}
+
+func NewTokenIssuer(secret []byte, accessTTL time.Duration) *TokenIssuer {
+	return &TokenIssuer{secret: secret, accessTTL: accessTTL}
+}
+
+func (t *TokenIssuer) AccessTokenTTL() time.Duration {
+	return t.accessTTL
+}
+
+func (t *TokenIssuer) IssueAccessToken(subject uuid.UUID, role string, mfa bool) (string, error) {
+	now := time.Now()
+
+	claims := AccessClaims{
+		Type:  ACCESS_TOKEN_TYPE,
+		Role:  role,
+		MFA:   mfa,
+		Scope: strings.Join(ROLE_SCOPES[role], " "),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(t.accessTTL)),
+			ID:        uuid.NewString(),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(t.secret)
+}
+
+func (t *TokenIssuer) ParseAccessToken(token string) (*AccessClaims, error) {
+	var claims AccessClaims
+
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return t.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}))
+
+	if err != nil || claims.Type != ACCESS_TOKEN_TYPE {
+		return nil, ErrInvalidToken
+	}
+
+	return &claims, nil
+}
+
+// NewOpaqueToken returns a random token to hand to the client together with
+// the hash that should be persisted in its place.
+func NewOpaqueToken() (string, string, error) {
+	buf := make([]byte, 32)
+
+	_, err := rand.Read(buf)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	return token, HashOpaqueToken(token), nil
+}
+
+func HashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// PurposeClaims back the single-purpose tokens sent by email (verification,
+// password reset, ...). The token id lets the caller persist and consume the
+// token exactly once.
+type PurposeClaims struct {
+	Type string `json:"typ"`
+	jwt.RegisteredClaims
+}
+
+func (t *TokenIssuer) IssuePurposeToken(purpose string, subject uuid.UUID, tokenId uuid.UUID, ttl time.Duration) (string, error) {
+	now := time.Now()
+
+	claims := PurposeClaims{
+		Type: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			ID:        tokenId.String(),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(t.secret)
+}
+
+// ParsePurposeToken returns the subject and token id of a valid, unexpired
+// token issued for purpose.
+func (t *TokenIssuer) ParsePurposeToken(purpose string, token string) (uuid.UUID, uuid.UUID, error) {
+	var claims PurposeClaims
+
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return t.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}))
+
+	if err != nil || claims.Type != purpose {
+		return uuid.Nil, uuid.Nil, ErrInvalidToken
+	}
+
+	subject, err := uuid.Parse(claims.Subject)
+
+	if err != nil {
+		return uuid.Nil, uuid.Nil, ErrInvalidToken
+	}
+
+	tokenId, err := uuid.Parse(claims.ID)
+
+	if err != nil {
+		return uuid.Nil, uuid.Nil, ErrInvalidToken
+	}
+
+	return subject, tokenId, nil
+}
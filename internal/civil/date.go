@@ -0,0 +1,127 @@
+package civil
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DATE_LAYOUT is the ISO 8601 calendar date, which every Date is written as.
+const DATE_LAYOUT = "2006-01-02"
+
+var ErrInvalidDate = errors.New("invalid date, expected YYYY-MM-DD or an RFC 3339 time")
+
+// Date is a calendar date, without a time of day or a time zone, such as a
+// date of birth. The zero Date is 0000-00-00.
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// ParseDate reads a date written as YYYY-MM-DD, or as an RFC 3339 time, in
+// which case the date is the one of the time in its own offset.
+func ParseDate(value string) (Date, error) {
+	t, err := time.Parse(DATE_LAYOUT, value)
+
+	if err == nil {
+		return DateOf(t), nil
+	}
+
+	t, err = time.Parse(time.RFC3339, value)
+
+	if err != nil {
+		return Date{}, ErrInvalidDate
+	}
+
+	return DateOf(t), nil
+}
+
+// DateOf returns the date of t in its location.
+func DateOf(t time.Time) Date {
+	year, month, day := t.Date()
+
+	return Date{Year: year, Month: month, Day: day}
+}
+
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+func (d Date) IsZero() bool {
+	return d == Date{}
+}
+
+func (d Date) Before(other Date) bool {
+	if d.Year != other.Year {
+		return d.Year < other.Year
+	}
+
+	if d.Month != other.Month {
+		return d.Month < other.Month
+	}
+
+	return d.Day < other.Day
+}
+
+func (d Date) After(other Date) bool {
+	return other.Before(d)
+}
+
+// YearsUntil counts the anniversaries of d up to other, like an age. People
+// born on February 29 get a year older on March 1 of the common years.
+func (d Date) YearsUntil(other Date) int {
+	years := other.Year - d.Year
+
+	if other.Month < d.Month || other.Month == d.Month && other.Day < d.Day {
+		years--
+	}
+
+	return years
+}
+
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Date) UnmarshalText(text []byte) error {
+	date, err := ParseDate(string(text))
+
+	if err != nil {
+		return err
+	}
+
+	*d = date
+
+	return nil
+}
+
+// GormDataType stores dates in date columns, unless a serializer, such as
+// the pii one, says otherwise.
+func (Date) GormDataType() string {
+	return "date"
+}
+
+func (d Date) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+func (d *Date) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*d = Date{}
+
+		return nil
+	case time.Time:
+		*d = DateOf(v)
+
+		return nil
+	case string:
+		return d.UnmarshalText([]byte(v))
+	case []byte:
+		return d.UnmarshalText(v)
+	}
+
+	return fmt.Errorf("unable to scan %T into a date", src)
+}
@@ -0,0 +1,129 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec reads and writes request and response bodies in one media type.
+// Every codec uses the json tags of the DTOs, so the field names don't
+// depend on the encoding.
+type Codec struct {
+	MediaType string
+	// Aliases are other media types accepted for the same encoding.
+	Aliases   []string
+	Marshal   func(interface{}) ([]byte, error)
+	Unmarshal func([]byte, interface{}) error
+}
+
+var JSON = &Codec{
+	MediaType: "application/json",
+	Marshal:   json.Marshal,
+	Unmarshal: json.Unmarshal,
+}
+
+var MessagePack = &Codec{
+	MediaType: "application/msgpack",
+	Aliases:   []string{"application/x-msgpack", "application/vnd.msgpack"},
+	Marshal:   marshalMessagePack,
+	Unmarshal: unmarshalMessagePack,
+}
+
+var CBOR = &Codec{
+	MediaType: "application/cbor",
+	Marshal:   marshalCBOR,
+	Unmarshal: unmarshalCBOR,
+}
+
+var XML = &Codec{
+	MediaType: "application/xml",
+	Aliases:   []string{"text/xml"},
+	Marshal:   marshalXML,
+	Unmarshal: unmarshalXML,
+}
+
+// CODECS lists the supported codecs, the preferred one first.
+var CODECS = []*Codec{JSON, MessagePack, CBOR, XML}
+
+// MediaTypes returns the main media type of every codec.
+func MediaTypes() []string {
+	mediaTypes := make([]string, len(CODECS))
+
+	for i, c := range CODECS {
+		mediaTypes[i] = c.MediaType
+	}
+
+	return mediaTypes
+}
+
+// ForMediaType finds the codec of a Content-Type header value, ignoring its
+// parameters. An empty value is taken as JSON.
+func ForMediaType(value string) (*Codec, bool) {
+	if value == "" {
+		return JSON, true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(value)
+
+	if err != nil {
+		return nil, false
+	}
+
+	for _, c := range CODECS {
+		if c.MediaType == mediaType {
+			return c, true
+		}
+
+		for _, alias := range c.Aliases {
+			if alias == mediaType {
+				return c, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+func marshalMessagePack(v interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	encoder := msgpack.NewEncoder(&buffer)
+	encoder.SetCustomStructTag("json")
+	encoder.UseCompactInts(true)
+
+	err := encoder.Encode(v)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func unmarshalMessagePack(data []byte, v interface{}) error {
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetCustomStructTag("json")
+
+	return decoder.Decode(v)
+}
+
+// cbor falls back to the json tags on its own; maps are decoded with string
+// keys so generic bodies look like the JSON ones.
+var cborDecoder, _ = cbor.DecOptions{
+	DefaultMapType: reflect.TypeOf(map[string]interface{}{}),
+}.DecMode()
+
+var cborEncoder, _ = cbor.EncOptions{Sort: cbor.SortCanonical}.EncMode()
+
+func marshalCBOR(v interface{}) ([]byte, error) {
+	return cborEncoder.Marshal(v)
+}
+
+func unmarshalCBOR(data []byte, v interface{}) error {
+	return cborDecoder.Unmarshal(data, v)
+}
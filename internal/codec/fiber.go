@@ -0,0 +1,72 @@
+package codec
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// Acceptable picks the codec the client prefers according to its Accept
+// header. A missing header or a wildcard gets JSON.
+func Acceptable(fi *fiber.Ctx) (*Codec, bool) {
+	var offers []string
+
+	for _, c := range CODECS {
+		offers = append(offers, c.MediaType)
+		offers = append(offers, c.Aliases...)
+	}
+
+	accepted := fi.Accepts(offers...)
+
+	if accepted == "" {
+		return nil, false
+	}
+
+	return ForMediaType(accepted)
+}
+
+// Request is the codec of the request body. Bodies in an unsupported media
+// type are read as JSON; middleware.Negotiate rejects them beforehand.
+func Request(fi *fiber.Ctx) *Codec {
+	c, ok := ForMediaType(fi.Get(fiber.HeaderContentType))
+
+	if !ok {
+		return JSON
+	}
+
+	return c
+}
+
+// Response is the codec of the response body, JSON when the client accepts
+// none of them.
+func Response(fi *fiber.Ctx) *Codec {
+	c, ok := Acceptable(fi)
+
+	if !ok {
+		return JSON
+	}
+
+	return c
+}
+
+// UNPARSABLE_BODY_MESSAGE answers the request bodies Decode fails on.
+const UNPARSABLE_BODY_MESSAGE = "unable to parse the body"
+
+// Decode reads the request body into v with the codec of its Content-Type.
+func Decode(fi *fiber.Ctx, v interface{}) error {
+	return Request(fi).Unmarshal(fi.Body(), v)
+}
+
+// Send answers with v in the media type negotiated with the client.
+func Send(fi *fiber.Ctx, status int, v interface{}) error {
+	c := Response(fi)
+
+	body, err := c.Marshal(v)
+
+	if err != nil {
+		return fi.Status(fiber.StatusInternalServerError).JSON(map[string]string{"message": "internal server error"})
+	}
+
+	fi.Vary(fiber.HeaderAccept)
+	fi.Set(fiber.HeaderContentType, c.MediaType)
+
+	return fi.Status(status).Send(body)
+}
@@ -0,0 +1,189 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// XML_ROOT names the root element of every XML response.
+const XML_ROOT = "response"
+
+// XML_ITEM names the elements of a list.
+const XML_ITEM = "item"
+
+// encoding/xml can't encode the map bodies of the services, so XML goes
+// through the JSON form of the value: objects become elements named after
+// their keys, lists repeat an item element and scalars become text.
+func marshalXML(v interface{}) ([]byte, error) {
+	encoded, err := json.Marshal(v)
+
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+
+	var value interface{}
+
+	err = decoder.Decode(&value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+
+	buffer.WriteString(xml.Header)
+
+	encoder := xml.NewEncoder(&buffer)
+
+	err = encodeElement(encoder, XML_ROOT, value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = encoder.Flush()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func encodeElement(encoder *xml.Encoder, name string, value interface{}) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+
+	err := encoder.EncodeToken(start)
+
+	if err != nil {
+		return err
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+
+		for key := range value {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			err = encodeElement(encoder, key, value[key])
+
+			if err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range value {
+			err = encodeElement(encoder, XML_ITEM, item)
+
+			if err != nil {
+				return err
+			}
+		}
+	case nil:
+	default:
+		err = encoder.EncodeToken(xml.CharData(fmt.Sprint(value)))
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return encoder.EncodeToken(start.End())
+}
+
+// unmarshalXML reads the children of the root element, whatever its name,
+// as the fields of an object and decodes that object like a JSON one. Text
+// only elements are strings; repeated elements become a list.
+func unmarshalXML(data []byte, v interface{}) error {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	for {
+		token, err := decoder.Token()
+
+		if err == io.EOF {
+			return errors.New("xml: missing root element")
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if _, ok := token.(xml.StartElement); ok {
+			break
+		}
+	}
+
+	value, err := decodeElement(decoder)
+
+	if err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(value)
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(encoded, v)
+}
+
+func decodeElement(decoder *xml.Decoder) (interface{}, error) {
+	var text strings.Builder
+
+	var children map[string]interface{}
+
+	for {
+		token, err := decoder.Token()
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			child, err := decodeElement(decoder)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if children == nil {
+				children = map[string]interface{}{}
+			}
+
+			name := token.Name.Local
+
+			switch existing := children[name].(type) {
+			case nil:
+				children[name] = child
+			case []interface{}:
+				children[name] = append(existing, child)
+			default:
+				children[name] = []interface{}{existing, child}
+			}
+		case xml.CharData:
+			text.Write(token)
+		case xml.EndElement:
+			if children != nil {
+				return children, nil
+			}
+
+			return strings.TrimSpace(text.String()), nil
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/LucasAndFlores/user_api/internal/service"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type AuditController interface {
+	HandleUserAuditTrail(*fiber.Ctx) error
+}
+
+type AuditTrailController struct {
+	service service.AuditService
+}
+
+func NewAuditController(s service.AuditService) AuditController {
+	return &AuditTrailController{service: s}
+}
+
+func (c *AuditTrailController) HandleUserAuditTrail(fi *fiber.Ctx) error {
+	uuid, err := uuid.Parse(fi.Params("id"))
+
+	if err != nil {
+		return fi.Status(fiber.StatusBadRequest).JSON(map[string]string{"message": "unable to parse the id"})
+	}
+
+	limit, err := strconv.Atoi(fi.Query("limit", "0"))
+
+	if err != nil {
+		return fi.Status(fiber.StatusBadRequest).JSON(map[string]string{"message": "unable to parse the limit"})
+	}
+
+	status, body := c.service.Trail(fi.Context(), uuid, limit, fi.Query("cursor"))
+
+	return fi.Status(status).JSON(redacted(fi, uuid.String(), body))
+}
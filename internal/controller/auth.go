@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/LucasAndFlores/user_api/internal/dto"
+	"github.com/LucasAndFlores/user_api/internal/service"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type AuthController interface {
+	HandleLogin(*fiber.Ctx) error
+	HandleLoginMFA(*fiber.Ctx) error
+	HandleRefresh(*fiber.Ctx) error
+	HandleLogout(*fiber.Ctx) error
+	HandleRevokeAllSessions(*fiber.Ctx) error
+	HandleVerifyEmail(*fiber.Ctx) error
+	HandleResendVerification(*fiber.Ctx) error
+	HandleForgotPassword(*fiber.Ctx) error
+	HandleResetPassword(*fiber.Ctx) error
+}
+
+type UserAuthController struct {
+	service       service.AuthService
+	verification  service.VerificationService
+	passwordReset service.PasswordResetService
+}
+
+func NewAuthController(s service.AuthService, v service.VerificationService, p service.PasswordResetService) AuthController {
+	return &UserAuthController{service: s, verification: v, passwordReset: p}
+}
+
+func (c *UserAuthController) HandleLogin(fi *fiber.Ctx) error {
+	var loginDTO dto.LoginDTO
+
+	err := fi.BodyParser(&loginDTO)
+
+	if err != nil {
+		return fi.Status(fiber.StatusInternalServerError).JSON(map[string]string{"message": "internal server error"})
+	}
+
+	status, body := c.service.Login(fi.Context(), loginDTO)
+
+	return fi.Status(status).JSON(body)
+}
+
+func (c *UserAuthController) HandleLoginMFA(fi *fiber.Ctx) error {
+	var mfaDTO dto.MFALoginDTO
+
+	err := fi.BodyParser(&mfaDTO)
+
+	if err != nil {
+		return fi.Status(fiber.StatusInternalServerError).JSON(map[string]string{"message": "internal server error"})
+	}
+
+	status, body := c.service.LoginMFA(fi.Context(), mfaDTO)
+
+	if retryAfter, ok := body["retry_after"]; ok {
+		fi.Set(fiber.HeaderRetryAfter, fmt.Sprint(retryAfter))
+	}
+
+	return fi.Status(status).JSON(body)
+}
+
+func (c *UserAuthController) HandleRefresh(fi *fiber.Ctx) error {
+	var refreshDTO dto.RefreshTokenDTO
+
+	err := fi.BodyParser(&refreshDTO)
+
+	if err != nil {
+		return fi.Status(fiber.StatusInternalServerError).JSON(map[string]string{"message": "internal server error"})
+	}
+
+	status, body := c.service.Refresh(fi.Context(), refreshDTO)
+
+	return fi.Status(status).JSON(body)
+}
+
+func (c *UserAuthController) HandleLogout(fi *fiber.Ctx) error {
+	var refreshDTO dto.RefreshTokenDTO
+
+	err := fi.BodyParser(&refreshDTO)
+
+	if err != nil {
+		return fi.Status(fiber.StatusInternalServerError).JSON(map[string]string{"message": "internal server error"})
+	}
+
+	status, body := c.service.Logout(fi.Context(), refreshDTO)
+
+	return fi.Status(status).JSON(body)
+}
+
+func (c *UserAuthController) HandleRevokeAllSessions(fi *fiber.Ctx) error {
+	id, err := uuid.Parse(fi.Params("id"))
+
+	if err != nil {
+		return fi.Status(fiber.StatusBadRequest).JSON(map[string]string{"message": "unable to parse the id"})
+	}
+
+	status, body := c.service.RevokeAllSessions(fi.Context(), id)
+
+	return fi.Status(status).JSON(body)
+}
+
+func (c *UserAuthController) HandleVerifyEmail(fi *fiber.Ctx) error {
+	token := fi.Query("token")
+
+	if token == "" {
+		return fi.Status(fiber.StatusBadRequest).JSON(map[string]string{"message": "missing token"})
+	}
+
+	status, body := c.verification.Verify(fi.Context(), token)
+
+	return fi.Status(status).JSON(body)
+}
+
+func (c *UserAuthController) HandleResendVerification(fi *fiber.Ctx) error {
+	var emailDTO dto.EmailDTO
+
+	err := fi.BodyParser(&emailDTO)
+
+	if err != nil {
+		return fi.Status(fiber.StatusInternalServerError).JSON(map[string]string{"message": "internal server error"})
+	}
+
+	status, body := c.verification.Resend(fi.Context(), emailDTO)
+
+	return fi.Status(status).JSON(body)
+}
+
+func (c *UserAuthController) HandleForgotPassword(fi *fiber.Ctx) error {
+	var emailDTO dto.EmailDTO
+
+	err := fi.BodyParser(&emailDTO)
+
+	if err != nil {
+		return fi.Status(fiber.StatusInternalServerError).JSON(map[string]string{"message": "internal server error"})
+	}
+
+	status, body := c.passwordReset.Forgot(fi.Context(), emailDTO, fi.IP())
+
+	if retryAfter, ok := body["retry_after"]; ok {
+		fi.Set(fiber.HeaderRetryAfter, fmt.Sprint(retryAfter))
+	}
+
+	return fi.Status(status).JSON(body)
+}
+
+func (c *UserAuthController) HandleResetPassword(fi *fiber.Ctx) error {
+	var resetDTO dto.ResetPasswordDTO
+
+	err := fi.BodyParser(&resetDTO)
+
+	if err != nil {
+		return fi.Status(fiber.StatusInternalServerError).JSON(map[string]string{"message": "internal server error"})
+	}
+
+	status, body := c.passwordReset.Reset(fi.Context(), resetDTO)
+
+	return fi.Status(status).JSON(body)
+}
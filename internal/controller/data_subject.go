@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/LucasAndFlores/user_api/internal/service"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type DataSubjectController interface {
+	HandleExportUser(*fiber.Ctx) error
+	HandleEraseUser(*fiber.Ctx) error
+}
+
+type GDPRController struct {
+	service service.DataSubjectService
+}
+
+func NewDataSubjectController(s service.DataSubjectService) DataSubjectController {
+	return &GDPRController{service: s}
+}
+
+// HandleExportUser answers with the archive as a JSON attachment. Unlike
+// the other representations of the user, it doesn't honour fields.
+func (c *GDPRController) HandleExportUser(fi *fiber.Ctx) error {
+	uuid, err := uuid.Parse(fi.Params("id"))
+
+	if err != nil {
+		return fi.Status(fiber.StatusBadRequest).JSON(map[string]string{"message": "unable to parse the id"})
+	}
+
+	// the export must hold everything stored about the user
+	if fi.Query("fields") != "" {
+		return fi.Status(fiber.StatusBadRequest).JSON(map[string]string{"message": "the export holds every field, it can't select fields"})
+	}
+
+	status, body := c.service.Export(fi.Context(), uuid)
+
+	export, ok := body["export"]
+
+	if !ok {
+		return fi.Status(status).JSON(body)
+	}
+
+	fi.Attachment(fmt.Sprintf("user-%v.json", uuid))
+
+	return fi.Status(status).JSON(redacted(fi, uuid.String(), export))
+}
+
+func (c *GDPRController) HandleEraseUser(fi *fiber.Ctx) error {
+	uuid, err := uuid.Parse(fi.Params("id"))
+
+	if err != nil {
+		return fi.Status(fiber.StatusBadRequest).JSON(map[string]string{"message": "unable to parse the id"})
+	}
+
+	status, body := c.service.Erase(fi.Context(), uuid)
+
+	return fi.Status(status).JSON(body)
+}
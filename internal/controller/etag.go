@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/LucasAndFlores/user_api/internal/auth"
+	"github.com/LucasAndFlores/user_api/internal/codec"
+	"github.com/LucasAndFlores/user_api/internal/dto"
+	"github.com/LucasAndFlores/user_api/internal/redact"
+	"github.com/gofiber/fiber/v2"
+)
+
+// versionETag identifies the representation of a version of the user sent
+// to the caller, which is the one Vary lists: a partial representation, the
+// fields masked for the caller, and a media type other than JSON each get
+// their own ETag, which still starts with the version.
+func versionETag(fi *fiber.Ctx, owner string, version int, fields dto.UserFields) string {
+	fi.Vary(fiber.HeaderAuthorization, fiber.HeaderAccept)
+
+	tag := fmt.Sprint(version)
+
+	if fields != nil {
+		tag += ";" + fields.String()
+	}
+
+	viewer := redact.ViewerOf(auth.PrincipalFrom(fi), owner)
+
+	var masked []string
+
+	for name, rule := range redact.POLICY {
+		if !viewer.Reveals(rule.Scope) && (fields == nil || slices.Contains(fields, name)) {
+			masked = append(masked, name)
+		}
+	}
+
+	if len(masked) != 0 {
+		slices.Sort(masked)
+
+		tag += ";masked=" + strings.Join(masked, ",")
+	}
+
+	if c := codec.Response(fi); c != codec.JSON {
+		tag += ";" + c.MediaType
+	}
+
+	return fmt.Sprintf("\"%s\"", tag)
+}
+
+// pastVersionETag marks the ETag of a past version of the user, read with
+// as_of, so it can't pass for the current version: it is weak, which
+// If-Match never accepts, and tagged as_of.
+func pastVersionETag(etag string) string {
+	return "W/" + strings.TrimSuffix(etag, "\"") + ";as_of\""
+}
+
+// notModified applies the weak comparison of RFC 9110 to the If-None-Match
+// header.
+func notModified(fi *fiber.Ctx, etag string) bool {
+	header := fi.Get(fiber.HeaderIfNoneMatch)
+
+	if header == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+
+		if candidate == "*" || candidate == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// expectedVersion reads the If-Match header. It returns nil when the header
+// is missing or "*", and -1, which never matches, for anything that is not a
+// strong ETag of the current version. ETags of partial representations are
+// accepted, those of past versions are not.
+func expectedVersion(fi *fiber.Ctx) *int {
+	header := strings.TrimSpace(fi.Get(fiber.HeaderIfMatch))
+
+	if header == "" || header == "*" {
+		return nil
+	}
+
+	parts := strings.Split(strings.Trim(header, "\""), ";")
+
+	version, err := strconv.Atoi(parts[0])
+
+	if err != nil || !strings.HasPrefix(header, "\"") || !strings.HasSuffix(header, "\"") || slices.Contains(parts, "as_of") {
+		version = -1
+	}
+
+	return &version
+}
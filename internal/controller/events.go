@@ -0,0 +1,174 @@
+package controller
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/events"
+	"github.com/gofiber/fiber/v2"
+)
+
+const LAST_EVENT_ID_HEADER = "Last-Event-ID"
+
+type EventStreamController interface {
+	HandleStreamUserEvents(*fiber.Ctx) error
+}
+
+type EventStreamOptions struct {
+	PollInterval time.Duration
+	// Heartbeat is the interval of the comments keeping idle connections
+	// open through proxies.
+	Heartbeat time.Duration
+}
+
+type SSEController struct {
+	stream  *events.Stream
+	options EventStreamOptions
+}
+
+func NewEventStreamController(s *events.Stream, options EventStreamOptions) EventStreamController {
+	return &SSEController{stream: s, options: options}
+}
+
+// HandleStreamUserEvents streams the user events as Server-Sent Events,
+// with the outbox id as the event id. A client resumes after the events it
+// got with the Last-Event-ID header, which browsers send on reconnection,
+// or the last_event_id query parameter; otherwise only the events to come
+// are sent. Resuming after an event cleaned up from the outbox answers 410,
+// as the events that followed it may be gone. The types query parameter
+// filters the event types.
+func (c *SSEController) HandleStreamUserEvents(fi *fiber.Ctx) error {
+	types, err := parseEventTypes(fi.Query("types"))
+
+	if err != nil {
+		return fi.Status(fiber.StatusBadRequest).JSON(map[string]string{"message": err.Error()})
+	}
+
+	lastEventId := fi.Get(LAST_EVENT_ID_HEADER, fi.Query("last_event_id"))
+
+	var position events.Position
+
+	if lastEventId != "" {
+		var lastId int
+
+		lastId, err = strconv.Atoi(lastEventId)
+
+		if err != nil || lastId < 0 {
+			return fi.Status(fiber.StatusBadRequest).JSON(map[string]string{"message": "unable to parse the last event id"})
+		}
+
+		position, err = c.stream.After(fi.Context(), lastId)
+
+		if errors.Is(err, events.ErrUnknownEvent) {
+			return fi.Status(fiber.StatusGone).JSON(map[string]string{"message": err.Error()})
+		}
+	} else {
+		position, err = c.stream.Last(fi.Context())
+	}
+
+	if err != nil {
+		return fi.Status(fiber.StatusInternalServerError).JSON(map[string]string{"message": "internal server error"})
+	}
+
+	fi.Set(fiber.HeaderContentType, "text/event-stream")
+	fi.Set(fiber.HeaderCacheControl, "no-cache")
+	fi.Set("X-Accel-Buffering", "no")
+
+	// the request context is recycled once the handler returns, so the
+	// stream can't use it
+	fi.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		c.write(context.Background(), w, position, types)
+	})
+
+	return nil
+}
+
+// write sends events until the client goes away, which shows as a failed
+// flush.
+func (c *SSEController) write(ctx context.Context, w *bufio.Writer, position events.Position, types []string) {
+	poll := time.NewTicker(c.options.PollInterval)
+	heartbeat := time.NewTicker(c.options.Heartbeat)
+
+	defer poll.Stop()
+	defer heartbeat.Stop()
+
+	fmt.Fprintf(w, "retry: %d\n\n", c.options.PollInterval.Milliseconds())
+
+	if w.Flush() != nil {
+		return
+	}
+
+	for {
+		found, next, err := c.stream.Next(ctx, position, types)
+
+		if err != nil {
+			// the client reconnects and resumes after the last event
+			log.Printf("An error occurred when tried to read the event stream: %v", err)
+			return
+		}
+
+		for _, event := range found {
+			data, _ := json.Marshal(event)
+
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Id, event.Type, data)
+		}
+
+		position = next
+
+		// a backlog is sent without waiting between the batches
+		if len(found) != 0 {
+			if w.Flush() != nil {
+				return
+			}
+
+			continue
+		}
+
+		select {
+		case <-poll.C:
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+
+			if w.Flush() != nil {
+				return
+			}
+		}
+	}
+}
+
+func parseEventTypes(raw string) ([]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var types []string
+
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+
+		if !contains(events.TYPES, name) {
+			return nil, fmt.Errorf("unknown event type %q, the types are %s", name, strings.Join(events.TYPES, ", "))
+		}
+
+		types = append(types, name)
+	}
+
+	return types, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
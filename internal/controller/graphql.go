@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"github.com/LucasAndFlores/user_api/internal/auth"
+	"github.com/LucasAndFlores/user_api/internal/gql"
+	"github.com/LucasAndFlores/user_api/internal/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+type GraphQLController interface {
+	HandleGraphQL(*fiber.Ctx) error
+}
+
+type graphQLController struct {
+	server  *gql.Server
+	clients *middleware.Clients
+}
+
+func NewGraphQLController(s *gql.Server, c *middleware.Clients) GraphQLController {
+	return &graphQLController{server: s, clients: c}
+}
+
+func (c *graphQLController) HandleGraphQL(fi *fiber.Ctx) error {
+	var request gql.Request
+
+	err := fi.BodyParser(&request)
+
+	if err != nil || request.Query == "" {
+		return fi.Status(fiber.StatusBadRequest).JSON(map[string]string{"message": "a JSON body with a query is required"})
+	}
+
+	ctx := gql.WithPrincipal(fi.Context(), auth.PrincipalFrom(fi))
+	ctx = gql.WithClient(ctx, c.clients.Key(fi), fi.Get(middleware.IDEMPOTENCY_KEY_HEADER))
+
+	result, rejected := c.server.Execute(ctx, request)
+
+	// errors during execution come along with the data that could be
+	// resolved, so only requests rejected before are failures
+	if rejected {
+		return fi.Status(fiber.StatusBadRequest).JSON(result)
+	}
+
+	return fi.JSON(result)
+}
@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"github.com/LucasAndFlores/user_api/internal/auth"
+	"github.com/LucasAndFlores/user_api/internal/dto"
+	"github.com/LucasAndFlores/user_api/internal/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type MFAController interface {
+	HandleEnrollTOTP(*fiber.Ctx) error
+	HandleConfirmTOTP(*fiber.Ctx) error
+}
+
+type TOTPController struct {
+	service service.MFAService
+}
+
+func NewMFAController(s service.MFAService) MFAController {
+	return &TOTPController{service: s}
+}
+
+func (c *TOTPController) HandleEnrollTOTP(fi *fiber.Ctx) error {
+	principal := auth.PrincipalFrom(fi)
+
+	status, body := c.service.EnrollTOTP(fi.Context(), principal.ExternalId)
+
+	return fi.Status(status).JSON(body)
+}
+
+func (c *TOTPController) HandleConfirmTOTP(fi *fiber.Ctx) error {
+	var codeDTO dto.TOTPCodeDTO
+
+	err := fi.BodyParser(&codeDTO)
+
+	if err != nil {
+		return fi.Status(fiber.StatusInternalServerError).JSON(map[string]string{"message": "internal server error"})
+	}
+
+	principal := auth.PrincipalFrom(fi)
+
+	status, body := c.service.ConfirmTOTP(fi.Context(), principal.ExternalId, codeDTO)
+
+	return fi.Status(status).JSON(body)
+}
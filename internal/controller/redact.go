@@ -0,0 +1,16 @@
+package controller
+
+import (
+	"github.com/LucasAndFlores/user_api/internal/auth"
+	"github.com/LucasAndFlores/user_api/internal/redact"
+	"github.com/gofiber/fiber/v2"
+)
+
+// redacted masks the pii about the user with the given id that the caller
+// can't see. As the answer depends on the caller, shared caches must not
+// serve it to others.
+func redacted(fi *fiber.Ctx, owner string, body interface{}) interface{} {
+	fi.Vary(fiber.HeaderAuthorization)
+
+	return redact.Apply(redact.ViewerOf(auth.PrincipalFrom(fi), owner), body)
+}
@@ -1,15 +1,28 @@
 package controller
 
 import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/codec"
 	"github.com/LucasAndFlores/user_api/internal/dto"
 	"github.com/LucasAndFlores/user_api/internal/service"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
 
+// USER_LOCATION is the canonical address of a user, whichever route created it.
+const USER_LOCATION = "/api/v1/users/%s"
+
 type Controller interface {
 	HandleCreateUser(*fiber.Ctx) error
 	HandleFindUserByExternalId(*fiber.Ctx) error
+	HandleListUsers(*fiber.Ctx) error
+	HandleUpdateUser(*fiber.Ctx) error
+	HandleDeleteUser(*fiber.Ctx) error
+	HandleListUserVersions(*fiber.Ctx) error
+	HandleRevertUser(*fiber.Ctx) error
 }
 
 type UserController struct {
@@ -23,15 +36,19 @@ func NewUserController(s service.Service) Controller {
 func (c *UserController) HandleCreateUser(fi *fiber.Ctx) error {
 	var userDTO dto.UserDTO
 
-	err := fi.BodyParser(&userDTO)
+	err := codec.Decode(fi, &userDTO)
 
 	if err != nil {
-		return fi.Status(fiber.StatusInternalServerError).JSON(map[string]string{"message": "internal server error"})
+		return codec.Send(fi, fiber.StatusBadRequest, map[string]string{"message": codec.UNPARSABLE_BODY_MESSAGE})
 	}
 
 	status, body := c.service.Create(fi.Context(), userDTO)
 
-	return fi.Status(status).JSON(body)
+	if status == fiber.StatusCreated {
+		fi.Location(fmt.Sprintf(USER_LOCATION, userDTO.ExternalId))
+	}
+
+	return codec.Send(fi, status, body)
 }
 
 func (c *UserController) HandleFindUserByExternalId(fi *fiber.Ctx) error {
@@ -40,10 +57,165 @@ func (c *UserController) HandleFindUserByExternalId(fi *fiber.Ctx) error {
 	uuid, err := uuid.Parse(id)
 
 	if err != nil {
-		return fi.Status(fiber.StatusBadRequest).JSON(map[string]string{"message": "unable to parse the id"})
+		return codec.Send(fi, fiber.StatusBadRequest, map[string]string{"message": "unable to parse the id"})
+	}
+
+	fields, err := dto.ParseUserFields(fi.Query("fields"))
+
+	if err != nil {
+		return codec.Send(fi, fiber.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	var status int
+	var body map[string]interface{}
+
+	asOf := fi.Query("as_of")
+
+	if asOf != "" {
+		at, err := time.Parse(time.RFC3339, asOf)
+
+		if err != nil {
+			return codec.Send(fi, fiber.StatusBadRequest, map[string]string{"message": "unable to parse as_of, expected an RFC 3339 timestamp"})
+		}
+
+		status, body = c.service.FindUserAsOf(fi.Context(), uuid, at)
+	} else {
+		status, body = c.service.FindUserByExternalId(fi.Context(), uuid, fields)
+	}
+
+	if user, ok := body["user"].(dto.UserDTO); ok {
+		etag := versionETag(fi, id, user.Version, fields)
+
+		if asOf != "" {
+			etag = pastVersionETag(etag)
+		}
+
+		fi.Set(fiber.HeaderETag, etag)
+
+		if notModified(fi, etag) {
+			return fi.SendStatus(fiber.StatusNotModified)
+		}
+
+		body["user"] = fields.Project(redacted(fi, id, user).(dto.UserDTO))
+	}
+
+	return codec.Send(fi, status, body)
+}
+
+func (c *UserController) HandleListUsers(fi *fiber.Ctx) error {
+	fields, err := dto.ParseUserFields(fi.Query("fields"))
+
+	if err != nil {
+		return codec.Send(fi, fiber.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	limit, err := strconv.Atoi(fi.Query("limit", "0"))
+
+	if err != nil {
+		return codec.Send(fi, fiber.StatusBadRequest, map[string]string{"message": "unable to parse the limit"})
+	}
+
+	filter := dto.UserFilter{Email: fi.Query("email"), Name: fi.Query("name")}
+
+	if updatedSince := fi.Query("updated_since"); updatedSince != "" {
+		since, err := time.Parse(time.RFC3339, updatedSince)
+
+		if err != nil {
+			return codec.Send(fi, fiber.StatusBadRequest, map[string]string{"message": "unable to parse updated_since, expected an RFC 3339 timestamp"})
+		}
+
+		filter.UpdatedSince = &since
+	}
+
+	status, body := c.service.List(fi.Context(), filter, fields, limit, fi.Query("cursor"))
+
+	users, ok := body["users"].([]dto.UserDTO)
+
+	if !ok {
+		return codec.Send(fi, status, body)
+	}
+
+	projected := make([]interface{}, len(users))
+
+	for i, user := range users {
+		projected[i] = fields.Project(redacted(fi, user.ExternalId, user).(dto.UserDTO))
+	}
+
+	return codec.Send(fi, status, map[string]interface{}{"users": projected, "next_cursor": body["next_cursor"]})
+}
+
+func (c *UserController) HandleUpdateUser(fi *fiber.Ctx) error {
+	uuid, err := uuid.Parse(fi.Params("id"))
+
+	if err != nil {
+		return codec.Send(fi, fiber.StatusBadRequest, map[string]string{"message": "unable to parse the id"})
+	}
+
+	var updateDTO dto.UpdateUserDTO
+
+	err = codec.Decode(fi, &updateDTO)
+
+	if err != nil {
+		return codec.Send(fi, fiber.StatusBadRequest, map[string]string{"message": codec.UNPARSABLE_BODY_MESSAGE})
+	}
+
+	status, body := c.service.Update(fi.Context(), uuid, updateDTO, expectedVersion(fi))
+
+	if user, ok := body["user"].(dto.UserDTO); ok {
+		fi.Set(fiber.HeaderETag, versionETag(fi, uuid.String(), user.Version, nil))
+	}
+
+	return codec.Send(fi, status, redacted(fi, uuid.String(), body))
+}
+
+func (c *UserController) HandleDeleteUser(fi *fiber.Ctx) error {
+	uuid, err := uuid.Parse(fi.Params("id"))
+
+	if err != nil {
+		return codec.Send(fi, fiber.StatusBadRequest, map[string]string{"message": "unable to parse the id"})
 	}
 
-	status, body := c.service.FindUserByExternalId(fi.Context(), uuid)
+	status, body := c.service.Delete(fi.Context(), uuid, expectedVersion(fi))
+
+	return codec.Send(fi, status, body)
+}
+
+func (c *UserController) HandleListUserVersions(fi *fiber.Ctx) error {
+	uuid, err := uuid.Parse(fi.Params("id"))
+
+	if err != nil {
+		return codec.Send(fi, fiber.StatusBadRequest, map[string]string{"message": "unable to parse the id"})
+	}
+
+	limit, err := strconv.Atoi(fi.Query("limit", "0"))
+
+	if err != nil {
+		return codec.Send(fi, fiber.StatusBadRequest, map[string]string{"message": "unable to parse the limit"})
+	}
+
+	status, body := c.service.Versions(fi.Context(), uuid, limit, fi.Query("cursor"))
+
+	return codec.Send(fi, status, redacted(fi, uuid.String(), body))
+}
+
+func (c *UserController) HandleRevertUser(fi *fiber.Ctx) error {
+	uuid, err := uuid.Parse(fi.Params("id"))
+
+	if err != nil {
+		return codec.Send(fi, fiber.StatusBadRequest, map[string]string{"message": "unable to parse the id"})
+	}
+
+	version, err := strconv.Atoi(fi.Params("version"))
+
+	if err != nil {
+		return codec.Send(fi, fiber.StatusBadRequest, map[string]string{"message": "unable to parse the version"})
+	}
+
+	status, body := c.service.Revert(fi.Context(), uuid, version, expectedVersion(fi))
+
+	if user, ok := body["user"].(dto.UserDTO); ok {
+		fi.Set(fiber.HeaderETag, versionETag(fi, uuid.String(), user.Version, nil))
+	}
 
-	return fi.Status(status).JSON(body)
+	return codec.Send(fi, status, redacted(fi, uuid.String(), body))
 }
@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"github.com/LucasAndFlores/user_api/internal/dto"
+	"github.com/LucasAndFlores/user_api/internal/service"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type WebhookController interface {
+	HandleCreateWebhook(*fiber.Ctx) error
+	HandleListWebhooks(*fiber.Ctx) error
+	HandleFindWebhook(*fiber.Ctx) error
+	HandleDeleteWebhook(*fiber.Ctx) error
+	HandleEnableWebhook(*fiber.Ctx) error
+	HandleListDeliveries(*fiber.Ctx) error
+	HandleRedeliver(*fiber.Ctx) error
+}
+
+type WebhookSubscriptionController struct {
+	service service.WebhookService
+}
+
+func NewWebhookController(s service.WebhookService) WebhookController {
+	return &WebhookSubscriptionController{service: s}
+}
+
+func (c *WebhookSubscriptionController) HandleCreateWebhook(fi *fiber.Ctx) error {
+	var webhookDTO dto.WebhookDTO
+
+	err := fi.BodyParser(&webhookDTO)
+
+	if err != nil {
+		return fi.Status(fiber.StatusInternalServerError).JSON(map[string]string{"message": "internal server error"})
+	}
+
+	status, body := c.service.Create(fi.Context(), webhookDTO)
+
+	return fi.Status(status).JSON(body)
+}
+
+func (c *WebhookSubscriptionController) HandleListWebhooks(fi *fiber.Ctx) error {
+	status, body := c.service.List(fi.Context())
+
+	return fi.Status(status).JSON(body)
+}
+
+func (c *WebhookSubscriptionController) HandleFindWebhook(fi *fiber.Ctx) error {
+	uuid, err := uuid.Parse(fi.Params("id"))
+
+	if err != nil {
+		return fi.Status(fiber.StatusBadRequest).JSON(map[string]string{"message": "unable to parse the id"})
+	}
+
+	status, body := c.service.Find(fi.Context(), uuid)
+
+	return fi.Status(status).JSON(body)
+}
+
+func (c *WebhookSubscriptionController) HandleDeleteWebhook(fi *fiber.Ctx) error {
+	uuid, err := uuid.Parse(fi.Params("id"))
+
+	if err != nil {
+		return fi.Status(fiber.StatusBadRequest).JSON(map[string]string{"message": "unable to parse the id"})
+	}
+
+	status, body := c.service.Delete(fi.Context(), uuid)
+
+	return fi.Status(status).JSON(body)
+}
+
+func (c *WebhookSubscriptionController) HandleEnableWebhook(fi *fiber.Ctx) error {
+	uuid, err := uuid.Parse(fi.Params("id"))
+
+	if err != nil {
+		return fi.Status(fiber.StatusBadRequest).JSON(map[string]string{"message": "unable to parse the id"})
+	}
+
+	status, body := c.service.Enable(fi.Context(), uuid)
+
+	return fi.Status(status).JSON(body)
+}
+
+func (c *WebhookSubscriptionController) HandleListDeliveries(fi *fiber.Ctx) error {
+	uuid, err := uuid.Parse(fi.Params("id"))
+
+	if err != nil {
+		return fi.Status(fiber.StatusBadRequest).JSON(map[string]string{"message": "unable to parse the id"})
+	}
+
+	status, body := c.service.Deliveries(fi.Context(), uuid)
+
+	return fi.Status(status).JSON(body)
+}
+
+func (c *WebhookSubscriptionController) HandleRedeliver(fi *fiber.Ctx) error {
+	webhookId, err := uuid.Parse(fi.Params("id"))
+
+	if err != nil {
+		return fi.Status(fiber.StatusBadRequest).JSON(map[string]string{"message": "unable to parse the id"})
+	}
+
+	deliveryId, err := uuid.Parse(fi.Params("deliveryId"))
+
+	if err != nil {
+		return fi.Status(fiber.StatusBadRequest).JSON(map[string]string{"message": "unable to parse the delivery id"})
+	}
+
+	status, body := c.service.Redeliver(fi.Context(), webhookId, deliveryId)
+
+	return fi.Status(status).JSON(body)
+}
@@ -0,0 +1,49 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/LucasAndFlores/user_api/internal/redact"
+)
+
+type AuditRecordDTO struct {
+	Id        int                 `json:"id"`
+	Actor     string              `json:"actor"`
+	RequestId string              `json:"request_id"`
+	Action    string              `json:"action"`
+	Changes   []model.AuditChange `json:"changes"`
+	CreatedAt time.Time           `json:"created_at"`
+}
+
+func (d *AuditRecordDTO) ConvertToAuditRecordDTO(r *model.AuditRecord) error {
+	d.Id = r.Id
+	d.Actor = r.Actor
+	d.RequestId = r.RequestId
+	d.Action = r.Action
+	d.CreatedAt = r.CreatedAt
+
+	return json.Unmarshal(r.Changes, &d.Changes)
+}
+
+// Redact masks the values of the changed fields holding pii.
+func (d AuditRecordDTO) Redact(viewer redact.Viewer) interface{} {
+	if d.Changes == nil {
+		return d
+	}
+
+	changes := make([]model.AuditChange, len(d.Changes))
+
+	for i, change := range d.Changes {
+		changes[i] = model.AuditChange{
+			Field:  change.Field,
+			Before: redact.Value(viewer, change.Field, change.Before),
+			After:  redact.Value(viewer, change.Field, change.After),
+		}
+	}
+
+	d.Changes = changes
+
+	return d
+}
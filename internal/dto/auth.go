@@ -0,0 +1,29 @@
+package dto
+
+type LoginDTO struct {
+	Email    string `json:"email" validate:"email,required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type RefreshTokenDTO struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type EmailDTO struct {
+	Email string `json:"email" validate:"email,required"`
+}
+
+type ResetPasswordDTO struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,password"`
+}
+
+type TOTPCodeDTO struct {
+	Code string `json:"code" validate:"required,numeric,len=6"`
+}
+
+// MFALoginDTO completes a login with either a TOTP code or a recovery code.
+type MFALoginDTO struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
@@ -0,0 +1,110 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/model"
+)
+
+// DataSubjectExportDTO is everything stored about a user, as handed to them
+// when they exercise their right of access. Secrets, such as password
+// hashes, token hashes and the TOTP secret, are left out.
+type DataSubjectExportDTO struct {
+	ExportedAt time.Time `json:"exported_at"`
+	// User is nil once the user was deleted, while its versions and audit
+	// records remain.
+	User               *ExportedUserDTO     `json:"user"`
+	Versions           []UserVersionDTO     `json:"versions"`
+	AuditRecords       []AuditRecordDTO     `json:"audit_records"`
+	Sessions           []ExportedSessionDTO `json:"sessions"`
+	EmailVerifications []ExportedTokenDTO   `json:"email_verifications"`
+	PasswordResets     []ExportedTokenDTO   `json:"password_resets"`
+	MFA                ExportedMFADTO       `json:"mfa"`
+	Events             []ExportedEventDTO   `json:"events"`
+	// SharedEvents are the events sent to webhook subscribers.
+	SharedEvents []SharedEventDTO `json:"shared_events"`
+}
+
+type ExportedUserDTO struct {
+	Id              string     `json:"id"`
+	Name            string     `json:"name"`
+	Email           string     `json:"email" redact:"email"`
+	DateOfBirth     string     `json:"date_of_birth" redact:"date_of_birth"`
+	Role            string     `json:"role"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	Version         int        `json:"version"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+type ExportedSessionDTO struct {
+	MFA       bool       `json:"mfa"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RotatedAt *time.Time `json:"rotated_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+// ExportedTokenDTO is an email verification or password reset link.
+type ExportedTokenDTO struct {
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+type ExportedMFADTO struct {
+	Enrolled          bool       `json:"enrolled"`
+	ConfirmedAt       *time.Time `json:"confirmed_at"`
+	RecoveryCodes     int        `json:"recovery_codes"`
+	RecoveryCodesUsed int        `json:"recovery_codes_used"`
+}
+
+type ExportedEventDTO struct {
+	Id          int         `json:"id"`
+	Type        string      `json:"type"`
+	Payload     interface{} `json:"payload"`
+	CreatedAt   time.Time   `json:"created_at"`
+	PublishedAt *time.Time  `json:"published_at"`
+}
+
+type SharedEventDTO struct {
+	EventId   int       `json:"event_id"`
+	EventType string    `json:"event_type"`
+	URL       string    `json:"url"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (d *ExportedUserDTO) ConvertToExportedUserDTO(u *model.User) {
+	var user UserDTO
+
+	user.ConvertToUserDTO(u)
+
+	d.Id = user.ExternalId
+	d.Name = user.Name
+	d.Email = user.Email
+	d.DateOfBirth = user.DateOfBirth
+	d.Role = u.Role
+	d.EmailVerifiedAt = u.EmailVerifiedAt
+	d.Version = u.Version
+	d.CreatedAt = u.CreatedAt
+	d.UpdatedAt = u.UpdatedAt
+}
+
+func (d *ExportedSessionDTO) ConvertToExportedSessionDTO(t *model.RefreshToken) {
+	d.MFA = t.MFA
+	d.CreatedAt = t.CreatedAt
+	d.ExpiresAt = t.ExpiresAt
+	d.RotatedAt = t.RotatedAt
+	d.RevokedAt = t.RevokedAt
+}
+
+func (d *ExportedEventDTO) ConvertToExportedEventDTO(e *model.OutboxEvent) error {
+	d.Id = e.Id
+	d.Type = e.Type
+	d.CreatedAt = e.CreatedAt
+	d.PublishedAt = e.PublishedAt
+
+	return json.Unmarshal(e.Payload, &d.Payload)
+}
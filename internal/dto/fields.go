@@ -0,0 +1,120 @@
+package dto
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// USER_FIELDS is the allow-list of the fields clients can select with the
+// fields query parameter, along with the column each one is loaded from.
+var USER_FIELDS = []struct {
+	Name   string
+	Column string
+}{
+	{Name: "id", Column: "external_id"},
+	{Name: "name", Column: "name"},
+	{Name: "email", Column: "email"},
+	{Name: "date_of_birth", Column: "date_of_birth"},
+	{Name: "version", Column: "version"},
+	{Name: "created_at", Column: "created_at"},
+	{Name: "updated_at", Column: "updated_at"},
+}
+
+// UserFields is a selection of USER_FIELDS, in allow-list order. nil selects
+// every field.
+type UserFields []string
+
+// ParseUserFields reads a comma separated list like "id,name". An empty list
+// selects every field.
+func ParseUserFields(raw string) (UserFields, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	requested := map[string]bool{}
+
+	for _, name := range strings.Split(raw, ",") {
+		requested[strings.TrimSpace(name)] = true
+	}
+
+	var fields UserFields
+
+	for _, field := range USER_FIELDS {
+		if requested[field.Name] {
+			fields = append(fields, field.Name)
+			delete(requested, field.Name)
+		}
+	}
+
+	if len(requested) != 0 {
+		var unknown []string
+
+		for name := range requested {
+			unknown = append(unknown, fmt.Sprintf("%q", name))
+		}
+
+		return nil, fmt.Errorf("unknown fields %v, allowed fields are %v", strings.Join(unknown, ", "), strings.Join(UserFieldNames(), ", "))
+	}
+
+	return fields, nil
+}
+
+func UserFieldNames() []string {
+	names := make([]string, len(USER_FIELDS))
+
+	for i, field := range USER_FIELDS {
+		names[i] = field.Name
+	}
+
+	return names
+}
+
+// Columns returns the columns to select for the fields, along with the ones
+// every read needs, or nil for all of them.
+func (f UserFields) Columns() []string {
+	if f == nil {
+		return nil
+	}
+
+	columns := []string{"id", "version"}
+
+	for _, field := range USER_FIELDS {
+		for _, name := range f {
+			if name == field.Name && !slices.Contains(columns, field.Column) {
+				columns = append(columns, field.Column)
+			}
+		}
+	}
+
+	return columns
+}
+
+func (f UserFields) String() string {
+	return strings.Join(f, ",")
+}
+
+// Project keeps the selected fields of the user, or the whole user when f is
+// nil.
+func (f UserFields) Project(user UserDTO) interface{} {
+	if f == nil {
+		return user
+	}
+
+	encoded, _ := json.Marshal(user)
+
+	var all map[string]interface{}
+
+	json.Unmarshal(encoded, &all)
+
+	projected := make(map[string]interface{}, len(f))
+
+	for _, name := range f {
+		if value, ok := all[name]; ok {
+			projected[name] = value
+		}
+	}
+
+	return projected
+}
@@ -3,15 +3,37 @@ package dto
 import (
 	"time"
 
+	"github.com/LucasAndFlores/user_api/internal/civil"
 	"github.com/LucasAndFlores/user_api/internal/model"
 	"github.com/google/uuid"
 )
 
 type UserDTO struct {
 	Name        string `json:"name" validate:"required,min=2"`
-	Email       string `json:"email" validate:"email,required,min=2"`
+	Email       string `json:"email" validate:"email,required,min=2" redact:"email"`
 	ExternalId  string `json:"id" validate:"uuid,required"`
-	DateOfBirth string `json:"date_of_birth" validate:"required"`
+	DateOfBirth string `json:"date_of_birth" validate:"required,date,date_of_birth" redact:"date_of_birth"`
+	Password    string `json:"password,omitempty" validate:"omitempty,password"`
+	// Version, CreatedAt and UpdatedAt are set by the server, and ignored in
+	// the requests.
+	Version   int       `json:"version" openapi:"readonly"`
+	CreatedAt time.Time `json:"created_at" openapi:"readonly"`
+	UpdatedAt time.Time `json:"updated_at" openapi:"readonly"`
+}
+
+type UpdateUserDTO struct {
+	Name        string `json:"name" validate:"required,min=2"`
+	Email       string `json:"email" validate:"email,required,min=2"`
+	DateOfBirth string `json:"date_of_birth" validate:"required,date,date_of_birth"`
+}
+
+// UserFilter narrows a listing. Empty fields match every user; Name matches
+// a part of the name, case-insensitively, and UpdatedSince the users updated
+// at or after it, less repository.SYNC_LAG.
+type UserFilter struct {
+	Email        string
+	Name         string
+	UpdatedSince *time.Time
 }
 
 func (d *UserDTO) ConvertToUserDTO(u *model.User) {
@@ -19,6 +41,9 @@ func (d *UserDTO) ConvertToUserDTO(u *model.User) {
 	d.Email = u.Email
 	d.ExternalId = u.ExternalId.String()
 	d.DateOfBirth = u.DateOfBirth.String()
+	d.Version = u.Version
+	d.CreatedAt = u.CreatedAt
+	d.UpdatedAt = u.UpdatedAt
 }
 
 func (d *UserDTO) ConvertToUserModel() (model.User, error) {
@@ -28,7 +53,7 @@ func (d *UserDTO) ConvertToUserModel() (model.User, error) {
 		return model.User{}, err
 	}
 
-	date, err := time.Parse(time.RFC3339, d.DateOfBirth)
+	date, err := civil.ParseDate(d.DateOfBirth)
 
 	if err != nil {
 		return model.User{}, err
@@ -41,3 +66,18 @@ func (d *UserDTO) ConvertToUserModel() (model.User, error) {
 		DateOfBirth: date,
 	}, nil
 }
+
+// ApplyTo replaces the profile of u with the body, once validated.
+func (d *UpdateUserDTO) ApplyTo(u *model.User) error {
+	date, err := civil.ParseDate(d.DateOfBirth)
+
+	if err != nil {
+		return err
+	}
+
+	u.Name = d.Name
+	u.Email = d.Email
+	u.DateOfBirth = date
+
+	return nil
+}
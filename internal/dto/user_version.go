@@ -0,0 +1,26 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/model"
+)
+
+type UserVersionDTO struct {
+	Version     int    `json:"version"`
+	Name        string `json:"name"`
+	Email       string `json:"email" redact:"email"`
+	DateOfBirth string `json:"date_of_birth" redact:"date_of_birth"`
+	// Deleted marks the version recorded when the user was deleted.
+	Deleted    bool      `json:"deleted"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+func (d *UserVersionDTO) ConvertToUserVersionDTO(v *model.UserVersion) {
+	d.Version = v.Version
+	d.Name = v.Name
+	d.Email = v.Email
+	d.DateOfBirth = v.DateOfBirth.String()
+	d.Deleted = v.Deleted
+	d.RecordedAt = v.RecordedAt
+}
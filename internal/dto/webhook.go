@@ -0,0 +1,70 @@
+package dto
+
+import (
+	"strings"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/model"
+)
+
+type WebhookDTO struct {
+	URL        string   `json:"url" validate:"required,http_url,public_url"`
+	EventTypes []string `json:"event_types" validate:"required,min=1,dive,oneof=user.created user.updated user.deleted"`
+	// Secret signs the deliveries. One is generated when it is left empty.
+	Secret string `json:"secret,omitempty" validate:"omitempty,min=32"`
+}
+
+// WebhookResponseDTO is a subscription as shown to admins. The secret is
+// only shown when the subscription is created.
+type WebhookResponseDTO struct {
+	Id                  string     `json:"id"`
+	URL                 string     `json:"url"`
+	EventTypes          []string   `json:"event_types"`
+	Secret              string     `json:"secret,omitempty"`
+	Enabled             bool       `json:"enabled"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	CreatedAt           time.Time  `json:"created_at"`
+	DisabledAt          *time.Time `json:"disabled_at"`
+}
+
+type WebhookDeliveryDTO struct {
+	Id             string     `json:"id"`
+	EventId        int        `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	ResponseStatus int        `json:"response_status"`
+	LastError      string     `json:"last_error"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at"`
+}
+
+func (d *WebhookResponseDTO) ConvertToWebhookDTO(s *model.WebhookSubscription) {
+	d.Id = s.ExternalId.String()
+	d.URL = s.URL
+	d.EventTypes = s.Types()
+	d.Enabled = s.DisabledAt == nil
+	d.ConsecutiveFailures = s.ConsecutiveFailures
+	d.CreatedAt = s.CreatedAt
+	d.DisabledAt = s.DisabledAt
+}
+
+func (d *WebhookDTO) ConvertToSubscriptionModel() model.WebhookSubscription {
+	return model.WebhookSubscription{
+		URL:        d.URL,
+		EventTypes: strings.Join(d.EventTypes, ","),
+		Secret:     d.Secret,
+	}
+}
+
+func (d *WebhookDeliveryDTO) ConvertToDeliveryDTO(delivery *model.WebhookDelivery) {
+	d.Id = delivery.ExternalId.String()
+	d.EventId = delivery.EventId
+	d.EventType = delivery.EventType
+	d.Status = delivery.Status
+	d.Attempts = delivery.Attempts
+	d.ResponseStatus = delivery.ResponseStatus
+	d.LastError = delivery.LastError
+	d.CreatedAt = delivery.CreatedAt
+	d.LastAttemptAt = delivery.LastAttemptAt
+}
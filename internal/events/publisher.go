@@ -0,0 +1,117 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/redact"
+	"github.com/google/uuid"
+)
+
+const (
+	USER_CREATED = "user.created"
+	USER_UPDATED = "user.updated"
+	USER_DELETED = "user.deleted"
+)
+
+// Event is what consumers receive. Id is unique and increasing, so they can
+// drop the duplicates of an at-least-once delivery.
+type Event struct {
+	Id          int             `json:"id"`
+	Type        string          `json:"type"`
+	AggregateId uuid.UUID       `json:"aggregate_id"`
+	Payload     json.RawMessage `json:"payload"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+}
+
+type Publisher interface {
+	Publish(context.Context, Event) error
+}
+
+// MultiPublisher hands every event to each of its publishers, stopping at
+// the first failure. The relay retries the whole event, so the publishers
+// must tolerate duplicates.
+type MultiPublisher struct {
+	publishers []Publisher
+}
+
+func NewMultiPublisher(publishers ...Publisher) Publisher {
+	return &MultiPublisher{publishers: publishers}
+}
+
+func (p *MultiPublisher) Publish(ctx context.Context, event Event) error {
+	for _, publisher := range p.publishers {
+		err := publisher.Publish(ctx, event)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FilePublisher writes every event to its own file in dir, named after its
+// id, which is handy for local development and tests.
+type FilePublisher struct {
+	dir string
+}
+
+func NewFilePublisher(dir string) (Publisher, error) {
+	err := os.MkdirAll(dir, 0o755)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &FilePublisher{dir: dir}, nil
+}
+
+func (p *FilePublisher) Publish(ctx context.Context, event Event) error {
+	encoded, err := json.Marshal(event)
+
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%020d-%s.json", event.Id, event.Type)
+
+	return os.WriteFile(filepath.Join(p.dir, name), encoded, 0o644)
+}
+
+type LogPublisher struct{}
+
+func NewLogPublisher() Publisher {
+	return &LogPublisher{}
+}
+
+func (p *LogPublisher) Publish(ctx context.Context, event Event) error {
+	log.Printf("event id=%d type=%s aggregate=%s payload=%s", event.Id, event.Type, event.AggregateId, redactPayload(event.Payload))
+
+	return nil
+}
+
+// redactPayload masks the pii of the payload, which is logged as is when it
+// isn't JSON.
+func redactPayload(payload json.RawMessage) []byte {
+	var value interface{}
+
+	err := json.Unmarshal(payload, &value)
+
+	if err != nil {
+		return payload
+	}
+
+	redacted, err := json.Marshal(redact.Apply(redact.EVERYONE, value))
+
+	if err != nil {
+		return payload
+	}
+
+	return redacted
+}
@@ -0,0 +1,156 @@
+package events
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/LucasAndFlores/user_api/internal/repository"
+)
+
+type RelayOptions struct {
+	// Interval is the pause between two polls of the outbox once it is
+	// drained.
+	Interval  time.Duration
+	BatchSize int
+	// MaxBackoff caps the exponential delay between the attempts of a
+	// failing event. Later events of the same user wait for it.
+	MaxBackoff time.Duration
+	// Retention is how long published events are kept before cleanup.
+	Retention time.Duration
+}
+
+// Relay publishes the outbox events at least once: an event is marked as
+// published only after the publisher accepted it, so a crash in between
+// publishes it again.
+type Relay struct {
+	outbox     repository.OutboxRepository
+	transactor repository.Transactor
+	publisher  Publisher
+	options    RelayOptions
+}
+
+func NewRelay(o repository.OutboxRepository, t repository.Transactor, p Publisher, options RelayOptions) *Relay {
+	return &Relay{outbox: o, transactor: t, publisher: p, options: options}
+}
+
+// Run polls the outbox until ctx is done.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.options.Interval)
+
+	defer ticker.Stop()
+
+	for {
+		for {
+			published, err := r.RunOnce(ctx)
+
+			if err != nil {
+				log.Printf("An error occurred when tried to relay the outbox: %v", err)
+			}
+
+			if err != nil || published == 0 {
+				break
+			}
+		}
+
+		_, err := r.outbox.DeletePublishedBefore(ctx, time.Now().Add(-r.options.Retention))
+
+		if err != nil {
+			log.Printf("An error occurred when tried to clean up the outbox: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce publishes one batch of due events and reports how many were
+// published. A failed event is scheduled for a later attempt.
+func (r *Relay) RunOnce(ctx context.Context) (int, error) {
+	published := 0
+
+	err := r.transactor.Transaction(ctx, func(ctx context.Context) error {
+		now := time.Now()
+
+		claimed, err := r.outbox.ClaimDue(ctx, now, r.options.BatchSize)
+
+		if err != nil {
+			return err
+		}
+
+		for i := range claimed {
+			event := &claimed[i]
+
+			event.Attempts++
+
+			// a failed publish rolls back what it wrote, so the retry
+			// starts over
+			err = r.transactor.Transaction(ctx, func(ctx context.Context) error {
+				opened, err := toEvent(event)
+
+				if err != nil {
+					return err
+				}
+
+				return r.publisher.Publish(ctx, opened)
+			})
+
+			if err != nil {
+				event.LastError = err.Error()
+				event.NextAttemptAt = now.Add(r.backoff(event.Attempts))
+
+				log.Printf("An error occurred when tried to publish the event %d, attempt %d: %v", event.Id, event.Attempts, err)
+
+				err = r.outbox.MarkFailed(ctx, event)
+			} else {
+				published++
+
+				err = r.outbox.MarkPublished(ctx, event, time.Now())
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return published, err
+}
+
+func (r *Relay) backoff(attempts int) time.Duration {
+	delay := time.Second
+
+	for i := 1; i < attempts && delay < r.options.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > r.options.MaxBackoff {
+		return r.options.MaxBackoff
+	}
+
+	return delay
+}
+
+// toEvent is what the consumers receive of the row, with the pii of the
+// payload decrypted.
+func toEvent(e *model.OutboxEvent) (Event, error) {
+	payload, err := OpenUserPayload(e.Payload)
+
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		Id:          e.Id,
+		Type:        e.Type,
+		AggregateId: e.AggregateId,
+		Payload:     payload,
+		OccurredAt:  e.CreatedAt,
+	}, nil
+}
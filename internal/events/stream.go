@@ -0,0 +1,82 @@
+package events
+
+import (
+	"context"
+	"errors"
+
+	"github.com/LucasAndFlores/user_api/internal/repository"
+	"gorm.io/gorm"
+)
+
+// TYPES lists every event type, in the order they happen to a user.
+var TYPES = []string{USER_CREATED, USER_UPDATED, USER_DELETED}
+
+// ErrUnknownEvent is returned when resuming after an event that is no
+// longer in the outbox, so the events that followed it may be gone too.
+var ErrUnknownEvent = errors.New("the last event id is unknown or was cleaned up, reconnect without it")
+
+type StreamOptions struct {
+	BatchSize int
+}
+
+// Position is where a reader of the stream is.
+type Position = repository.StreamPosition
+
+// Stream reads the events of the outbox in the order their transactions
+// committed, which is the sequence clients resume from. Events are kept
+// until the relay cleans them up.
+type Stream struct {
+	outbox  repository.OutboxRepository
+	options StreamOptions
+}
+
+func NewStream(o repository.OutboxRepository, options StreamOptions) *Stream {
+	return &Stream{outbox: o, options: options}
+}
+
+// Last is the position to read after to get only the events to come.
+func (s *Stream) Last(ctx context.Context) (Position, error) {
+	return s.outbox.LastPosition(ctx)
+}
+
+// After is the position following the event with id lastId, or the start of
+// the stream for 0. It fails with ErrUnknownEvent when the event is gone.
+func (s *Stream) After(ctx context.Context, lastId int) (Position, error) {
+	if lastId == 0 {
+		return Position{}, nil
+	}
+
+	position, err := s.outbox.PositionOf(ctx, lastId)
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Position{}, ErrUnknownEvent
+	}
+
+	return position, err
+}
+
+// Next returns the events following after, among types when some are given,
+// and the position following them.
+func (s *Stream) Next(ctx context.Context, after Position, types []string) ([]Event, Position, error) {
+	found, err := s.outbox.FindAfter(ctx, after, types, s.options.BatchSize)
+
+	if err != nil {
+		return nil, after, err
+	}
+
+	events := make([]Event, len(found))
+
+	for i := range found {
+		events[i], err = toEvent(&found[i])
+
+		if err != nil {
+			return nil, after, err
+		}
+	}
+
+	if len(found) != 0 {
+		after = Position{TransactionId: found[len(found)-1].TransactionId, Id: found[len(found)-1].Id}
+	}
+
+	return events, after, nil
+}
@@ -0,0 +1,122 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/LucasAndFlores/user_api/internal/pii"
+)
+
+// UserPayload is the state of the user after the change. Deletions only
+// carry the id. The email and the date of birth are encrypted at rest, and
+// decrypted when the event is published.
+type UserPayload struct {
+	Id          string `json:"id"`
+	Name        string `json:"name,omitempty"`
+	Email       string `json:"email,omitempty"`
+	DateOfBirth string `json:"date_of_birth,omitempty"`
+	Version     int    `json:"version"`
+}
+
+// seal applies encrypt to the pii of the payload.
+func (p *UserPayload) seal(encrypt func(string) (string, error)) error {
+	for _, value := range []*string{&p.Email, &p.DateOfBirth} {
+		if *value == "" {
+			continue
+		}
+
+		sealed, err := encrypt(*value)
+
+		if err != nil {
+			return err
+		}
+
+		*value = sealed
+	}
+
+	return nil
+}
+
+// SealUserPayload encrypts the pii of an encoded payload, which is how the
+// payloads are stored in the outbox and in the webhook deliveries.
+func SealUserPayload(payload []byte) ([]byte, error) {
+	return transformUserPayload(payload, pii.Encrypt)
+}
+
+// OpenUserPayload decrypts the pii of a payload written by SealUserPayload.
+// The payloads stored before they were sealed are returned as they were.
+func OpenUserPayload(payload []byte) ([]byte, error) {
+	return transformUserPayload(payload, pii.Decrypt)
+}
+
+func transformUserPayload(payload []byte, transform func(string) (string, error)) ([]byte, error) {
+	var decoded UserPayload
+
+	err := json.Unmarshal(payload, &decoded)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = decoded.seal(transform)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(decoded)
+}
+
+// OpenEvent decrypts the pii of an encoded event whose payload was sealed,
+// like the bodies of the webhook deliveries.
+func OpenEvent(encoded []byte) ([]byte, error) {
+	var event Event
+
+	err := json.Unmarshal(encoded, &event)
+
+	if err != nil {
+		return nil, err
+	}
+
+	event.Payload, err = OpenUserPayload(event.Payload)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(event)
+}
+
+// NewUserEvent builds the outbox row of a change to the user.
+func NewUserEvent(eventType string, user *model.User) (*model.OutboxEvent, error) {
+	payload := UserPayload{Id: user.ExternalId.String(), Version: user.Version}
+
+	if eventType != USER_DELETED {
+		payload.Name = user.Name
+		payload.Email = user.Email
+		payload.DateOfBirth = user.DateOfBirth.String()
+	}
+
+	err := payload.seal(pii.Encrypt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(payload)
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	return &model.OutboxEvent{
+		AggregateId:   user.ExternalId,
+		Type:          eventType,
+		Payload:       encoded,
+		CreatedAt:     now,
+		NextAttemptAt: now,
+	}, nil
+}
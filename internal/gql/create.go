@@ -0,0 +1,162 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/dto"
+	"github.com/LucasAndFlores/user_api/internal/middleware"
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	clientKey         contextKey = "client"
+	idempotencyKeyKey contextKey = "idempotency_key"
+)
+
+// WithClient tells the mutations who the client is, as the rate limits and
+// the idempotency keys of the HTTP API do, and which Idempotency-Key header
+// it sent, if any.
+func WithClient(ctx context.Context, client string, idempotencyKey string) context.Context {
+	ctx = context.WithValue(ctx, clientKey, client)
+
+	return context.WithValue(ctx, idempotencyKeyKey, idempotencyKey)
+}
+
+func clientFrom(ctx context.Context) (client string, idempotencyKey string) {
+	client, _ = ctx.Value(clientKey).(string)
+	idempotencyKey, _ = ctx.Value(idempotencyKeyKey).(string)
+
+	return client, idempotencyKey
+}
+
+// takeCreateLimit takes from the create_user bucket of the client, the one
+// the POST /users routes take from too.
+func (r *resolver) takeCreateLimit(ctx context.Context) error {
+	if r.limiter == nil {
+		return nil
+	}
+
+	client, _ := clientFrom(ctx)
+
+	result, err := r.limiter.Take(ctx, "create_user:"+client, r.createLimit)
+
+	// an unavailable store must not take the API down with it
+	if err != nil {
+		log.Printf("An error occurred when tried to apply the rate limit: %v", err)
+		return nil
+	}
+
+	if !result.Allowed {
+		return serviceError(fiber.StatusTooManyRequests, map[string]interface{}{"message": "too many requests"})
+	}
+
+	return nil
+}
+
+// createIdempotently runs create once per Idempotency-Key, with the rules of
+// middleware.Idempotency: a retry with the same input gets the outcome of
+// the first call, and server errors are not stored. Only the id of the
+// created user is stored, and a replay reads the user again.
+func (r *resolver) createIdempotently(ctx context.Context, user dto.UserDTO, create func() (int, map[string]interface{})) (int, map[string]interface{}) {
+	client, key := clientFrom(ctx)
+
+	if key == "" || r.idempotency == nil {
+		return create()
+	}
+
+	if len(key) > middleware.MAX_IDEMPOTENCY_KEY_LENGTH {
+		return fiber.StatusBadRequest, map[string]interface{}{"message": "idempotency key is too long"}
+	}
+
+	now := time.Now()
+
+	record := &model.IdempotencyKey{
+		Scope:       "graphql createUser " + client,
+		Key:         key,
+		Fingerprint: fingerprint(user),
+		Status:      model.IDEMPOTENCY_PROCESSING,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(r.idempotencyTTL),
+		LockedUntil: now.Add(r.idempotencyLease),
+	}
+
+	reserved, err := r.idempotency.Reserve(ctx, record)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, map[string]interface{}{"message": "internal server error"}
+	}
+
+	if !reserved {
+		return r.replay(ctx, record)
+	}
+
+	status, body := create()
+
+	if status >= fiber.StatusInternalServerError {
+		if releaseErr := r.idempotency.Release(ctx, record); releaseErr != nil {
+			log.Printf("An error occurred when tried to release the idempotency key: %v", releaseErr)
+		}
+
+		return status, body
+	}
+
+	record.ResponseStatus = status
+	record.ResponseType = fiber.MIMEApplicationJSON
+
+	if status == fiber.StatusCreated {
+		record.ResponseBody, _ = json.Marshal(map[string]interface{}{"id": user.ExternalId})
+	} else {
+		record.ResponseBody, _ = json.Marshal(map[string]interface{}{"message": body["message"]})
+	}
+
+	if err := r.idempotency.Complete(ctx, record); err != nil {
+		log.Printf("An error occurred when tried to store the idempotent response: %v", err)
+
+		if releaseErr := r.idempotency.Release(ctx, record); releaseErr != nil {
+			log.Printf("An error occurred when tried to release the idempotency key: %v", releaseErr)
+		}
+	}
+
+	return status, body
+}
+
+func (r *resolver) replay(ctx context.Context, record *model.IdempotencyKey) (int, map[string]interface{}) {
+	stored, err := r.idempotency.Find(ctx, record.Scope, record.Key)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, map[string]interface{}{"message": "internal server error"}
+	}
+
+	// the original call failed and released the key in the meantime
+	if stored.Key == "" {
+		return fiber.StatusConflict, map[string]interface{}{"message": "a request with the same idempotency key is in progress"}
+	}
+
+	if stored.Fingerprint != record.Fingerprint {
+		return fiber.StatusUnprocessableEntity, map[string]interface{}{"message": "idempotency key was already used with a different payload"}
+	}
+
+	if stored.Status != model.IDEMPOTENCY_COMPLETED {
+		return fiber.StatusConflict, map[string]interface{}{"message": "a request with the same idempotency key is in progress"}
+	}
+
+	var body map[string]interface{}
+
+	err = json.Unmarshal(stored.ResponseBody, &body)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, map[string]interface{}{"message": "internal server error"}
+	}
+
+	return stored.ResponseStatus, body
+}
+
+func fingerprint(user dto.UserDTO) string {
+	input, _ := json.Marshal(user)
+
+	return middleware.IdempotencyFingerprint("graphql createUser", input)
+}
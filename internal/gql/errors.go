@@ -0,0 +1,61 @@
+package gql
+
+import (
+	"github.com/LucasAndFlores/user_api/internal/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+// codesByStatus translates the HTTP statuses returned by the services to the
+// "code" extension of GraphQL errors.
+var codesByStatus = map[int]string{
+	fiber.StatusBadRequest:          "BAD_USER_INPUT",
+	fiber.StatusUnauthorized:        "UNAUTHENTICATED",
+	fiber.StatusForbidden:           "FORBIDDEN",
+	fiber.StatusNotFound:            "NOT_FOUND",
+	fiber.StatusConflict:            "CONFLICT",
+	fiber.StatusUnprocessableEntity: "BAD_USER_INPUT",
+	fiber.StatusTooManyRequests:     "TOO_MANY_REQUESTS",
+}
+
+// Error is a GraphQL error with extensions, which the executor copies to
+// the response.
+type Error struct {
+	Message    string
+	extensions map[string]interface{}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Extensions() map[string]interface{} {
+	return e.extensions
+}
+
+func serviceError(status int, body map[string]interface{}) error {
+	code, ok := codesByStatus[status]
+
+	if !ok {
+		code = "INTERNAL_SERVER_ERROR"
+	}
+
+	message, _ := body["message"].(string)
+
+	return &Error{Message: message, extensions: map[string]interface{}{"code": code}}
+}
+
+func forbidden() error {
+	return &Error{Message: "forbidden", extensions: map[string]interface{}{"code": codesByStatus[fiber.StatusForbidden]}}
+}
+
+// invalidInput reports the validation errors in the same shape as the 422
+// responses of the HTTP API.
+func invalidInput(errors []*middleware.RequestBodyError) error {
+	return &Error{
+		Message: "invalid input",
+		extensions: map[string]interface{}{
+			"code":   codesByStatus[fiber.StatusUnprocessableEntity],
+			"errors": errors,
+		},
+	}
+}
@@ -0,0 +1,109 @@
+package gql
+
+import (
+	"fmt"
+
+	"github.com/LucasAndFlores/user_api/internal/service"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// paginatedFields return lists whose size is set by their "first" argument,
+// so the cost of their selection is multiplied by it.
+var paginatedFields = map[string]int{
+	"users": service.DEFAULT_PAGE_SIZE,
+}
+
+// measure walks the operation to compute its depth and complexity, where
+// every field costs 1. Fragments are expanded where they are spread; the
+// document must be validated first, so they can't be cyclic.
+type measure struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+func (m *measure) selectionSet(set *ast.SelectionSet, depth int) (maxDepth int, complexity int) {
+	if set == nil {
+		return depth, 0
+	}
+
+	maxDepth = depth
+
+	for _, selection := range set.Selections {
+		var childDepth, childComplexity int
+
+		switch selection := selection.(type) {
+		case *ast.Field:
+			childDepth, childComplexity = m.selectionSet(selection.SelectionSet, depth+1)
+			childComplexity = 1 + m.multiplier(selection)*childComplexity
+		case *ast.InlineFragment:
+			childDepth, childComplexity = m.selectionSet(selection.SelectionSet, depth)
+		case *ast.FragmentSpread:
+			if fragment, ok := m.fragments[selection.Name.Value]; ok {
+				childDepth, childComplexity = m.selectionSet(fragment.SelectionSet, depth)
+			}
+		}
+
+		maxDepth = max(maxDepth, childDepth)
+		complexity += childComplexity
+	}
+
+	return maxDepth, complexity
+}
+
+func (m *measure) multiplier(field *ast.Field) int {
+	first, paginated := paginatedFields[field.Name.Value]
+
+	if !paginated {
+		return 1
+	}
+
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "first" {
+			continue
+		}
+
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			fmt.Sscan(value.Value, &first)
+		case *ast.Variable:
+			if variable, ok := m.variables[value.Name.Value].(float64); ok {
+				first = int(variable)
+			}
+		}
+	}
+
+	return max(first, 1)
+}
+
+// checkLimits measures the operation that will be executed.
+func checkLimits(document *ast.Document, operationName string, variables map[string]interface{}, maxDepth, maxComplexity int) error {
+	m := &measure{fragments: map[string]*ast.FragmentDefinition{}, variables: variables}
+
+	var operations []*ast.OperationDefinition
+
+	for _, definition := range document.Definitions {
+		switch definition := definition.(type) {
+		case *ast.FragmentDefinition:
+			m.fragments[definition.Name.Value] = definition
+		case *ast.OperationDefinition:
+			if operationName == "" || definition.Name != nil && definition.Name.Value == operationName {
+				operations = append(operations, definition)
+			}
+		}
+	}
+
+	for _, operation := range operations {
+		depth, complexity := m.selectionSet(operation.SelectionSet, 0)
+
+		if depth > maxDepth {
+			return fmt.Errorf("the query depth %d exceeds the maximum of %d", depth, maxDepth)
+		}
+
+		if complexity > maxComplexity {
+			return fmt.Errorf("the query complexity %d exceeds the maximum of %d", complexity, maxComplexity)
+		}
+	}
+
+	return nil
+}
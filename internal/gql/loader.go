@@ -0,0 +1,68 @@
+package gql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/LucasAndFlores/user_api/internal/dto"
+	"github.com/LucasAndFlores/user_api/internal/service"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// userLoader batches the user lookups of a request. Resolvers queue ids and
+// return thunks; the executor runs the thunks of a level only after every
+// resolver of that level ran, so the first thunk loads all queued ids with
+// one query. The executor is single threaded, hence no locking.
+type userLoader struct {
+	ctx     context.Context
+	users   service.Service
+	pending []uuid.UUID
+	loaded  map[uuid.UUID]*dto.UserDTO
+	err     error
+}
+
+func newUserLoader(ctx context.Context, users service.Service) *userLoader {
+	return &userLoader{ctx: ctx, users: users, loaded: map[uuid.UUID]*dto.UserDTO{}}
+}
+
+func (l *userLoader) load(id uuid.UUID) func() (interface{}, error) {
+	if _, queued := l.loaded[id]; !queued {
+		l.loaded[id] = nil
+		l.pending = append(l.pending, id)
+	}
+
+	return func() (interface{}, error) {
+		if len(l.pending) != 0 {
+			l.flush()
+		}
+
+		if l.err != nil {
+			return nil, l.err
+		}
+
+		if user := l.loaded[id]; user != nil {
+			return *user, nil
+		}
+
+		return nil, nil
+	}
+}
+
+func (l *userLoader) flush() {
+	status, body := l.users.FindUsersByExternalIds(l.ctx, l.pending)
+
+	l.pending = nil
+
+	if status != fiber.StatusOK {
+		message, _ := body["message"].(string)
+		l.err = errors.New(message)
+		return
+	}
+
+	for _, user := range body["users"].([]dto.UserDTO) {
+		user := user
+
+		l.loaded[uuid.MustParse(user.ExternalId)] = &user
+	}
+}
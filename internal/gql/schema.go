@@ -0,0 +1,285 @@
+package gql
+
+import (
+	"context"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/auth"
+	"github.com/LucasAndFlores/user_api/internal/dto"
+	"github.com/LucasAndFlores/user_api/internal/middleware"
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/LucasAndFlores/user_api/internal/ratelimit"
+	"github.com/LucasAndFlores/user_api/internal/redact"
+	"github.com/LucasAndFlores/user_api/internal/repository"
+	"github.com/LucasAndFlores/user_api/internal/service"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+)
+
+type contextKey string
+
+const (
+	principalKey contextKey = "principal"
+	loaderKey    contextKey = "loader"
+)
+
+func WithPrincipal(ctx context.Context, principal *auth.Principal) context.Context {
+	return context.WithValue(ctx, principalKey, principal)
+}
+
+func principalFrom(ctx context.Context) *auth.Principal {
+	principal, _ := ctx.Value(principalKey).(*auth.Principal)
+
+	return principal
+}
+
+func loaderFrom(ctx context.Context) *userLoader {
+	return ctx.Value(loaderKey).(*userLoader)
+}
+
+// userField resolves a field of dto.UserDTO, the source of every User.
+func userField(value func(dto.UserDTO) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return value(p.Source.(dto.UserDTO)), nil
+	}
+}
+
+// piiField resolves a field of dto.UserDTO masked for the caller, per the
+// redaction policy of the field.
+func piiField(field string, value func(dto.UserDTO) string) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		user := p.Source.(dto.UserDTO)
+
+		return redact.String(redact.ViewerOf(principalFrom(p.Context), user.ExternalId), field, value(user)), nil
+	}
+}
+
+var userType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "User",
+	Fields: graphql.Fields{
+		"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.ID), Resolve: userField(func(u dto.UserDTO) interface{} { return u.ExternalId })},
+		"name":        &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: userField(func(u dto.UserDTO) interface{} { return u.Name })},
+		"email":       &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: piiField("email", func(u dto.UserDTO) string { return u.Email })},
+		"dateOfBirth": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: piiField("date_of_birth", func(u dto.UserDTO) string { return u.DateOfBirth })},
+		"version":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: userField(func(u dto.UserDTO) interface{} { return u.Version })},
+		"createdAt":   &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime), Resolve: userField(func(u dto.UserDTO) interface{} { return u.CreatedAt })},
+		"updatedAt":   &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime), Resolve: userField(func(u dto.UserDTO) interface{} { return u.UpdatedAt })},
+	},
+})
+
+type userEdge struct {
+	Cursor string      `json:"cursor"`
+	Node   dto.UserDTO `json:"node"`
+}
+
+type pageInfo struct {
+	HasNextPage bool    `json:"hasNextPage"`
+	EndCursor   *string `json:"endCursor"`
+}
+
+type userConnection struct {
+	Edges    []userEdge `json:"edges"`
+	PageInfo pageInfo   `json:"pageInfo"`
+}
+
+var userConnectionType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "UserConnection",
+	Fields: graphql.Fields{
+		"edges": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.NewObject(graphql.ObjectConfig{
+			Name: "UserEdge",
+			Fields: graphql.Fields{
+				"cursor": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"node":   &graphql.Field{Type: graphql.NewNonNull(userType)},
+			},
+		}))))},
+		"pageInfo": &graphql.Field{Type: graphql.NewNonNull(graphql.NewObject(graphql.ObjectConfig{
+			Name: "PageInfo",
+			Fields: graphql.Fields{
+				"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+				"endCursor":   &graphql.Field{Type: graphql.String},
+			},
+		}))},
+	},
+})
+
+var userFilterType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "UserFilter",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"email":        &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Exact email, case-insensitive"},
+		"name":         &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Part of the name, case-insensitive"},
+		"updatedSince": &graphql.InputObjectFieldConfig{Type: graphql.DateTime, Description: "Users updated at or after this time, widened by a minute to catch the changes committing late"},
+	},
+})
+
+var createUserInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "CreateUserInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"id":          &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.ID)},
+		"name":        &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"email":       &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"dateOfBirth": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String), Description: "YYYY-MM-DD, or RFC 3339"},
+		"password":    &graphql.InputObjectFieldConfig{Type: graphql.String},
+	},
+})
+
+type resolver struct {
+	users            service.Service
+	requireAdminMFA  bool
+	limiter          ratelimit.Store
+	createLimit      ratelimit.Limit
+	idempotency      repository.IdempotencyRepository
+	idempotencyTTL   time.Duration
+	idempotencyLease time.Duration
+	validator        *validator.Validate
+}
+
+func newSchema(r *resolver) (graphql.Schema, error) {
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"user": &graphql.Field{
+					Type: userType,
+					Args: graphql.FieldConfigArgument{
+						"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					},
+					Resolve: r.user,
+				},
+				"users": &graphql.Field{
+					Type:        graphql.NewNonNull(userConnectionType),
+					Description: "Admins only",
+					Args: graphql.FieldConfigArgument{
+						"filter": &graphql.ArgumentConfig{Type: userFilterType},
+						"first":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: service.DEFAULT_PAGE_SIZE},
+						"after":  &graphql.ArgumentConfig{Type: graphql.String},
+					},
+					Resolve: r.listUsers,
+				},
+			},
+		}),
+		Mutation: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Mutation",
+			Fields: graphql.Fields{
+				"createUser": &graphql.Field{
+					Type: graphql.NewNonNull(userType),
+					Args: graphql.FieldConfigArgument{
+						"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(createUserInputType)},
+					},
+					Resolve: r.createUser,
+				},
+			},
+		}),
+	})
+}
+
+func (r *resolver) user(p graphql.ResolveParams) (interface{}, error) {
+	id, err := uuid.Parse(p.Args["id"].(string))
+
+	if err != nil {
+		return nil, serviceError(fiber.StatusBadRequest, map[string]interface{}{"message": "unable to parse the id"})
+	}
+
+	return loaderFrom(p.Context).load(id), nil
+}
+
+func (r *resolver) listUsers(p graphql.ResolveParams) (interface{}, error) {
+	principal := principalFrom(p.Context)
+
+	if principal == nil || (principal.Role != model.ROLE_ADMIN && principal.Role != model.ROLE_SUPPORT) || (r.requireAdminMFA && !principal.MFA) {
+		return nil, forbidden()
+	}
+
+	var filter dto.UserFilter
+
+	if args, ok := p.Args["filter"].(map[string]interface{}); ok {
+		filter.Email, _ = args["email"].(string)
+		filter.Name, _ = args["name"].(string)
+
+		if since, ok := args["updatedSince"].(time.Time); ok {
+			filter.UpdatedSince = &since
+		}
+	}
+
+	after, _ := p.Args["after"].(string)
+
+	status, body := r.users.List(p.Context, filter, nil, p.Args["first"].(int), after)
+
+	if status != fiber.StatusOK {
+		return nil, serviceError(status, body)
+	}
+
+	users := body["users"].([]dto.UserDTO)
+	cursors := body["cursors"].([]string)
+
+	connection := userConnection{Edges: make([]userEdge, len(users))}
+
+	for i, user := range users {
+		connection.Edges[i] = userEdge{Cursor: cursors[i], Node: user}
+	}
+
+	if len(cursors) != 0 {
+		connection.PageInfo.EndCursor = &cursors[len(cursors)-1]
+	}
+
+	connection.PageInfo.HasNextPage = body["next_cursor"].(string) != ""
+
+	return connection, nil
+}
+
+func (r *resolver) createUser(p graphql.ResolveParams) (interface{}, error) {
+	input := p.Args["input"].(map[string]interface{})
+
+	user := dto.UserDTO{
+		Name:        input["name"].(string),
+		Email:       input["email"].(string),
+		ExternalId:  input["id"].(string),
+		DateOfBirth: input["dateOfBirth"].(string),
+	}
+
+	user.Password, _ = input["password"].(string)
+
+	err := r.takeCreateLimit(p.Context)
+
+	if err != nil {
+		return nil, err
+	}
+
+	errors := r.validate(user)
+
+	if len(errors) != 0 {
+		return nil, invalidInput(errors)
+	}
+
+	status, body := r.createIdempotently(p.Context, user, func() (int, map[string]interface{}) {
+		return r.users.Create(p.Context, user)
+	})
+
+	if status != fiber.StatusCreated {
+		return nil, serviceError(status, body)
+	}
+
+	status, body = r.users.FindUserByExternalId(p.Context, uuid.MustParse(user.ExternalId), nil)
+
+	if status != fiber.StatusOK {
+		return nil, serviceError(status, body)
+	}
+
+	return body["user"], nil
+}
+
+// validate applies the checks of middleware.ValidateRequestBody.
+func (r *resolver) validate(user dto.UserDTO) []*middleware.RequestBodyError {
+	var errors []*middleware.RequestBodyError
+
+	err := r.validator.Struct(user)
+
+	if fieldErrors, ok := err.(validator.ValidationErrors); ok {
+		for _, fieldError := range fieldErrors {
+			errors = append(errors, &middleware.RequestBodyError{Field: fieldError.Field(), Tag: fieldError.Tag(), Value: fieldError.Param()})
+		}
+	}
+
+	return errors
+}
@@ -0,0 +1,97 @@
+package gql
+
+import (
+	"context"
+	"github.com/go-playground/validator/v10"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/ratelimit"
+	"github.com/LucasAndFlores/user_api/internal/repository"
+	"github.com/LucasAndFlores/user_api/internal/service"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+type Options struct {
+	MaxDepth      int
+	MaxComplexity int
+	// RequireAdminMFA is the same rule as for the admin routes of the HTTP API.
+	RequireAdminMFA bool
+	// Limiter and CreateLimit apply the limit of the POST /users routes to
+	// createUser, in the same buckets.
+	Limiter     ratelimit.Store
+	CreateLimit ratelimit.Limit
+	// Idempotency stores the outcome of createUser per Idempotency-Key, for
+	// IdempotencyTTL. A call holds its key for IdempotencyLease at most.
+	Idempotency      repository.IdempotencyRepository
+	IdempotencyTTL   time.Duration
+	IdempotencyLease time.Duration
+	// Validator applies the validate tags of the HTTP API.
+	Validator *validator.Validate
+}
+
+type Request struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+// Server executes GraphQL requests on top of the user service.
+type Server struct {
+	schema  graphql.Schema
+	users   service.Service
+	options Options
+}
+
+func NewServer(users service.Service, options Options) (*Server, error) {
+	schema, err := newSchema(&resolver{
+		users:            users,
+		requireAdminMFA:  options.RequireAdminMFA,
+		limiter:          options.Limiter,
+		createLimit:      options.CreateLimit,
+		idempotency:      options.Idempotency,
+		idempotencyTTL:   options.IdempotencyTTL,
+		idempotencyLease: options.IdempotencyLease,
+		validator:        options.Validator,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{schema: schema, users: users, options: options}, nil
+}
+
+// Execute runs the request. It reports rejected when the request was not
+// executed at all, for being unparsable, invalid or over the limits.
+func (s *Server) Execute(ctx context.Context, request Request) (result *graphql.Result, rejected bool) {
+	document, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(request.Query), Name: "GraphQL request"}),
+	})
+
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}, true
+	}
+
+	validation := graphql.ValidateDocument(&s.schema, document, nil)
+
+	if !validation.IsValid {
+		return &graphql.Result{Errors: validation.Errors}, true
+	}
+
+	err = checkLimits(document, request.OperationName, request.Variables, s.options.MaxDepth, s.options.MaxComplexity)
+
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}, true
+	}
+
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        s.schema,
+		AST:           document,
+		OperationName: request.OperationName,
+		Args:          request.Variables,
+		Context:       context.WithValue(ctx, loaderKey, newUserLoader(ctx, s.users)),
+	}), false
+}
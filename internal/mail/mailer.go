@@ -0,0 +1,171 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/redact"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(context.Context, Message) error
+}
+
+func (m Message) format(from string) []byte {
+	return []byte(fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n", from, m.To, m.Subject, m.Body))
+}
+
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPMailer(host string, port int, username string, password string, from string) Mailer {
+	var auth smtp.Auth
+
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTPMailer{addr: fmt.Sprintf("%s:%d", host, port), auth: auth, from: from}
+}
+
+// Send does what smtp.SendMail does, on a connection bound to ctx: the
+// dial is cancelled with it, and the whole exchange must end by its
+// deadline.
+func (m *SMTPMailer) Send(ctx context.Context, message Message) error {
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+
+	defer stop()
+
+	host, _, _ := net.SplitHostPort(m.addr)
+
+	client, err := smtp.NewClient(conn, host)
+
+	if err != nil {
+		return err
+	}
+
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: host})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.auth != nil {
+		err = client.Auth(m.auth)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	err = client.Mail(m.from)
+
+	if err != nil {
+		return err
+	}
+
+	err = client.Rcpt(message.To)
+
+	if err != nil {
+		return err
+	}
+
+	writer, err := client.Data()
+
+	if err != nil {
+		return err
+	}
+
+	_, err = writer.Write(message.format(m.from))
+
+	if err != nil {
+		return err
+	}
+
+	err = writer.Close()
+
+	if err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// FileMailer writes every message to its own file in dir instead of sending
+// it, which is handy for local development and tests.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir string, from string) (Mailer, error) {
+	err := os.MkdirAll(dir, 0o755)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, message Message) error {
+	recipient := strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(message.To)
+
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), recipient)
+
+	return os.WriteFile(filepath.Join(m.dir, name), message.format(m.from), 0o644)
+}
+
+// tokenPattern matches the tokens of the verification and password reset
+// links, which would let anyone reading the logs take over the account.
+var tokenPattern = regexp.MustCompile(`token=[^\s&]+`)
+
+// LogMailer only logs the messages, with the recipient masked and the
+// tokens of the links removed, so it doesn't deliver anything usable.
+type LogMailer struct{}
+
+func NewLogMailer() Mailer {
+	return &LogMailer{}
+}
+
+func (m *LogMailer) Send(ctx context.Context, message Message) error {
+	log.Printf("mail to=%s subject=%q body=%q", redact.String(redact.EVERYONE, "email", message.To), message.Subject, tokenPattern.ReplaceAllString(message.Body, "token=[redacted]"))
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/LucasAndFlores/user_api/internal/audit"
+	"github.com/LucasAndFlores/user_api/internal/auth"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Authenticate requires a valid bearer access token and stores its
+// principal in the request locals.
+func Authenticate(issuer *auth.TokenIssuer) fiber.Handler {
+	return authenticate(issuer, false)
+}
+
+// OptionalAuthenticate lets anonymous requests through, but still rejects
+// invalid tokens.
+func OptionalAuthenticate(issuer *auth.TokenIssuer) fiber.Handler {
+	return authenticate(issuer, true)
+}
+
+func authenticate(issuer *auth.TokenIssuer, optional bool) fiber.Handler {
+	return func(fi *fiber.Ctx) error {
+		header := fi.Get(fiber.HeaderAuthorization)
+
+		if optional && header == "" {
+			return fi.Next()
+		}
+
+		token, found := strings.CutPrefix(header, "Bearer ")
+
+		if !found || token == "" {
+			return fi.Status(fiber.StatusUnauthorized).JSON(map[string]string{"message": "missing bearer token"})
+		}
+
+		claims, err := issuer.ParseAccessToken(token)
+
+		if err != nil {
+			return fi.Status(fiber.StatusUnauthorized).JSON(map[string]string{"message": "invalid token"})
+		}
+
+		principal, err := claims.Principal()
+
+		if err != nil {
+			return fi.Status(fiber.StatusUnauthorized).JSON(map[string]string{"message": "invalid token"})
+		}
+
+		fi.Locals(auth.PRINCIPAL_KEY, principal)
+
+		audit.Bind(fi, audit.Actor{
+			Principal: audit.UserPrincipal(principal.Role, principal.ExternalId),
+			RequestId: audit.ActorFrom(fi.Context()).RequestId,
+		})
+
+		return fi.Next()
+	}
+}
+
+// RequireRole must run after Authenticate. It lets callers with any of the
+// roles through.
+func RequireRole(roles ...string) fiber.Handler {
+	return func(fi *fiber.Ctx) error {
+		principal := auth.PrincipalFrom(fi)
+
+		if principal == nil || !slices.Contains(roles, principal.Role) {
+			return fi.Status(fiber.StatusForbidden).JSON(map[string]string{"message": "forbidden"})
+		}
+
+		return fi.Next()
+	}
+}
+
+// RequireMFA must run after Authenticate. It rejects sessions that were not
+// opened with a second factor.
+func RequireMFA(fi *fiber.Ctx) error {
+	principal := auth.PrincipalFrom(fi)
+
+	if principal == nil || !principal.MFA {
+		return fi.Status(fiber.StatusForbidden).JSON(map[string]string{"message": "multi-factor authentication required"})
+	}
+
+	return fi.Next()
+}
+
+// RequireSelfOrRole must run after Authenticate. It lets users act on their
+// own resource, identified by the "id" route param, and callers with any of
+// the roles act on anyone's, provided they used a second factor when
+// requireMFA is set.
+func RequireSelfOrRole(requireMFA bool, roles ...string) fiber.Handler {
+	return func(fi *fiber.Ctx) error {
+		principal := auth.PrincipalFrom(fi)
+
+		if principal == nil {
+			return fi.Status(fiber.StatusForbidden).JSON(map[string]string{"message": "forbidden"})
+		}
+
+		if strings.EqualFold(principal.ExternalId.String(), fi.Params("id")) {
+			return fi.Next()
+		}
+
+		if !slices.Contains(roles, principal.Role) || (requireMFA && !principal.MFA) {
+			return fi.Status(fiber.StatusForbidden).JSON(map[string]string{"message": "forbidden"})
+		}
+
+		return fi.Next()
+	}
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Deprecated marks the responses of a route that is kept only for backwards
+// compatibility, following RFC 9745 (Deprecation) and RFC 8594 (Sunset), and
+// points clients to the route that replaces it.
+func Deprecated(deprecatedAt, sunset time.Time, successor string) fiber.Handler {
+	deprecation := fmt.Sprintf("@%d", deprecatedAt.Unix())
+	sunsetDate := sunset.UTC().Format(http.TimeFormat)
+	link := fmt.Sprintf(`<%s>; rel="successor-version"`, successor)
+
+	return func(fi *fiber.Ctx) error {
+		fi.Set("Deprecation", deprecation)
+		fi.Set("Sunset", sunsetDate)
+		fi.Append(fiber.HeaderLink, link)
+
+		return fi.Next()
+	}
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"log"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/LucasAndFlores/user_api/internal/pii"
+	"github.com/LucasAndFlores/user_api/internal/repository"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	IDEMPOTENCY_KEY_HEADER     = "Idempotency-Key"
+	IDEMPOTENT_REPLAYED_HEADER = "Idempotent-Replayed"
+	MAX_IDEMPOTENCY_KEY_LENGTH = 255
+)
+
+// Idempotency replays the stored response when a request is retried with
+// the same Idempotency-Key header and payload. Requests without the header
+// are handled as usual. Server errors are not stored, so they can be retried.
+// A request holds its key for lease at most, after which a retry runs again.
+func Idempotency(repo repository.IdempotencyRepository, clients *Clients, ttl time.Duration, lease time.Duration) fiber.Handler {
+	return func(fi *fiber.Ctx) error {
+		key := fi.Get(IDEMPOTENCY_KEY_HEADER)
+
+		if key == "" {
+			return fi.Next()
+		}
+
+		if len(key) > MAX_IDEMPOTENCY_KEY_LENGTH {
+			return fi.Status(fiber.StatusBadRequest).JSON(map[string]string{"message": "idempotency key is too long"})
+		}
+
+		now := time.Now()
+
+		record := &model.IdempotencyKey{
+			Scope:       fi.Method() + " " + fi.Route().Path + " " + clients.Key(fi),
+			Key:         key,
+			Fingerprint: IdempotencyFingerprint(fi.Method()+" "+fi.OriginalURL(), fi.Body()),
+			Status:      model.IDEMPOTENCY_PROCESSING,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(ttl),
+			LockedUntil: now.Add(lease),
+		}
+
+		reserved, err := repo.Reserve(fi.Context(), record)
+
+		if err != nil {
+			return fi.Status(fiber.StatusInternalServerError).JSON(map[string]string{"message": "internal server error"})
+		}
+
+		if !reserved {
+			return replay(fi, repo, record)
+		}
+
+		err = fi.Next()
+
+		status := fi.Response().StatusCode()
+
+		if err != nil || status >= fiber.StatusInternalServerError {
+			if releaseErr := repo.Release(fi.Context(), record); releaseErr != nil {
+				log.Printf("An error occurred when tried to release the idempotency key: %v", releaseErr)
+			}
+
+			return err
+		}
+
+		record.ResponseStatus = status
+		record.ResponseType = string(fi.Response().Header.ContentType())
+		record.ResponseBody = append([]byte(nil), fi.Response().Body()...)
+		record.ResponseLocation = string(fi.Response().Header.Peek(fiber.HeaderLocation))
+
+		if err := repo.Complete(fi.Context(), record); err != nil {
+			log.Printf("An error occurred when tried to store the idempotent response: %v", err)
+
+			// a retry runs again rather than waiting for the lease
+			if releaseErr := repo.Release(fi.Context(), record); releaseErr != nil {
+				log.Printf("An error occurred when tried to release the idempotency key: %v", releaseErr)
+			}
+		}
+
+		return nil
+	}
+}
+
+func replay(fi *fiber.Ctx, repo repository.IdempotencyRepository, record *model.IdempotencyKey) error {
+	stored, err := repo.Find(fi.Context(), record.Scope, record.Key)
+
+	if err != nil {
+		return fi.Status(fiber.StatusInternalServerError).JSON(map[string]string{"message": "internal server error"})
+	}
+
+	// the original request failed and released the key in the meantime
+	if stored.Key == "" {
+		fi.Set(fiber.HeaderRetryAfter, "1")
+		return fi.Status(fiber.StatusConflict).JSON(map[string]string{"message": "a request with the same idempotency key is in progress"})
+	}
+
+	if stored.Fingerprint != record.Fingerprint {
+		return fi.Status(fiber.StatusUnprocessableEntity).JSON(map[string]string{"message": "idempotency key was already used with a different payload"})
+	}
+
+	if stored.Status != model.IDEMPOTENCY_COMPLETED {
+		fi.Set(fiber.HeaderRetryAfter, "1")
+		return fi.Status(fiber.StatusConflict).JSON(map[string]string{"message": "a request with the same idempotency key is in progress"})
+	}
+
+	fi.Set(IDEMPOTENT_REPLAYED_HEADER, "true")
+	fi.Set(fiber.HeaderContentType, stored.ResponseType)
+
+	if stored.ResponseLocation != "" {
+		fi.Location(stored.ResponseLocation)
+	}
+
+	return fi.Status(stored.ResponseStatus).Send(stored.ResponseBody)
+}
+
+// IdempotencyFingerprint identifies the payload sent with an Idempotency-Key,
+// the operation and its input, for every API storing idempotency keys. The
+// hash is keyed, as the input may hold a password.
+func IdempotencyFingerprint(operation string, input []byte) string {
+	return pii.Fingerprint([]byte(operation), input)
+}
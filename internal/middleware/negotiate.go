@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/LucasAndFlores/user_api/internal/codec"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Negotiate rejects requests whose body or accepted responses are in none of
+// the media types of the codec package, before any other work is done.
+func Negotiate(fi *fiber.Ctx) error {
+	supported := strings.Join(codec.MediaTypes(), ", ")
+
+	if _, ok := codec.Acceptable(fi); !ok {
+		return fi.Status(fiber.StatusNotAcceptable).JSON(map[string]string{"message": fmt.Sprintf("responses are available as %s", supported)})
+	}
+
+	if len(fi.Body()) != 0 {
+		if _, ok := codec.ForMediaType(fi.Get(fiber.HeaderContentType)); !ok {
+			return fi.Status(fiber.StatusUnsupportedMediaType).JSON(map[string]string{"message": fmt.Sprintf("request bodies must be one of %s", supported)})
+		}
+	}
+
+	return fi.Next()
+}
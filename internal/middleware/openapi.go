@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/LucasAndFlores/user_api/internal/codec"
+	"github.com/LucasAndFlores/user_api/internal/openapi"
+	"github.com/gofiber/fiber/v2"
+)
+
+type OpenAPIOptions struct {
+	ValidateRequests bool
+	// ValidateResponses turns undocumented responses into server errors. It
+	// is meant for tests, where a drift must fail loudly.
+	ValidateResponses bool
+}
+
+// OpenAPIValidation checks the requests of documented operations against
+// the document, answering 422 with the same error list as
+// ValidateRequestBody. Paths missing from the document are left alone.
+func OpenAPIValidation(doc *openapi.Document, options OpenAPIOptions) fiber.Handler {
+	return func(fi *fiber.Ctx) error {
+		operation := doc.Match(fi.Method(), fi.Path())
+
+		if operation == nil {
+			return fi.Next()
+		}
+
+		if options.ValidateRequests {
+			violations := requestViolations(fi, doc, operation)
+
+			if len(violations) != 0 {
+				return fi.Status(fiber.ErrUnprocessableEntity.Code).JSON(toRequestBodyErrors(violations))
+			}
+		}
+
+		err := fi.Next()
+
+		if err != nil || !options.ValidateResponses {
+			return err
+		}
+
+		violations := responseViolations(fi, doc, operation)
+
+		if len(violations) != 0 {
+			log.Printf("The response of %v %v does not match the OpenAPI document: %+v", fi.Method(), fi.Path(), violations)
+
+			return fi.Status(fiber.StatusInternalServerError).JSON(map[string]interface{}{
+				"message": "the response does not match the OpenAPI document",
+				"errors":  toRequestBodyErrors(violations),
+			})
+		}
+
+		return nil
+	}
+}
+
+func requestViolations(fi *fiber.Ctx, doc *openapi.Document, operation *openapi.Operation) []openapi.Violation {
+	var violations []openapi.Violation
+
+	for _, parameter := range operation.Parameters {
+		if !parameter.Required {
+			continue
+		}
+
+		var value string
+
+		switch parameter.In {
+		case "query":
+			value = fi.Query(parameter.Name)
+		case "header":
+			value = fi.Get(parameter.Name)
+		default:
+			continue
+		}
+
+		if value == "" {
+			violations = append(violations, openapi.Violation{Field: parameter.Name, Keyword: "required"})
+		}
+	}
+
+	if operation.RequestBody == nil {
+		return violations
+	}
+
+	media, ok := operation.RequestBody.Content[fiber.MIMEApplicationJSON]
+
+	if !ok {
+		return violations
+	}
+
+	var body interface{}
+
+	// an unparsable body is reported as a value of the wrong type
+	codec.Decode(fi, &body)
+
+	return append(violations, doc.Validate(media.Schema, body)...)
+}
+
+func responseViolations(fi *fiber.Ctx, doc *openapi.Document, operation *openapi.Operation) []openapi.Violation {
+	status := fi.Response().StatusCode()
+
+	// server errors are not part of the contract of any operation
+	if status >= fiber.StatusInternalServerError {
+		return nil
+	}
+
+	// streams never end, so they can't be read here
+	if fi.Response().IsBodyStream() {
+		return nil
+	}
+
+	response, ok := operation.Responses[strconv.Itoa(status)]
+
+	if !ok {
+		return []openapi.Violation{{Field: "status", Keyword: "responses", Param: strconv.Itoa(status)}}
+	}
+
+	media, ok := response.Content[fiber.MIMEApplicationJSON]
+
+	body := fi.Response().Body()
+
+	if !ok || len(body) == 0 {
+		return nil
+	}
+
+	// every codec shares the schema of the JSON media type, but XML can't
+	// tell a one item list from an object
+	c, ok := codec.ForMediaType(string(fi.Response().Header.ContentType()))
+
+	if !ok || c == codec.XML {
+		return nil
+	}
+
+	var value interface{}
+
+	err := c.Unmarshal(body, &value)
+
+	if err != nil {
+		return []openapi.Violation{{Field: "body", Keyword: "type", Param: c.MediaType}}
+	}
+
+	return doc.Validate(media.Schema, value)
+}
+
+func toRequestBodyErrors(violations []openapi.Violation) []*RequestBodyError {
+	var errors []*RequestBodyError
+
+	for _, violation := range violations {
+		errors = append(errors, &RequestBodyError{
+			Field: violation.Field,
+			Tag:   violation.Keyword,
+			Value: violation.Param,
+		})
+	}
+
+	return errors
+}
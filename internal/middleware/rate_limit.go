@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+
+	"github.com/LucasAndFlores/user_api/internal/auth"
+	"github.com/LucasAndFlores/user_api/internal/ratelimit"
+	"github.com/gofiber/fiber/v2"
+)
+
+const API_KEY_HEADER = "X-API-Key"
+
+// Clients tells apart the clients of the rate limits and of the idempotency
+// keys: by API key, then by authenticated principal, and finally by IP
+// (which honours the trusted proxy settings of the app). Only the issued API
+// keys count, or a client could get a new bucket with every request by
+// making up keys.
+type Clients struct {
+	issuer  *auth.TokenIssuer
+	apiKeys map[string]bool
+}
+
+// NewClients takes the SHA-256 hashes, in hex, of the issued API keys.
+func NewClients(issuer *auth.TokenIssuer, apiKeyHashes []string) *Clients {
+	apiKeys := make(map[string]bool, len(apiKeyHashes))
+
+	for _, hash := range apiKeyHashes {
+		apiKeys[strings.ToLower(hash)] = true
+	}
+
+	return &Clients{issuer: issuer, apiKeys: apiKeys}
+}
+
+// RateLimit applies limit to each client of the route group identified by
+// name.
+func RateLimit(store ratelimit.Store, clients *Clients, name string, limit ratelimit.Limit) fiber.Handler {
+	return func(fi *fiber.Ctx) error {
+		key := fmt.Sprintf("%s:%s", name, clients.Key(fi))
+
+		result, err := store.Take(fi.Context(), key, limit)
+
+		// an unavailable store must not take the API down with it
+		if err != nil {
+			log.Printf("An error occurred when tried to apply the rate limit: %v", err)
+			return fi.Next()
+		}
+
+		fi.Set("RateLimit-Limit", fmt.Sprint(result.Limit))
+		fi.Set("RateLimit-Remaining", fmt.Sprint(result.Remaining))
+		fi.Set("RateLimit-Reset", fmt.Sprint(ceilSeconds(result.ResetAfter.Seconds())))
+
+		if !result.Allowed {
+			fi.Set(fiber.HeaderRetryAfter, fmt.Sprint(ceilSeconds(result.RetryAfter.Seconds())))
+			return fi.Status(fiber.StatusTooManyRequests).JSON(map[string]string{"message": "too many requests"})
+		}
+
+		return fi.Next()
+	}
+}
+
+func (c *Clients) Key(fi *fiber.Ctx) string {
+	if apiKey := fi.Get(API_KEY_HEADER); apiKey != "" {
+		if hash := auth.HashOpaqueToken(apiKey); c.apiKeys[hash] {
+			return "key:" + hash
+		}
+	}
+
+	if principal := auth.PrincipalFrom(fi); principal != nil {
+		return "principal:" + principal.ExternalId.String()
+	}
+
+	if token, found := strings.CutPrefix(fi.Get(fiber.HeaderAuthorization), "Bearer "); found {
+		if claims, err := c.issuer.ParseAccessToken(token); err == nil {
+			return "principal:" + claims.Subject
+		}
+	}
+
+	return "ip:" + fi.IP()
+}
+
+func ceilSeconds(s float64) int {
+	return int(math.Ceil(s))
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"github.com/LucasAndFlores/user_api/internal/audit"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// MAX_REQUEST_ID_LENGTH bounds the ids sent by clients, which end up in the
+// audit records.
+const MAX_REQUEST_ID_LENGTH = 128
+
+// RequestID tags the request with the X-Request-ID header sent by the client,
+// or a new one, and echoes it in the response. Until Authenticate finds a
+// principal, the changes made by the request are audited as anonymous.
+func RequestID(fi *fiber.Ctx) error {
+	id := fi.Get(fiber.HeaderXRequestID)
+
+	if id == "" || len(id) > MAX_REQUEST_ID_LENGTH {
+		id = uuid.NewString()
+	}
+
+	fi.Set(fiber.HeaderXRequestID, id)
+
+	audit.Bind(fi, audit.Actor{Principal: audit.ANONYMOUS, RequestId: id})
+
+	return fi.Next()
+}
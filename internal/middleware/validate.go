@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"errors"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/auth"
+	"github.com/LucasAndFlores/user_api/internal/civil"
+	"github.com/LucasAndFlores/user_api/internal/codec"
+	"github.com/LucasAndFlores/user_api/internal/webhook"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type RequestBodyError struct {
+	Field string
+	Tag   string
+	Value string
+}
+
+// DateOfBirthRules bound the ages accepted by the date_of_birth rule, which
+// always rejects the dates in the future.
+type DateOfBirthRules struct {
+	MinAge int
+	MaxAge int
+}
+
+// ValidatorOptions are the rules of the config applied by the validator.
+type ValidatorOptions struct {
+	DateOfBirth DateOfBirthRules
+	// AllowPrivateWebhookTargets makes the public_url rule accept the hosts
+	// of the local network.
+	AllowPrivateWebhookTargets bool
+}
+
+// latestTimeZone is where the days start first, so that a date of birth
+// is only in the future once it is in the future everywhere.
+var latestTimeZone = time.FixedZone("UTC+14", 14*60*60)
+
+func (r DateOfBirthRules) Accept(date civil.Date, today civil.Date) bool {
+	if date.After(today) {
+		return false
+	}
+
+	age := date.YearsUntil(today)
+
+	return age >= r.MinAge && (r.MaxAge == 0 || age <= r.MaxAge)
+}
+
+// NewValidator builds the validator of the validate tags of the DTOs, shared
+// by the HTTP, GraphQL and gRPC APIs.
+func NewValidator(options ValidatorOptions) *validator.Validate {
+	v := validator.New()
+
+	v.RegisterValidation("password", func(fl validator.FieldLevel) bool {
+		return auth.IsStrongPassword(fl.Field().String())
+	})
+
+	v.RegisterValidation("date", func(fl validator.FieldLevel) bool {
+		_, err := civil.ParseDate(fl.Field().String())
+
+		return err == nil
+	})
+
+	// only the URL itself is checked, as resolving its host here would block
+	// on the network; the dispatcher checks the addresses when connecting
+	v.RegisterValidation("public_url", func(fl validator.FieldLevel) bool {
+		err := webhook.CheckTarget(fl.Field().String())
+
+		return err == nil || (options.AllowPrivateWebhookTargets && errors.Is(err, webhook.ErrPrivateTarget))
+	})
+
+	// the date rule checks the format
+	v.RegisterValidation("date_of_birth", func(fl validator.FieldLevel) bool {
+		date, err := civil.ParseDate(fl.Field().String())
+
+		if err != nil {
+			return true
+		}
+
+		return options.DateOfBirth.Accept(date, civil.DateOf(time.Now().In(latestTimeZone)))
+	})
+
+	return v
+}
+
+// ValidateRequestBody checks the body against the validate tags of T and
+// answers with the list of the failed rules.
+func ValidateRequestBody[T any](v *validator.Validate) fiber.Handler {
+	return func(fi *fiber.Ctx) error {
+		var errors []*RequestBodyError
+
+		var body T
+
+		err := codec.Decode(fi, &body)
+
+		if err != nil {
+			return codec.Send(fi, fiber.StatusBadRequest, map[string]string{"message": codec.UNPARSABLE_BODY_MESSAGE})
+		}
+
+		err = v.Struct(body)
+
+		if err != nil {
+			for _, err := range err.(validator.ValidationErrors) {
+				var el RequestBodyError
+				el.Field = err.Field()
+				el.Tag = err.Tag()
+				el.Value = err.Param()
+				errors = append(errors, &el)
+			}
+		}
+
+		if len(errors) != 0 {
+			return codec.Send(fi, fiber.ErrUnprocessableEntity.Code, errors)
+		}
+
+		return fi.Next()
+	}
+}
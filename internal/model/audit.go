@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AUDIT_ACTION_CREATE = "create"
+	AUDIT_ACTION_UPDATE = "update"
+	AUDIT_ACTION_DELETE = "delete"
+	AUDIT_ACTION_ERASE  = "erase"
+)
+
+// AuditRecord is a change made to a user. Records outlive the user they
+// describe, and the database refuses to update or delete them.
+type AuditRecord struct {
+	Id             int       `gorm:"primary_key"`
+	UserExternalId uuid.UUID `gorm:"column:user_external_id;type:uuid;not null;index"`
+	// Actor is the principal behind the change, such as "admin:<external id>".
+	Actor     string    `gorm:"not null"`
+	RequestId string    `gorm:"column:request_id"`
+	Action    string    `gorm:"not null"`
+	Changes   []byte    `gorm:"type:jsonb;not null"`
+	CreatedAt time.Time `gorm:"column:created_at;type:timestamp with time zone;not null"`
+}
+
+// AuditChange is the change of one field, stored in AuditRecord.Changes.
+type AuditChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
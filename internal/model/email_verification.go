@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmailVerification is a verification link sent to an email. EmailIndex is
+// the blind index of that email, so a link sent before the user changed it
+// can't verify the new one.
+type EmailVerification struct {
+	Id         int        `gorm:"type:int;primary_key"`
+	UserId     int        `gorm:"column:user_id;not null;index"`
+	TokenId    uuid.UUID  `gorm:"column:token_id;type:uuid;unique;not null"`
+	EmailIndex string     `gorm:"column:email_index;not null;default:''"`
+	ExpiresAt  time.Time  `gorm:"column:expires_at;type:timestamp with time zone;not null"`
+	CreatedAt  time.Time  `gorm:"column:created_at;type:timestamp with time zone;not null"`
+	UsedAt     *time.Time `gorm:"column:used_at;type:timestamp with time zone"`
+}
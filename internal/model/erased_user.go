@@ -0,0 +1,14 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErasedUser is the tombstone left by the erasure of a user's data. It only
+// keeps the id, which can't be registered again.
+type ErasedUser struct {
+	ExternalId uuid.UUID `gorm:"column:external_id;type:uuid;primary_key"`
+	ErasedAt   time.Time `gorm:"column:erased_at;type:timestamp with time zone;not null"`
+}
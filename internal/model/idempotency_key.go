@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+const (
+	IDEMPOTENCY_PROCESSING = "processing"
+	IDEMPOTENCY_COMPLETED  = "completed"
+)
+
+// IdempotencyKey remembers the outcome of a request sent with an
+// Idempotency-Key header. Scope separates the same key sent by different
+// clients or to different routes. A processing record is only held until
+// LockedUntil, so a request that never completed, e.g. because the process
+// crashed, doesn't block the key until it expires.
+type IdempotencyKey struct {
+	Scope            string    `gorm:"primary_key"`
+	Key              string    `gorm:"primary_key"`
+	Fingerprint      string    `gorm:"not null"`
+	Status           string    `gorm:"not null"`
+	ResponseStatus   int       `gorm:"column:response_status"`
+	ResponseType     string    `gorm:"column:response_type"`
+	ResponseBody     []byte    `gorm:"column:response_body"`
+	ResponseLocation string    `gorm:"column:response_location"`
+	CreatedAt        time.Time `gorm:"column:created_at;type:timestamp with time zone;not null"`
+	LockedUntil      time.Time `gorm:"column:locked_until;type:timestamp with time zone;not null;default:now()"`
+	ExpiresAt        time.Time `gorm:"column:expires_at;type:timestamp with time zone;not null;index"`
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+type TOTPCredential struct {
+	Id           int        `gorm:"type:int;primary_key"`
+	UserId       int        `gorm:"column:user_id;unique;not null"`
+	Secret       string     `gorm:"type:text;not null;serializer:pii"`
+	LastUsedStep int64      `gorm:"column:last_used_step;not null;default:0"`
+	CreatedAt    time.Time  `gorm:"column:created_at;type:timestamp with time zone;not null"`
+	ConfirmedAt  *time.Time `gorm:"column:confirmed_at;type:timestamp with time zone"`
+}
+
+type RecoveryCode struct {
+	Id       int        `gorm:"type:int;primary_key"`
+	UserId   int        `gorm:"column:user_id;not null;index"`
+	CodeHash string     `gorm:"column:code_hash;not null"`
+	UsedAt   *time.Time `gorm:"column:used_at;type:timestamp with time zone"`
+}
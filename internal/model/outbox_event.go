@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutboxEvent is an event written in the same transaction as the change it
+// describes, and published later by the relay. Events of the same aggregate
+// are published in Id order.
+type OutboxEvent struct {
+	Id            int        `gorm:"primary_key"`
+	AggregateId   uuid.UUID  `gorm:"column:aggregate_id;type:uuid;not null;index"`
+	Type          string     `gorm:"not null"`
+	Payload       []byte     `gorm:"type:jsonb;not null"`
+	Attempts      int        `gorm:"not null;default:0"`
+	LastError     string     `gorm:"column:last_error"`
+	CreatedAt     time.Time  `gorm:"column:created_at;type:timestamp with time zone;not null"`
+	NextAttemptAt time.Time  `gorm:"column:next_attempt_at;type:timestamp with time zone;not null"`
+	PublishedAt   *time.Time `gorm:"column:published_at;type:timestamp with time zone;index"`
+	// TransactionId is the id of the transaction that wrote the event, set
+	// by the database; see database.addOutboxTransactionIds.
+	TransactionId uint64 `gorm:"column:transaction_id;->;-:migration"`
+}
@@ -0,0 +1,16 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PasswordReset struct {
+	Id        int        `gorm:"type:int;primary_key"`
+	UserId    int        `gorm:"column:user_id;not null;index"`
+	TokenId   uuid.UUID  `gorm:"column:token_id;type:uuid;unique;not null"`
+	ExpiresAt time.Time  `gorm:"column:expires_at;type:timestamp with time zone;not null"`
+	CreatedAt time.Time  `gorm:"column:created_at;type:timestamp with time zone;not null"`
+	UsedAt    *time.Time `gorm:"column:used_at;type:timestamp with time zone"`
+}
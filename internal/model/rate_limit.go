@@ -0,0 +1,9 @@
+package model
+
+import "time"
+
+type RateLimitBucket struct {
+	Key       string    `gorm:"primary_key"`
+	Tokens    float64   `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"column:updated_at;type:timestamp with time zone;not null;index"`
+}
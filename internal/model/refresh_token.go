@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshToken is one link of a rotation chain. Every token issued from the
+// same login shares the FamilyId, so reusing an already rotated token can
+// revoke the whole chain at once.
+type RefreshToken struct {
+	Id        int        `gorm:"type:int;primary_key"`
+	UserId    int        `gorm:"column:user_id;not null;index"`
+	FamilyId  uuid.UUID  `gorm:"column:family_id;type:uuid;not null;index"`
+	TokenHash string     `gorm:"column:token_hash;unique;not null"`
+	MFA       bool       `gorm:"column:mfa;not null;default:false"`
+	ExpiresAt time.Time  `gorm:"column:expires_at;type:timestamp with time zone;not null"`
+	CreatedAt time.Time  `gorm:"column:created_at;type:timestamp with time zone;not null"`
+	RotatedAt *time.Time `gorm:"column:rotated_at;type:timestamp with time zone"`
+	RevokedAt *time.Time `gorm:"column:revoked_at;type:timestamp with time zone"`
+}
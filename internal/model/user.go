@@ -3,13 +3,32 @@ package model
 import (
 	"time"
 
+	"github.com/LucasAndFlores/user_api/internal/civil"
 	"github.com/google/uuid"
 )
 
+const (
+	ROLE_USER  = "user"
+	ROLE_ADMIN = "admin"
+	// ROLE_SUPPORT reads the users, without their pii.
+	ROLE_SUPPORT = "support"
+)
+
+// User keeps Email and DateOfBirth encrypted at rest. Email is looked up
+// through EmailIndex, its blind index. GORM sets CreatedAt and UpdatedAt.
 type User struct {
-	Id          int       `gorm:"type:int;primary_key"`
-	Name        string    `gorm:"not null"`
-	Email       string    `gorm:"unique;not null"`
-	ExternalId  uuid.UUID `gorm:"column:external_id;type:uuid;unique;not null"`
-	DateOfBirth time.Time `gorm:"column:date_of_birth;type:timestamp with time zone"`
+	Id              int        `gorm:"type:int;primary_key"`
+	Name            string     `gorm:"not null"`
+	Email           string     `gorm:"type:text;not null;serializer:pii"`
+	EmailIndex      string     `gorm:"column:email_index;uniqueIndex;not null"`
+	ExternalId      uuid.UUID  `gorm:"column:external_id;type:uuid;unique;not null"`
+	DateOfBirth     civil.Date `gorm:"column:date_of_birth;type:text;serializer:pii"`
+	PasswordHash    string     `gorm:"column:password_hash"`
+	Role            string     `gorm:"not null;default:user"`
+	EmailVerifiedAt *time.Time `gorm:"column:email_verified_at;type:timestamp with time zone"`
+	// Version is incremented by every change, password resets and email
+	// verifications included, and backs the ETag of the user.
+	Version   int       `gorm:"not null;default:1"`
+	CreatedAt time.Time `gorm:"column:created_at;type:timestamp with time zone;not null"`
+	UpdatedAt time.Time `gorm:"column:updated_at;type:timestamp with time zone;not null;index"`
 }
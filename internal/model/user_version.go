@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/civil"
+	"github.com/google/uuid"
+)
+
+// UserVersion is the profile of a user as it was from RecordedAt until the
+// next version. Deleting a user records a last version marked as Deleted.
+type UserVersion struct {
+	Id              int        `gorm:"primary_key"`
+	UserExternalId  uuid.UUID  `gorm:"column:user_external_id;type:uuid;not null;uniqueIndex:idx_user_versions_user_version"`
+	Version         int        `gorm:"not null;uniqueIndex:idx_user_versions_user_version"`
+	Name            string     `gorm:"not null"`
+	Email           string     `gorm:"type:text;not null;serializer:pii"`
+	DateOfBirth     civil.Date `gorm:"column:date_of_birth;type:text;serializer:pii"`
+	Role            string     `gorm:"not null"`
+	EmailVerifiedAt *time.Time `gorm:"column:email_verified_at;type:timestamp with time zone"`
+	Deleted         bool       `gorm:"not null;default:false"`
+	RecordedAt      time.Time  `gorm:"column:recorded_at;type:timestamp with time zone;not null"`
+}
+
+// User is the user as of this version, updated when the version was
+// recorded. CreatedAt is left to the first version.
+func (v *UserVersion) User() *User {
+	return &User{
+		ExternalId:      v.UserExternalId,
+		Name:            v.Name,
+		Email:           v.Email,
+		DateOfBirth:     v.DateOfBirth,
+		Role:            v.Role,
+		EmailVerifiedAt: v.EmailVerifiedAt,
+		Version:         v.Version,
+		UpdatedAt:       v.RecordedAt,
+	}
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DELIVERY_PENDING   = "pending"
+	DELIVERY_SUCCEEDED = "succeeded"
+	DELIVERY_FAILED    = "failed"
+)
+
+// WebhookSubscription sends the events of EventTypes, a comma separated
+// list, to URL, signed with Secret, which is encrypted like the pii. It is
+// disabled after too many consecutive failed attempts.
+type WebhookSubscription struct {
+	Id                  int        `gorm:"type:int;primary_key"`
+	ExternalId          uuid.UUID  `gorm:"column:external_id;type:uuid;unique;not null"`
+	URL                 string     `gorm:"column:url;not null"`
+	EventTypes          string     `gorm:"column:event_types;not null"`
+	Secret              string     `gorm:"type:text;not null;serializer:pii"`
+	ConsecutiveFailures int        `gorm:"column:consecutive_failures;not null;default:0"`
+	CreatedAt           time.Time  `gorm:"column:created_at;type:timestamp with time zone;not null"`
+	DisabledAt          *time.Time `gorm:"column:disabled_at;type:timestamp with time zone"`
+}
+
+func (s *WebhookSubscription) Types() []string {
+	return strings.Split(s.EventTypes, ",")
+}
+
+func (s *WebhookSubscription) Subscribes(eventType string) bool {
+	for _, subscribed := range s.Types() {
+		if subscribed == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WebhookDelivery is one event sent to one subscription, and the log of its
+// attempts. Payload is the event as sent, except for its pii, which is
+// encrypted until the body is signed and sent.
+type WebhookDelivery struct {
+	Id             int       `gorm:"type:int;primary_key"`
+	ExternalId     uuid.UUID `gorm:"column:external_id;type:uuid;unique;not null"`
+	SubscriptionId int       `gorm:"column:subscription_id;not null;index"`
+	EventId        int       `gorm:"column:event_id;not null"`
+	EventType      string    `gorm:"column:event_type;not null"`
+	Payload        []byte    `gorm:"not null"`
+	Status         string    `gorm:"not null;index"`
+	Attempts       int       `gorm:"not null;default:0"`
+	ResponseStatus int       `gorm:"column:response_status"`
+	LastError      string    `gorm:"column:last_error"`
+	CreatedAt      time.Time `gorm:"column:created_at;type:timestamp with time zone;not null"`
+	NextAttemptAt  time.Time `gorm:"column:next_attempt_at;type:timestamp with time zone;not null"`
+	// LastAttemptAt is nil until the first attempt.
+	LastAttemptAt *time.Time `gorm:"column:last_attempt_at;type:timestamp with time zone"`
+}
@@ -0,0 +1,19 @@
+package openapi
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// DocsPage renders the document served at /openapi.json with Swagger UI.
+//
+//go:embed docs.html
+var DocsPage []byte
+
+//go:embed swagger-ui
+var swaggerUI embed.FS
+
+// DocsAssets are the scripts and styles of Swagger UI, vendored by `make
+// swagger-ui` and served with the binary, so the docs page doesn't depend on
+// a CDN.
+var DocsAssets, _ = fs.Sub(swaggerUI, "swagger-ui")
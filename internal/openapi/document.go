@@ -0,0 +1,147 @@
+package openapi
+
+import (
+	"regexp"
+	"strings"
+)
+
+const VERSION = "3.1.0"
+
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// PathItem maps a lowercase HTTP method to its operation.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationId string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+func New(title, version string) *Document {
+	return &Document{
+		OpenAPI: VERSION,
+		Info:    Info{Title: title, Version: version},
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			Schemas:         map[string]*Schema{},
+			SecuritySchemes: map[string]SecurityScheme{},
+		},
+	}
+}
+
+// Component stores the schema generated from v under name and returns a
+// reference to it.
+func (d *Document) Component(name string, v interface{}) *Schema {
+	d.Components.Schemas[name] = SchemaOf(v)
+
+	return Ref(name)
+}
+
+var fiberParam = regexp.MustCompile(`:(\w+)`)
+
+// Add documents the operation of a route written the Fiber way, e.g.
+// "/users/:id". Path parameters missing from the operation are added as
+// required strings.
+func (d *Document) Add(method, path string, op *Operation) {
+	for _, match := range fiberParam.FindAllStringSubmatch(path, -1) {
+		if !hasParameter(op, match[1]) {
+			op.Parameters = append(op.Parameters, Parameter{Name: match[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+
+	path = Path(path)
+
+	if d.Paths[path] == nil {
+		d.Paths[path] = PathItem{}
+	}
+
+	d.Paths[path][strings.ToLower(method)] = op
+}
+
+// Path converts a Fiber route path to the OpenAPI template syntax.
+func Path(fiberPath string) string {
+	return fiberParam.ReplaceAllString(fiberPath, "{$1}")
+}
+
+func hasParameter(op *Operation, name string) bool {
+	for _, parameter := range op.Parameters {
+		if parameter.In == "path" && parameter.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func JSON(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: schema}}
+}
+
+func Body(schema *Schema) *RequestBody {
+	return &RequestBody{Required: true, Content: JSON(schema)}
+}
+
+func Reply(description string, schema *Schema) Response {
+	response := Response{Description: description}
+
+	if schema != nil {
+		response.Content = JSON(schema)
+	}
+
+	return response
+}
@@ -0,0 +1,179 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Schema struct {
+	Ref         string             `json:"$ref,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	OneOf       []*Schema          `json:"oneOf,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	MinLength   *int               `json:"minLength,omitempty"`
+	MaxLength   *int               `json:"maxLength,omitempty"`
+	MinItems    *int               `json:"minItems,omitempty"`
+	MaxItems    *int               `json:"maxItems,omitempty"`
+	Pattern     string             `json:"pattern,omitempty"`
+	ReadOnly    bool               `json:"readOnly,omitempty"`
+}
+
+func Ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// validations translate validator tags to schema keywords. Tags without an
+// entry, like required, are handled by SchemaOf or have no equivalent.
+var validations = map[string]func(*Schema, string){
+	"email":    func(s *Schema, _ string) { s.Format = "email" },
+	"uuid":     func(s *Schema, _ string) { s.Format = "uuid" },
+	"http_url": func(s *Schema, _ string) { s.Format = "uri" },
+	"oneof":    func(s *Schema, param string) { s.Enum = strings.Fields(param) },
+	"min": func(s *Schema, param string) {
+		if s.Type == "array" {
+			s.MinItems = atoi(param)
+		} else {
+			s.MinLength = atoi(param)
+		}
+	},
+	"max": func(s *Schema, param string) {
+		if s.Type == "array" {
+			s.MaxItems = atoi(param)
+		} else {
+			s.MaxLength = atoi(param)
+		}
+	},
+	"len": func(s *Schema, param string) {
+		s.MinLength = atoi(param)
+		s.MaxLength = atoi(param)
+	},
+	"numeric": func(s *Schema, _ string) { s.Pattern = "^[0-9]+$" },
+	"datetime": func(s *Schema, param string) {
+		if param == time.RFC3339 {
+			s.Format = "date-time"
+		}
+	},
+}
+
+// RegisterValidation documents a custom validator tag, the same way it is
+// registered on the validator.
+func RegisterValidation(tag string, fn func(s *Schema, param string)) {
+	validations[tag] = fn
+}
+
+// SchemaOf generates the schema of v from its json and validate tags. Fields
+// tagged `openapi:"readonly"` are only sent by the server.
+func SchemaOf(v interface{}) *Schema {
+	return schemaOf(reflect.TypeOf(v))
+}
+
+func schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == reflect.TypeOf(time.Time{}) {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: schemaOf(t.Elem())}
+	case reflect.Struct:
+		return structSchema(t)
+	}
+
+	return &Schema{}
+}
+
+func structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		property := schemaOf(field.Type)
+
+		property.ReadOnly = field.Tag.Get("openapi") == "readonly"
+
+		// the rules after dive apply to the items of a list
+		target := property
+
+		for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+			tag, param, _ := strings.Cut(rule, "=")
+
+			if tag == "required" && target == property {
+				schema.Required = append(schema.Required, name)
+				continue
+			}
+
+			if tag == "dive" && target.Items != nil {
+				target = target.Items
+				continue
+			}
+
+			if fn, ok := validations[tag]; ok {
+				fn(target, param)
+			}
+		}
+
+		// the validator's required also rejects empty strings
+		if property.Type == "string" && property.MinLength == nil && contains(schema.Required, name) {
+			property.MinLength = atoi("1")
+		}
+
+		schema.Properties[name] = property
+	}
+
+	return schema
+}
+
+func atoi(value string) *int {
+	n, err := strconv.Atoi(value)
+
+	if err != nil {
+		return nil
+	}
+
+	return &n
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,241 @@
+package openapi
+
+import (
+	"math"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Violation is a value that doesn't satisfy a keyword of its schema. Field
+// is the dotted path of the value, Param the expected value of the keyword.
+type Violation struct {
+	Field   string
+	Keyword string
+	Param   string
+}
+
+// Match finds the operation documented for a request path, e.g.
+// "/api/v1/users/4e6e0b08-f1a7-4ff3-85d3-f93fabc8ad5d". Like the router,
+// it prefers literal segments, so "/api/v1/users/events" doesn't match
+// "/api/v1/users/{id}" when both are documented.
+func (d *Document) Match(method, path string) *Operation {
+	segments := strings.Split(strings.TrimSuffix(path, "/"), "/")
+
+	var match *Operation
+
+	fewest := len(segments) + 1
+
+	for template, item := range d.Paths {
+		operation, ok := item[strings.ToLower(method)]
+
+		if !ok {
+			continue
+		}
+
+		parameters, matched := matchTemplate(strings.Split(template, "/"), segments)
+
+		if matched && parameters < fewest {
+			match = operation
+			fewest = parameters
+		}
+	}
+
+	return match
+}
+
+// matchTemplate reports whether the segments match the template, and how
+// many of them are parameters.
+func matchTemplate(template, segments []string) (int, bool) {
+	if len(template) != len(segments) {
+		return 0, false
+	}
+
+	parameters := 0
+
+	for i, segment := range template {
+		isParameter := strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+
+		if isParameter && segments[i] == "" || !isParameter && segment != segments[i] {
+			return 0, false
+		}
+
+		if isParameter {
+			parameters++
+		}
+	}
+
+	return parameters, true
+}
+
+// Validate checks a value decoded from JSON against the schema, resolving
+// references to the components of the document.
+func (d *Document) Validate(schema *Schema, value interface{}) []Violation {
+	var violations []Violation
+
+	d.validate(schema, value, "", &violations)
+
+	return violations
+}
+
+func (d *Document) validate(s *Schema, value interface{}, field string, violations *[]Violation) {
+	if s.Ref != "" {
+		s = d.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+
+		if s == nil {
+			return
+		}
+	}
+
+	if len(s.OneOf) != 0 {
+		matches := 0
+
+		for _, option := range s.OneOf {
+			if len(d.Validate(option, value)) == 0 {
+				matches++
+			}
+		}
+
+		if matches != 1 {
+			*violations = append(*violations, Violation{Field: field, Keyword: "oneOf"})
+		}
+
+		return
+	}
+
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+
+		if !ok {
+			*violations = append(*violations, Violation{Field: field, Keyword: "type", Param: s.Type})
+			return
+		}
+
+		for _, name := range s.Required {
+			if object[name] == nil {
+				*violations = append(*violations, Violation{Field: join(field, name), Keyword: "required"})
+			}
+		}
+
+		names := make([]string, 0, len(s.Properties))
+
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		for _, name := range names {
+			if object[name] != nil {
+				d.validate(s.Properties[name], object[name], join(field, name), violations)
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+
+		if !ok {
+			*violations = append(*violations, Violation{Field: field, Keyword: "type", Param: s.Type})
+			return
+		}
+
+		if s.MinItems != nil && len(items) < *s.MinItems {
+			*violations = append(*violations, Violation{Field: field, Keyword: "minItems", Param: strconv.Itoa(*s.MinItems)})
+		}
+
+		if s.MaxItems != nil && len(items) > *s.MaxItems {
+			*violations = append(*violations, Violation{Field: field, Keyword: "maxItems", Param: strconv.Itoa(*s.MaxItems)})
+		}
+
+		for i, item := range items {
+			if s.Items != nil {
+				d.validate(s.Items, item, join(field, strconv.Itoa(i)), violations)
+			}
+		}
+	case "string":
+		text, ok := value.(string)
+
+		if !ok {
+			*violations = append(*violations, Violation{Field: field, Keyword: "type", Param: s.Type})
+			return
+		}
+
+		validateString(s, text, field, violations)
+	case "integer", "number":
+		number, ok := value.(float64)
+
+		if !ok || s.Type == "integer" && number != math.Trunc(number) {
+			*violations = append(*violations, Violation{Field: field, Keyword: "type", Param: s.Type})
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			*violations = append(*violations, Violation{Field: field, Keyword: "type", Param: s.Type})
+		}
+	}
+}
+
+func validateString(s *Schema, text string, field string, violations *[]Violation) {
+	length := utf8.RuneCountInString(text)
+
+	if s.MinLength != nil && length < *s.MinLength {
+		*violations = append(*violations, Violation{Field: field, Keyword: "minLength", Param: strconv.Itoa(*s.MinLength)})
+	}
+
+	if s.MaxLength != nil && length > *s.MaxLength {
+		*violations = append(*violations, Violation{Field: field, Keyword: "maxLength", Param: strconv.Itoa(*s.MaxLength)})
+	}
+
+	if s.Pattern != "" {
+		matched, err := regexp.MatchString(s.Pattern, text)
+
+		if err != nil || !matched {
+			*violations = append(*violations, Violation{Field: field, Keyword: "pattern", Param: s.Pattern})
+		}
+	}
+
+	if len(s.Enum) != 0 && !contains(s.Enum, text) {
+		*violations = append(*violations, Violation{Field: field, Keyword: "enum", Param: strings.Join(s.Enum, " ")})
+	}
+
+	if s.Format != "" && !validFormat(s.Format, text) {
+		*violations = append(*violations, Violation{Field: field, Keyword: "format", Param: s.Format})
+	}
+}
+
+func validFormat(format, text string) bool {
+	var err error
+
+	switch format {
+	case "email":
+		_, err = mail.ParseAddress(text)
+	case "uuid":
+		_, err = uuid.Parse(text)
+	case "date-time":
+		_, err = time.Parse(time.RFC3339, text)
+	case "uri":
+		var parsed *url.URL
+
+		parsed, err = url.ParseRequestURI(text)
+
+		if err == nil && parsed.Host == "" {
+			return false
+		}
+	}
+
+	return err == nil
+}
+
+func join(field, name string) string {
+	if field == "" {
+		return name
+	}
+
+	return field + "." + name
+}
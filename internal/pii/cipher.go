@@ -0,0 +1,150 @@
+package pii
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+const (
+	// FORMAT_VERSION prefixes the encrypted values, which read
+	// "v1:<key id>:<wrapped data key>:<ciphertext>".
+	FORMAT_VERSION = "v1"
+	SEPARATOR      = ":"
+)
+
+var ErrMalformedValue = errors.New("malformed encrypted value")
+
+// Cipher encrypts the values with envelope encryption and computes their
+// blind indexes.
+type Cipher struct {
+	keys KeyProvider
+}
+
+func NewCipher(keys KeyProvider) *Cipher {
+	return &Cipher{keys: keys}
+}
+
+func (c *Cipher) CurrentKeyId() string {
+	return c.keys.CurrentKeyId()
+}
+
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	dataKey := make([]byte, KEY_SIZE)
+
+	_, err := rand.Read(dataKey)
+
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newAEAD(dataKey)
+
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := seal(aead, []byte(plaintext))
+
+	if err != nil {
+		return "", err
+	}
+
+	keyId := c.keys.CurrentKeyId()
+
+	wrapped, err := c.keys.WrapKey(keyId, dataKey)
+
+	if err != nil {
+		return "", err
+	}
+
+	return KeyPrefix(keyId) + base64.RawStdEncoding.EncodeToString(wrapped) + SEPARATOR + base64.RawStdEncoding.EncodeToString(ciphertext), nil
+}
+
+// Decrypt reads a value written by Encrypt. Values without the format
+// prefix were written before the encryption was enabled, and are returned
+// as is.
+func (c *Cipher) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	parts := strings.Split(value, SEPARATOR)
+
+	if len(parts) != 4 {
+		return "", ErrMalformedValue
+	}
+
+	wrapped, err := base64.RawStdEncoding.DecodeString(parts[2])
+
+	if err != nil {
+		return "", ErrMalformedValue
+	}
+
+	ciphertext, err := base64.RawStdEncoding.DecodeString(parts[3])
+
+	if err != nil {
+		return "", ErrMalformedValue
+	}
+
+	dataKey, err := c.keys.UnwrapKey(parts[1], wrapped)
+
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newAEAD(dataKey)
+
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := open(aead, ciphertext)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// BlindIndex is a keyed hash of the value, stored next to the encrypted
+// value so it can be looked up without decrypting every row. Emails are
+// compared regardless of case.
+func (c *Cipher) BlindIndex(value string) string {
+	mac := hmac.New(sha256.New, c.keys.IndexKey())
+
+	mac.Write([]byte(strings.ToLower(value)))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Fingerprint is a keyed hash of the parts, for comparing payloads that may
+// hold secrets, such as passwords, without storing a hash anyone could
+// brute force. Unlike BlindIndex, it is case-sensitive.
+func (c *Cipher) Fingerprint(parts ...[]byte) string {
+	mac := hmac.New(sha256.New, c.keys.IndexKey())
+
+	mac.Write([]byte("fingerprint"))
+
+	for _, part := range parts {
+		mac.Write([]byte{0})
+		mac.Write(part)
+	}
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// KeyPrefix starts the values encrypted with the given key, so the values
+// to re-encrypt are found with a LIKE.
+func KeyPrefix(keyId string) string {
+	return FORMAT_VERSION + SEPARATOR + keyId + SEPARATOR
+}
+
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, FORMAT_VERSION+SEPARATOR)
+}
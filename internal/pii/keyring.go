@@ -0,0 +1,218 @@
+package pii
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const KEY_SIZE = 32
+
+var ErrUnknownKey = errors.New("unknown encryption key")
+
+// KeyProvider holds the key encryption keys. Each value is encrypted with a
+// data key of its own, which is wrapped by the current key, so a provider
+// backed by a KMS never has to see the data.
+type KeyProvider interface {
+	// CurrentKeyId names the key wrapping the data keys of new values.
+	CurrentKeyId() string
+	WrapKey(keyId string, dataKey []byte) ([]byte, error)
+	UnwrapKey(keyId string, wrapped []byte) ([]byte, error)
+	// IndexKey signs the blind indexes. It is not rotated with the other
+	// keys, as changing it would require recomputing every index.
+	IndexKey() []byte
+}
+
+// Keyring is the content of a keyring file. Keys are base64 encoded, and
+// the retired ones are kept to read the values not re-encrypted yet.
+type Keyring struct {
+	CurrentKeyId string            `json:"current_key_id"`
+	Keys         map[string]string `json:"keys"`
+	IndexKey     string            `json:"index_key"`
+}
+
+// FileKeyring is a KeyProvider reading its keys from a local file.
+type FileKeyring struct {
+	current  string
+	keys     map[string]cipher.AEAD
+	indexKey []byte
+}
+
+func NewFileKeyring(path string) (KeyProvider, error) {
+	content, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var keyring Keyring
+
+	err = json.Unmarshal(content, &keyring)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid keyring file: %w", err)
+	}
+
+	return newFileKeyring(keyring)
+}
+
+func newFileKeyring(keyring Keyring) (*FileKeyring, error) {
+	indexKey, err := decodeKey(keyring.IndexKey)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid index key: %w", err)
+	}
+
+	f := &FileKeyring{current: keyring.CurrentKeyId, keys: map[string]cipher.AEAD{}, indexKey: indexKey}
+
+	for id, encoded := range keyring.Keys {
+		if id == "" || strings.Contains(id, SEPARATOR) {
+			return nil, fmt.Errorf("invalid key id %q", id)
+		}
+
+		key, err := decodeKey(encoded)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", id, err)
+		}
+
+		f.keys[id], err = newAEAD(key)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := f.keys[f.current]; !ok {
+		return nil, fmt.Errorf("the current key %q is missing from the keyring", f.current)
+	}
+
+	return f, nil
+}
+
+func (f *FileKeyring) CurrentKeyId() string {
+	return f.current
+}
+
+func (f *FileKeyring) WrapKey(keyId string, dataKey []byte) ([]byte, error) {
+	aead, ok := f.keys[keyId]
+
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	return seal(aead, dataKey)
+}
+
+func (f *FileKeyring) UnwrapKey(keyId string, wrapped []byte) ([]byte, error) {
+	aead, ok := f.keys[keyId]
+
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	return open(aead, wrapped)
+}
+
+func (f *FileKeyring) IndexKey() []byte {
+	return f.indexKey
+}
+
+// GenerateKeyring creates a keyring with a single key.
+func GenerateKeyring() (Keyring, error) {
+	indexKey, err := randomKey()
+
+	if err != nil {
+		return Keyring{}, err
+	}
+
+	keyring := Keyring{Keys: map[string]string{}, IndexKey: indexKey}
+
+	return keyring, keyring.Rotate()
+}
+
+// Rotate adds a new key and makes it the current one. The values encrypted
+// with the previous keys are re-encrypted in the background.
+func (k *Keyring) Rotate() error {
+	key, err := randomKey()
+
+	if err != nil {
+		return err
+	}
+
+	id := time.Now().UTC().Format("20060102T150405")
+
+	for suffix := 2; k.Keys[id] != ""; suffix++ {
+		id = fmt.Sprintf("%s-%d", time.Now().UTC().Format("20060102T150405"), suffix)
+	}
+
+	k.Keys[id] = key
+	k.CurrentKeyId = id
+
+	return nil
+}
+
+func randomKey() (string, error) {
+	key := make([]byte, KEY_SIZE)
+
+	_, err := rand.Read(key)
+
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(key) != KEY_SIZE {
+		return nil, fmt.Errorf("the key must be %d bytes long", KEY_SIZE)
+	}
+
+	return key, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts the plaintext with a random nonce, which prefixes the
+// result.
+func seal(aead cipher.AEAD, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+
+	_, err := rand.Read(nonce)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(aead cipher.AEAD, sealed []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrMalformedValue
+	}
+
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+}
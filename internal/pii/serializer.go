@@ -0,0 +1,155 @@
+package pii
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"gorm.io/gorm/schema"
+)
+
+// SERIALIZER is the name of the GORM serializer encrypting the columns
+// tagged with `serializer:pii`.
+const SERIALIZER = "pii"
+
+var textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+var (
+	mu      sync.RWMutex
+	current *Cipher
+)
+
+// Use makes c the cipher of the pii columns and of the blind indexes. It
+// must be called before the models are first used.
+func Use(c *Cipher) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	current = c
+
+	schema.RegisterSerializer(SERIALIZER, Serializer{cipher: c})
+}
+
+// BlindIndex computes the blind index of the value with the cipher in use.
+func BlindIndex(value string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return current.BlindIndex(value)
+}
+
+// Fingerprint computes the fingerprint of the parts with the cipher in use.
+func Fingerprint(parts ...[]byte) string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return current.Fingerprint(parts...)
+}
+
+// Encrypt encrypts the value with the cipher in use, for the pii stored
+// outside of the pii columns, e.g. inside JSON documents.
+func Encrypt(value string) (string, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return current.Encrypt(value)
+}
+
+// Decrypt reads a value written by Encrypt, or returns a plaintext value
+// as is.
+func Decrypt(value string) (string, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return current.Decrypt(value)
+}
+
+// CurrentKeyId is the id of the key encrypting the new values.
+func CurrentKeyId() string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return current.CurrentKeyId()
+}
+
+// Serializer encrypts string and time fields, and the fields written as
+// text, like civil.Date. Times are stored in UTC with a nanosecond
+// precision, and read in the local time zone, like the driver reads the
+// timestamp columns.
+type Serializer struct {
+	cipher *Cipher
+}
+
+func (s Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	if dbValue == nil {
+		return nil
+	}
+
+	var value string
+
+	switch v := dbValue.(type) {
+	case []byte:
+		value = string(v)
+	case string:
+		value = v
+	default:
+		return fmt.Errorf("failed to decrypt the value of %s: unsupported type %T", field.Name, dbValue)
+	}
+
+	plaintext, err := s.cipher.Decrypt(value)
+
+	if err != nil {
+		return fmt.Errorf("failed to decrypt the value of %s: %w", field.Name, err)
+	}
+
+	switch {
+	case field.FieldType == reflect.TypeOf(time.Time{}):
+		t, err := time.Parse(time.RFC3339Nano, plaintext)
+
+		if err != nil {
+			return fmt.Errorf("failed to decrypt the value of %s: %w", field.Name, err)
+		}
+
+		field.ReflectValueOf(ctx, dst).Set(reflect.ValueOf(t.Local()))
+	case reflect.PointerTo(field.FieldType).Implements(textUnmarshaler):
+		value := reflect.New(field.FieldType)
+
+		err = value.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(plaintext))
+
+		if err != nil {
+			return fmt.Errorf("failed to decrypt the value of %s: %w", field.Name, err)
+		}
+
+		field.ReflectValueOf(ctx, dst).Set(value.Elem())
+	default:
+		field.ReflectValueOf(ctx, dst).SetString(plaintext)
+	}
+
+	return nil
+}
+
+func (s Serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	var plaintext string
+
+	switch v := fieldValue.(type) {
+	case string:
+		plaintext = v
+	case time.Time:
+		plaintext = v.UTC().Format(time.RFC3339Nano)
+	case encoding.TextMarshaler:
+		text, err := v.MarshalText()
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt the value of %s: %w", field.Name, err)
+		}
+
+		plaintext = string(text)
+	default:
+		return nil, fmt.Errorf("failed to encrypt the value of %s: unsupported type %T", field.Name, fieldValue)
+	}
+
+	return s.cipher.Encrypt(plaintext)
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit describes a token bucket: it holds up to Burst tokens and refills
+// Requests tokens every Period.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until the next token is available.
+	RetryAfter time.Duration
+	// ResetAfter is how long until the bucket is full again.
+	ResetAfter time.Duration
+}
+
+// Store keeps the buckets. Take must be atomic per key, so concurrent
+// requests can't spend the same token twice.
+type Store interface {
+	Take(context.Context, string, Limit) (Result, error)
+}
+
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+func (l Limit) burst() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+
+	return float64(l.Requests)
+}
+
+// take refills the bucket for the time elapsed since updatedAt and tries to
+// spend one token, returning the new token count.
+func take(tokens float64, updatedAt time.Time, now time.Time, limit Limit) (float64, Result) {
+	burst := limit.burst()
+	rate := limit.rate()
+
+	tokens = math.Min(burst, tokens+now.Sub(updatedAt).Seconds()*rate)
+
+	result := Result{Limit: int(burst)}
+
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - tokens) / rate)
+	}
+
+	result.Remaining = int(math.Floor(tokens))
+	result.ResetAfter = seconds((burst - tokens) / rate)
+
+	return tokens, result
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	full      time.Time
+}
+
+// MemoryStore keeps the buckets in the process. Limits are not shared
+// between replicas; use PostgresStore for that.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	takes   int
+}
+
+func NewMemoryStore() Store {
+	return &MemoryStore{buckets: map[string]*bucket{}}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	s.takes++
+
+	if s.takes%1000 == 0 {
+		s.evictFull(now)
+	}
+
+	b, ok := s.buckets[key]
+
+	if !ok {
+		b = &bucket{tokens: limit.burst(), updatedAt: now}
+		s.buckets[key] = b
+	}
+
+	tokens, result := take(b.tokens, b.updatedAt, now, limit)
+
+	b.tokens = tokens
+	b.updatedAt = now
+	b.full = now.Add(result.ResetAfter)
+
+	return result, nil
+}
+
+// evictFull drops the buckets that refilled completely, since a missing
+// bucket behaves exactly like a full one.
+func (s *MemoryStore) evictFull(now time.Time) {
+	for key, b := range s.buckets {
+		if now.After(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const STALE_BUCKET_AGE = 24 * time.Hour
+
+// PostgresStore keeps the buckets in the rate_limit_buckets table, so every
+// replica of the API shares the same limits.
+type PostgresStore struct {
+	db    *gorm.DB
+	takes int64
+}
+
+func NewPostgresStore(d *gorm.DB) Store {
+	return &PostgresStore{db: d}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	var result Result
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.RateLimitBucket{Key: key, Tokens: limit.burst(), UpdatedAt: now}).Error
+
+		if err != nil {
+			return err
+		}
+
+		var b model.RateLimitBucket
+
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Take(&b, "key = ?", key).Error
+
+		if err != nil {
+			return err
+		}
+
+		var tokens float64
+
+		tokens, result = take(b.Tokens, b.UpdatedAt, now, limit)
+
+		return tx.Model(&model.RateLimitBucket{}).Where("key = ?", key).
+			Updates(map[string]interface{}{"tokens": tokens, "updated_at": now}).Error
+	})
+
+	if err != nil {
+		return Result{}, err
+	}
+
+	if atomic.AddInt64(&s.takes, 1)%1000 == 0 {
+		s.prune(ctx)
+	}
+
+	return result, nil
+}
+
+// prune deletes the buckets nobody used for a while; a missing bucket
+// behaves like a full one.
+func (s *PostgresStore) prune(ctx context.Context) error {
+	return s.db.WithContext(ctx).Where("updated_at < ?", time.Now().Add(-STALE_BUCKET_AGE)).Delete(&model.RateLimitBucket{}).Error
+}
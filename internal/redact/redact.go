@@ -0,0 +1,236 @@
+package redact
+
+import (
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/LucasAndFlores/user_api/internal/auth"
+)
+
+const MASK = "***"
+
+// Rule masks the values of a field for the callers without its scope.
+type Rule struct {
+	Scope string
+	Mask  func(string) string
+}
+
+// POLICY holds the rules of the fields with pii, by name. Struct fields opt
+// in with a `redact:"<name>"` tag, while the keys of maps, like event
+// payloads, and the fields of audit changes match the names themselves.
+var POLICY = map[string]Rule{
+	"email":         {Scope: auth.SCOPE_READ_EMAIL, Mask: Email},
+	"date_of_birth": {Scope: auth.SCOPE_READ_DATE_OF_BIRTH, Mask: Year},
+}
+
+// Redactor is implemented by the values that can't be redacted from their
+// tags, e.g. because their field names are data. Redact returns a copy of
+// the same type.
+type Redactor interface {
+	Redact(Viewer) interface{}
+}
+
+// Viewer is who the values are redacted for.
+type Viewer struct {
+	all    bool
+	scopes []string
+}
+
+var (
+	// EVERYONE sees the masked values only, e.g. in the logs.
+	EVERYONE = Viewer{}
+	// OWNER sees every value, e.g. the user the values are about.
+	OWNER = Viewer{all: true}
+)
+
+func WithScopes(scopes []string) Viewer {
+	return Viewer{scopes: scopes}
+}
+
+// ViewerOf redacts the values about the owner for the principal, who may be
+// nil for anonymous callers. Users see all their own values.
+func ViewerOf(principal *auth.Principal, owner string) Viewer {
+	if principal == nil {
+		return EVERYONE
+	}
+
+	if strings.EqualFold(principal.ExternalId.String(), owner) {
+		return OWNER
+	}
+
+	return WithScopes(principal.Scopes)
+}
+
+func (v Viewer) Reveals(scope string) bool {
+	if v.all {
+		return true
+	}
+
+	for _, granted := range v.scopes {
+		if granted == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Value masks the value of the named field, unless the viewer has the scope
+// of its rule. Values other than strings are returned as is.
+func Value(viewer Viewer, field string, value interface{}) interface{} {
+	text, ok := value.(string)
+
+	if !ok {
+		return value
+	}
+
+	return String(viewer, field, text)
+}
+
+func String(viewer Viewer, field string, value string) string {
+	rule, ok := POLICY[field]
+
+	if !ok || viewer.Reveals(rule.Scope) {
+		return value
+	}
+
+	return rule.Mask(value)
+}
+
+// Apply returns a copy of value with the pii the viewer can't see masked.
+// It follows pointers, slices, maps and struct fields.
+func Apply(viewer Viewer, value interface{}) interface{} {
+	if viewer.all || value == nil {
+		return value
+	}
+
+	return apply(viewer, reflect.ValueOf(value)).Interface()
+}
+
+func apply(viewer Viewer, v reflect.Value) reflect.Value {
+	if redactor, ok := redactorOf(v); ok {
+		return reflect.ValueOf(redactor.Redact(viewer))
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+
+		out := reflect.New(v.Type().Elem())
+
+		out.Elem().Set(apply(viewer, v.Elem()))
+
+		return out
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+
+		out := reflect.New(v.Type()).Elem()
+
+		out.Set(apply(viewer, v.Elem()))
+
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(apply(viewer, v.Index(i)))
+		}
+
+		return out
+	case reflect.Map:
+		if v.IsNil() || v.Type().Key().Kind() != reflect.String {
+			return v
+		}
+
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+
+		iter := v.MapRange()
+
+		for iter.Next() {
+			value := apply(viewer, iter.Value())
+
+			if _, ok := POLICY[iter.Key().String()]; ok {
+				value = reflect.ValueOf(Value(viewer, iter.Key().String(), value.Interface()))
+			}
+
+			if !value.IsValid() {
+				value = reflect.Zero(v.Type().Elem())
+			}
+
+			out.SetMapIndex(iter.Key(), value.Convert(v.Type().Elem()))
+		}
+
+		return out
+	case reflect.Struct:
+		out := reflect.New(v.Type()).Elem()
+
+		out.Set(v)
+
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+
+			if !field.IsExported() {
+				continue
+			}
+
+			if name := field.Tag.Get("redact"); name != "" && field.Type.Kind() == reflect.String {
+				out.Field(i).SetString(String(viewer, name, v.Field(i).String()))
+				continue
+			}
+
+			out.Field(i).Set(apply(viewer, v.Field(i)))
+		}
+
+		return out
+	default:
+		return v
+	}
+}
+
+// redactorOf doesn't look behind pointers, as Redact returns a value.
+func redactorOf(v reflect.Value) (Redactor, bool) {
+	if !v.IsValid() || !v.CanInterface() || v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface && v.IsNil() {
+		return nil, false
+	}
+
+	redactor, ok := v.Interface().(Redactor)
+
+	return redactor, ok
+}
+
+// Email keeps the first character and the domain: j***@example.com.
+func Email(value string) string {
+	at := strings.LastIndex(value, "@")
+
+	if at < 0 {
+		return MASK
+	}
+
+	first, _ := utf8.DecodeRuneInString(value[:at])
+
+	if first == utf8.RuneError {
+		return MASK + value[at:]
+	}
+
+	return string(first) + MASK + value[at:]
+}
+
+// Year keeps the year of a date written like 1990-01-01 or in RFC 3339.
+func Year(value string) string {
+	year, _, found := strings.Cut(value, "-")
+
+	if !found || len(year) != 4 || strings.Trim(year, "0123456789") != "" {
+		return MASK
+	}
+
+	return year
+}
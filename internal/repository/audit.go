@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AuditRecordRepository struct {
+	db *gorm.DB
+}
+
+// AUDIT_ERASURE_SETTING lets a transaction rewrite the changes of the audit
+// records, which the database refuses otherwise.
+const AUDIT_ERASURE_SETTING = "user_api.audit_erasure"
+
+// AuditRepository only appends records: the table is append-only, except
+// for the erasure of a user's data.
+type AuditRepository interface {
+	Append(context.Context, *model.AuditRecord) error
+	FindByUserExternalId(ctx context.Context, externalId uuid.UUID, afterId int, limit int) ([]model.AuditRecord, error)
+	Redact(ctx context.Context, externalId uuid.UUID, value string) error
+}
+
+func NewAuditRepository(d *gorm.DB) AuditRepository {
+	return &AuditRecordRepository{
+		db: d,
+	}
+}
+
+// Append joins the transaction of the context, so the record is only stored
+// along with the change it describes.
+func (r *AuditRecordRepository) Append(ctx context.Context, record *model.AuditRecord) error {
+	return conn(ctx, r.db).Create(record).Error
+}
+
+// FindByUserExternalId returns the records of a user in the order they were
+// made, after the record afterId, all of them when limit is -1.
+func (r *AuditRecordRepository) FindByUserExternalId(ctx context.Context, externalId uuid.UUID, afterId int, limit int) ([]model.AuditRecord, error) {
+	var records []model.AuditRecord
+
+	err := r.db.WithContext(ctx).
+		Where("user_external_id = ? AND id > ?", externalId, afterId).
+		Order("id").
+		Limit(limit).
+		Find(&records).Error
+
+	return records, err
+}
+
+// Redact replaces every value of the changes of a user's records, except
+// the missing ones, with value. The records themselves are kept. It must run
+// in the transaction of the erasure, which it allows to update the records.
+func (r *AuditRecordRepository) Redact(ctx context.Context, externalId uuid.UUID, value string) error {
+	db := conn(ctx, r.db)
+
+	err := db.Exec("SELECT set_config(?, 'on', true)", AUDIT_ERASURE_SETTING).Error
+
+	if err != nil {
+		return err
+	}
+
+	return db.Exec(`UPDATE audit_records SET changes = (
+	SELECT COALESCE(jsonb_agg(jsonb_build_object(
+		'field', change->'field',
+		'before', CASE WHEN change->'before' = 'null'::jsonb THEN change->'before' ELSE to_jsonb(?::text) END,
+		'after', CASE WHEN change->'after' = 'null'::jsonb THEN change->'after' ELSE to_jsonb(?::text) END
+	) ORDER BY position), '[]'::jsonb)
+	FROM jsonb_array_elements(changes) WITH ORDINALITY AS c(change, position)
+) WHERE user_external_id = ?`, value, value, externalId).Error
+}
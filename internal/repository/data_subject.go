@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"gorm.io/gorm"
+)
+
+type DataSubjectRepository struct {
+	db *gorm.DB
+}
+
+// RelatedData is what is stored about a user besides its row, and deleted
+// along with it.
+type RelatedData struct {
+	RefreshTokens      []model.RefreshToken
+	EmailVerifications []model.EmailVerification
+	PasswordResets     []model.PasswordReset
+	// TOTPCredential has a zero Id when the user never enrolled.
+	TOTPCredential model.TOTPCredential
+	RecoveryCodes  []model.RecoveryCode
+}
+
+type SubjectRepository interface {
+	FindRelated(ctx context.Context, userId int) (*RelatedData, error)
+	InsertTombstone(context.Context, *model.ErasedUser) error
+}
+
+func NewDataSubjectRepository(d *gorm.DB) SubjectRepository {
+	return &DataSubjectRepository{
+		db: d,
+	}
+}
+
+func (r *DataSubjectRepository) FindRelated(ctx context.Context, userId int) (*RelatedData, error) {
+	var related RelatedData
+
+	db := r.db.WithContext(ctx)
+
+	for _, rows := range []interface{}{
+		&related.RefreshTokens,
+		&related.EmailVerifications,
+		&related.PasswordResets,
+		&related.RecoveryCodes,
+	} {
+		err := db.Where("user_id = ?", userId).Order("id").Find(rows).Error
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := db.Where("user_id = ?", userId).Limit(1).Find(&related.TOTPCredential).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &related, nil
+}
+
+// InsertTombstone joins the transaction of the context, so the tombstone is
+// only stored along with the erasure.
+func (r *DataSubjectRepository) InsertTombstone(ctx context.Context, tombstone *model.ErasedUser) error {
+	return conn(ctx, r.db).Create(tombstone).Error
+}
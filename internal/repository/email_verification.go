@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type EmailVerificationRepository struct {
+	db *gorm.DB
+}
+
+type VerificationRepository interface {
+	Insert(context.Context, *model.EmailVerification) error
+	FindByTokenId(context.Context, uuid.UUID) (*model.EmailVerification, error)
+	FindLatestForUser(context.Context, int) (*model.EmailVerification, error)
+	Consume(context.Context, *model.EmailVerification) (bool, error)
+}
+
+func NewEmailVerificationRepository(d *gorm.DB) VerificationRepository {
+	return &EmailVerificationRepository{
+		db: d,
+	}
+}
+
+func (r *EmailVerificationRepository) Insert(ctx context.Context, verification *model.EmailVerification) error {
+	return r.db.WithContext(ctx).Create(verification).Error
+}
+
+func (r *EmailVerificationRepository) FindByTokenId(ctx context.Context, tokenId uuid.UUID) (*model.EmailVerification, error) {
+	var verification model.EmailVerification
+
+	err := r.db.WithContext(ctx).Find(&verification, "token_id = ?", tokenId).Error
+
+	if err != nil {
+		return &model.EmailVerification{}, err
+	}
+
+	return &verification, nil
+}
+
+func (r *EmailVerificationRepository) FindLatestForUser(ctx context.Context, userId int) (*model.EmailVerification, error) {
+	var verification model.EmailVerification
+
+	err := r.db.WithContext(ctx).Where("user_id = ?", userId).Order("created_at DESC").Limit(1).Find(&verification).Error
+
+	if err != nil {
+		return &model.EmailVerification{}, err
+	}
+
+	return &verification, nil
+}
+
+// Consume marks the verification as used. It reports false when the token
+// was already used.
+func (r *EmailVerificationRepository) Consume(ctx context.Context, verification *model.EmailVerification) (bool, error) {
+	result := conn(ctx, r.db).Model(&model.EmailVerification{}).
+		Where("id = ? AND used_at IS NULL", verification.Id).
+		Update("used_at", time.Now())
+
+	return result.RowsAffected != 0, result.Error
+}
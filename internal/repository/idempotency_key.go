@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyKeyRepository struct {
+	db *gorm.DB
+}
+
+type IdempotencyRepository interface {
+	Reserve(context.Context, *model.IdempotencyKey) (bool, error)
+	Find(context.Context, string, string) (*model.IdempotencyKey, error)
+	Complete(context.Context, *model.IdempotencyKey) error
+	Release(context.Context, *model.IdempotencyKey) error
+	DeleteForUser(context.Context, uuid.UUID) error
+}
+
+func NewIdempotencyKeyRepository(d *gorm.DB) IdempotencyRepository {
+	return &IdempotencyKeyRepository{
+		db: d,
+	}
+}
+
+// Reserve inserts the key in the processing state, or takes over a
+// processing record whose lock expired. It reports false when an unexpired
+// record for the same scope and key is completed or still locked, which is
+// how concurrent duplicates are told apart from the first request. Expired
+// records are purged on the way.
+func (r *IdempotencyKeyRepository) Reserve(ctx context.Context, record *model.IdempotencyKey) (bool, error) {
+	reserved := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("expires_at < ?", time.Now()).Delete(&model.IdempotencyKey{}).Error
+
+		if err != nil {
+			return err
+		}
+
+		result := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "scope"}, {Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"fingerprint", "created_at", "expires_at", "locked_until"}),
+			Where: clause.Where{Exprs: []clause.Expression{
+				clause.Eq{Column: clause.Column{Table: "idempotency_keys", Name: "status"}, Value: model.IDEMPOTENCY_PROCESSING},
+				clause.Lt{Column: clause.Column{Table: "idempotency_keys", Name: "locked_until"}, Value: time.Now()},
+			}},
+		}).Create(record)
+
+		if result.Error != nil {
+			return result.Error
+		}
+
+		reserved = result.RowsAffected == 1
+
+		return nil
+	})
+
+	return reserved, err
+}
+
+func (r *IdempotencyKeyRepository) Find(ctx context.Context, scope string, key string) (*model.IdempotencyKey, error) {
+	var record model.IdempotencyKey
+
+	err := r.db.WithContext(ctx).Find(&record, "scope = ? AND key = ?", scope, key).Error
+
+	if err != nil {
+		return &model.IdempotencyKey{}, err
+	}
+
+	return &record, nil
+}
+
+func (r *IdempotencyKeyRepository) Complete(ctx context.Context, record *model.IdempotencyKey) error {
+	return r.db.WithContext(ctx).Model(&model.IdempotencyKey{}).
+		Where("scope = ? AND key = ?", record.Scope, record.Key).
+		Updates(map[string]interface{}{
+			"status":            model.IDEMPOTENCY_COMPLETED,
+			"response_status":   record.ResponseStatus,
+			"response_type":     record.ResponseType,
+			"response_body":     record.ResponseBody,
+			"response_location": record.ResponseLocation,
+		}).Error
+}
+
+// Release forgets the key, so the request can be retried.
+func (r *IdempotencyKeyRepository) Release(ctx context.Context, record *model.IdempotencyKey) error {
+	return r.db.WithContext(ctx).Where("scope = ? AND key = ?", record.Scope, record.Key).Delete(&model.IdempotencyKey{}).Error
+}
+
+// DeleteForUser forgets the responses that tell about the user, i.e. whose
+// Location or body holds its id, such as the creations of the user.
+func (r *IdempotencyKeyRepository) DeleteForUser(ctx context.Context, externalId uuid.UUID) error {
+	return conn(ctx, r.db).
+		Where("response_location LIKE ? OR position(convert_to(?, 'UTF8') in response_body) > 0", "%"+externalId.String(), externalId.String()).
+		Delete(&model.IdempotencyKey{}).Error
+}
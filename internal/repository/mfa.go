@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"gorm.io/gorm"
+)
+
+type UserMFARepository struct {
+	db *gorm.DB
+}
+
+type MFARepository interface {
+	FindTOTPByUser(context.Context, int) (*model.TOTPCredential, error)
+	ReplaceUnconfirmedTOTP(context.Context, *model.TOTPCredential) error
+	ConfirmTOTP(context.Context, *model.TOTPCredential, int64, []string) (bool, error)
+	UseTOTPStep(context.Context, *model.TOTPCredential, int64) (bool, error)
+	UseRecoveryCode(context.Context, int, string) (bool, error)
+}
+
+func NewMFARepository(d *gorm.DB) MFARepository {
+	return &UserMFARepository{
+		db: d,
+	}
+}
+
+func (r *UserMFARepository) FindTOTPByUser(ctx context.Context, userId int) (*model.TOTPCredential, error) {
+	var credential model.TOTPCredential
+
+	err := r.db.WithContext(ctx).Find(&credential, "user_id = ?", userId).Error
+
+	if err != nil {
+		return &model.TOTPCredential{}, err
+	}
+
+	return &credential, nil
+}
+
+// ReplaceUnconfirmedTOTP drops a pending enrollment of the user, if any, and
+// stores the new one.
+func (r *UserMFARepository) ReplaceUnconfirmedTOTP(ctx context.Context, credential *model.TOTPCredential) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND confirmed_at IS NULL", credential.UserId).Delete(&model.TOTPCredential{}).Error
+
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(credential).Error
+	})
+}
+
+// ConfirmTOTP enables the credential and replaces the recovery codes of the
+// user with the given hashes. It reports false when the credential was
+// already confirmed.
+func (r *UserMFARepository) ConfirmTOTP(ctx context.Context, credential *model.TOTPCredential, step int64, codeHashes []string) (bool, error) {
+	confirmed := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.TOTPCredential{}).
+			Where("id = ? AND confirmed_at IS NULL", credential.Id).
+			Updates(map[string]interface{}{"confirmed_at": time.Now(), "last_used_step": step})
+
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		confirmed = true
+
+		err := tx.Where("user_id = ?", credential.UserId).Delete(&model.RecoveryCode{}).Error
+
+		if err != nil {
+			return err
+		}
+
+		codes := make([]model.RecoveryCode, len(codeHashes))
+
+		for i, hash := range codeHashes {
+			codes[i] = model.RecoveryCode{UserId: credential.UserId, CodeHash: hash}
+		}
+
+		return tx.Create(&codes).Error
+	})
+
+	return confirmed, err
+}
+
+// UseTOTPStep records step as used, refusing it when it is not newer than
+// the last accepted one so a code can't be replayed.
+func (r *UserMFARepository) UseTOTPStep(ctx context.Context, credential *model.TOTPCredential, step int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.TOTPCredential{}).
+		Where("id = ? AND last_used_step < ?", credential.Id, step).
+		Update("last_used_step", step)
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (r *UserMFARepository) UseRecoveryCode(ctx context.Context, userId int, codeHash string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userId, codeHash).
+		Update("used_at", time.Now())
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
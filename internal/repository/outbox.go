@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OutboxEventRepository struct {
+	db *gorm.DB
+}
+
+type OutboxRepository interface {
+	Insert(context.Context, *model.OutboxEvent) error
+	ClaimDue(ctx context.Context, now time.Time, limit int) ([]model.OutboxEvent, error)
+	MarkPublished(context.Context, *model.OutboxEvent, time.Time) error
+	MarkFailed(context.Context, *model.OutboxEvent) error
+	DeletePublishedBefore(context.Context, time.Time) (int64, error)
+	FindAfter(ctx context.Context, after StreamPosition, types []string, limit int) ([]model.OutboxEvent, error)
+	LastPosition(context.Context) (StreamPosition, error)
+	PositionOf(ctx context.Context, id int) (StreamPosition, error)
+	FindByAggregate(ctx context.Context, aggregateId uuid.UUID) ([]model.OutboxEvent, error)
+	ReplacePayloads(ctx context.Context, aggregateId uuid.UUID, payload []byte) error
+}
+
+// StreamPosition is a place in the stream of the outbox events, which are
+// read in the order of the transactions that wrote them, and in id order
+// within a transaction. Only the transactions older than every transaction
+// in progress are read: they can't be followed by events of an older one,
+// whereas the ids, assigned before the commit, can be.
+type StreamPosition struct {
+	TransactionId uint64
+	Id            int
+}
+
+// settled keeps the events of the transactions older than the oldest one
+// still in progress, as seen by the snapshot of the statement.
+const settled = "transaction_id < pg_snapshot_xmin(pg_current_snapshot())"
+
+func NewOutboxRepository(d *gorm.DB) OutboxRepository {
+	return &OutboxEventRepository{
+		db: d,
+	}
+}
+
+// Insert joins the transaction of the context, so the event is only stored
+// along with the change it describes.
+func (r *OutboxEventRepository) Insert(ctx context.Context, event *model.OutboxEvent) error {
+	return conn(ctx, r.db).Create(event).Error
+}
+
+// ClaimDue locks the oldest unpublished event of every aggregate, when it is
+// due. Later events of an aggregate wait until the earlier ones are
+// published, which keeps them in order, and events locked by another relay
+// are skipped. It must run in a transaction, which holds the locks.
+func (r *OutboxEventRepository) ClaimDue(ctx context.Context, now time.Time, limit int) ([]model.OutboxEvent, error) {
+	var events []model.OutboxEvent
+
+	db := conn(ctx, r.db)
+
+	heads := db.Model(&model.OutboxEvent{}).
+		Select("DISTINCT ON (aggregate_id) id").
+		Where("published_at IS NULL").
+		Order("aggregate_id, id")
+
+	err := db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("id IN (?)", heads).
+		Where("next_attempt_at <= ?", now).
+		Order("id").
+		Limit(limit).
+		Find(&events).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (r *OutboxEventRepository) MarkPublished(ctx context.Context, event *model.OutboxEvent, at time.Time) error {
+	return conn(ctx, r.db).Model(&model.OutboxEvent{}).
+		Where("id = ?", event.Id).
+		Updates(map[string]interface{}{"published_at": at, "attempts": event.Attempts}).Error
+}
+
+// MarkFailed saves the attempts, error and next attempt of the event.
+func (r *OutboxEventRepository) MarkFailed(ctx context.Context, event *model.OutboxEvent) error {
+	return conn(ctx, r.db).Model(&model.OutboxEvent{}).
+		Where("id = ?", event.Id).
+		Updates(map[string]interface{}{
+			"attempts":        event.Attempts,
+			"last_error":      event.LastError,
+			"next_attempt_at": event.NextAttemptAt,
+		}).Error
+}
+
+func (r *OutboxEventRepository) DeletePublishedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := conn(ctx, r.db).Where("published_at < ?", before).Delete(&model.OutboxEvent{})
+
+	return result.RowsAffected, result.Error
+}
+
+// FindAfter reads the settled events following after, published or not,
+// among the given types when some are given.
+func (r *OutboxEventRepository) FindAfter(ctx context.Context, after StreamPosition, types []string, limit int) ([]model.OutboxEvent, error) {
+	var events []model.OutboxEvent
+
+	query := conn(ctx, r.db).Where(settled).Where("(transaction_id, id) > (?::xid8, ?)", fmt.Sprint(after.TransactionId), after.Id)
+
+	if len(types) != 0 {
+		query = query.Where("type IN ?", types)
+	}
+
+	err := query.Order("transaction_id, id").Limit(limit).Find(&events).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// LastPosition is the position of the latest settled event, the start of
+// the stream when there is none.
+func (r *OutboxEventRepository) LastPosition(ctx context.Context) (StreamPosition, error) {
+	var event model.OutboxEvent
+
+	err := conn(ctx, r.db).Select("id", "transaction_id").Where(settled).Order("transaction_id DESC, id DESC").Take(&event).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return StreamPosition{}, nil
+	}
+
+	return StreamPosition{TransactionId: event.TransactionId, Id: event.Id}, err
+}
+
+// PositionOf is the position of the event, which fails with
+// gorm.ErrRecordNotFound once the event is cleaned up.
+func (r *OutboxEventRepository) PositionOf(ctx context.Context, id int) (StreamPosition, error) {
+	var event model.OutboxEvent
+
+	err := conn(ctx, r.db).Select("id", "transaction_id").Where("id = ?", id).Take(&event).Error
+
+	return StreamPosition{TransactionId: event.TransactionId, Id: event.Id}, err
+}
+
+// FindByAggregate returns the events of an aggregate still in the outbox,
+// published or not.
+func (r *OutboxEventRepository) FindByAggregate(ctx context.Context, aggregateId uuid.UUID) ([]model.OutboxEvent, error) {
+	var events []model.OutboxEvent
+
+	err := r.db.WithContext(ctx).Where("aggregate_id = ?", aggregateId).Order("id").Find(&events).Error
+
+	return events, err
+}
+
+// ReplacePayloads overwrites the payload of every event of an aggregate.
+func (r *OutboxEventRepository) ReplacePayloads(ctx context.Context, aggregateId uuid.UUID, payload []byte) error {
+	return conn(ctx, r.db).Model(&model.OutboxEvent{}).
+		Where("aggregate_id = ?", aggregateId).
+		Update("payload", payload).Error
+}
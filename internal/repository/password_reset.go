@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PasswordResetRepository struct {
+	db *gorm.DB
+}
+
+type ResetRepository interface {
+	Insert(context.Context, *model.PasswordReset) error
+	FindByTokenId(context.Context, uuid.UUID) (*model.PasswordReset, error)
+	Consume(context.Context, *model.PasswordReset) (bool, error)
+}
+
+func NewPasswordResetRepository(d *gorm.DB) ResetRepository {
+	return &PasswordResetRepository{
+		db: d,
+	}
+}
+
+func (r *PasswordResetRepository) Insert(ctx context.Context, reset *model.PasswordReset) error {
+	return r.db.WithContext(ctx).Create(reset).Error
+}
+
+func (r *PasswordResetRepository) FindByTokenId(ctx context.Context, tokenId uuid.UUID) (*model.PasswordReset, error) {
+	var reset model.PasswordReset
+
+	err := r.db.WithContext(ctx).Find(&reset, "token_id = ?", tokenId).Error
+
+	if err != nil {
+		return &model.PasswordReset{}, err
+	}
+
+	return &reset, nil
+}
+
+// Consume marks the reset as used and revokes every refresh token of the
+// user in a single transaction. It reports false when the reset was already
+// used.
+func (r *PasswordResetRepository) Consume(ctx context.Context, reset *model.PasswordReset) (bool, error) {
+	consumed := false
+
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		result := tx.Model(&model.PasswordReset{}).
+			Where("id = ? AND used_at IS NULL", reset.Id).
+			Update("used_at", now)
+
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		consumed = true
+
+		return tx.Model(&model.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", reset.UserId).
+			Update("revoked_at", now).Error
+	})
+
+	return consumed, err
+}
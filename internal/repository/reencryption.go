@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/LucasAndFlores/user_api/internal/pii"
+	"gorm.io/gorm"
+)
+
+type PIIRepository struct {
+	db *gorm.DB
+}
+
+// ReencryptionRepository finds the rows with pii encrypted with a retired
+// key, and writes them back with the current one.
+type ReencryptionRepository interface {
+	FindStaleUsers(ctx context.Context, keyId string, limit int) ([]model.User, error)
+	FindStaleVersions(ctx context.Context, keyId string, limit int) ([]model.UserVersion, error)
+	RewriteUser(context.Context, *model.User) (bool, error)
+	RewriteVersion(context.Context, *model.UserVersion) error
+	FindStaleTOTPCredentials(ctx context.Context, keyId string, limit int) ([]model.TOTPCredential, error)
+	RewriteTOTPCredential(context.Context, *model.TOTPCredential) error
+	FindStaleWebhookSubscriptions(ctx context.Context, keyId string, limit int) ([]model.WebhookSubscription, error)
+	RewriteWebhookSubscription(context.Context, *model.WebhookSubscription) error
+}
+
+func NewReencryptionRepository(d *gorm.DB) ReencryptionRepository {
+	return &PIIRepository{
+		db: d,
+	}
+}
+
+// FindStaleUsers returns up to limit users with a pii column not encrypted
+// with the given key.
+func (r *PIIRepository) FindStaleUsers(ctx context.Context, keyId string, limit int) ([]model.User, error) {
+	var users []model.User
+
+	prefix := escapeLike(pii.KeyPrefix(keyId)) + "%"
+
+	err := r.db.WithContext(ctx).
+		Where("email NOT LIKE ? OR date_of_birth NOT LIKE ?", prefix, prefix).
+		Order("id").Limit(limit).Find(&users).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (r *PIIRepository) FindStaleVersions(ctx context.Context, keyId string, limit int) ([]model.UserVersion, error) {
+	var versions []model.UserVersion
+
+	prefix := escapeLike(pii.KeyPrefix(keyId)) + "%"
+
+	err := r.db.WithContext(ctx).
+		Where("email NOT LIKE ? OR date_of_birth NOT LIKE ?", prefix, prefix).
+		Order("id").Limit(limit).Find(&versions).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// RewriteUser encrypts the pii of the user again, without bumping its
+// version. It reports false when the user was changed in the meantime, in
+// which case the change was already encrypted with the current key.
+func (r *PIIRepository) RewriteUser(ctx context.Context, user *model.User) (bool, error) {
+	result := conn(ctx, r.db).Model(user).
+		Where("version = ?", user.Version).
+		Select("email", "date_of_birth").
+		UpdateColumns(user)
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected != 0, nil
+}
+
+func (r *PIIRepository) RewriteVersion(ctx context.Context, version *model.UserVersion) error {
+	return conn(ctx, r.db).Model(version).Select("email", "date_of_birth").UpdateColumns(version).Error
+}
+
+func (r *PIIRepository) FindStaleTOTPCredentials(ctx context.Context, keyId string, limit int) ([]model.TOTPCredential, error) {
+	var credentials []model.TOTPCredential
+
+	err := r.db.WithContext(ctx).
+		Where("secret NOT LIKE ?", escapeLike(pii.KeyPrefix(keyId))+"%").
+		Order("id").Limit(limit).Find(&credentials).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return credentials, nil
+}
+
+// RewriteTOTPCredential only writes the secret, which never changes, so it
+// can't undo a concurrent use of the credential.
+func (r *PIIRepository) RewriteTOTPCredential(ctx context.Context, credential *model.TOTPCredential) error {
+	return conn(ctx, r.db).Model(credential).Select("secret").UpdateColumns(credential).Error
+}
+
+func (r *PIIRepository) FindStaleWebhookSubscriptions(ctx context.Context, keyId string, limit int) ([]model.WebhookSubscription, error) {
+	var subscriptions []model.WebhookSubscription
+
+	err := r.db.WithContext(ctx).
+		Where("secret NOT LIKE ?", escapeLike(pii.KeyPrefix(keyId))+"%").
+		Order("id").Limit(limit).Find(&subscriptions).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// RewriteWebhookSubscription only writes the secret, which never changes, so
+// it can't undo a concurrent failure count or disabling.
+func (r *PIIRepository) RewriteWebhookSubscription(ctx context.Context, subscription *model.WebhookSubscription) error {
+	return conn(ctx, r.db).Model(subscription).Select("secret").UpdateColumns(subscription).Error
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type RefreshTokenRepository struct {
+	db *gorm.DB
+}
+
+type TokenRepository interface {
+	Insert(context.Context, *model.RefreshToken) error
+	FindByHash(context.Context, string) (*model.RefreshToken, error)
+	MarkRotated(context.Context, int) (bool, error)
+	RevokeFamily(context.Context, uuid.UUID) error
+	RevokeAllForUser(context.Context, int) error
+}
+
+func NewRefreshTokenRepository(d *gorm.DB) TokenRepository {
+	return &RefreshTokenRepository{
+		db: d,
+	}
+}
+
+func (r *RefreshTokenRepository) Insert(ctx context.Context, token *model.RefreshToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *RefreshTokenRepository) FindByHash(ctx context.Context, hash string) (*model.RefreshToken, error) {
+	var token model.RefreshToken
+
+	err := r.db.WithContext(ctx).Find(&token, "token_hash = ?", hash).Error
+
+	if err != nil {
+		return &model.RefreshToken{}, err
+	}
+
+	return &token, nil
+}
+
+// MarkRotated flags the token as used only if nobody did it before, so two
+// concurrent refreshes with the same token can't both succeed. It reports
+// whether this call was the one that rotated it.
+func (r *RefreshTokenRepository) MarkRotated(ctx context.Context, id int) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.RefreshToken{}).
+		Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", id).
+		Update("rotated_at", time.Now())
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (r *RefreshTokenRepository) RevokeFamily(ctx context.Context, familyId uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&model.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyId).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *RefreshTokenRepository) RevokeAllForUser(ctx context.Context, userId int) error {
+	return r.db.WithContext(ctx).Model(&model.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// Transactor runs several repository calls in one database transaction.
+// The transaction travels in the context, so the repositories given that
+// context write through it.
+type Transactor interface {
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type GormTransactor struct {
+	db *gorm.DB
+}
+
+func NewTransactor(d *gorm.DB) Transactor {
+	return &GormTransactor{db: d}
+}
+
+// Transaction commits when fn returns nil and rolls back otherwise. Nested
+// calls join the outer transaction through a savepoint.
+func (t *GormTransactor) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return conn(ctx, t.db).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// conn is the transaction of the context, or db outside of one.
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+
+	return db.WithContext(ctx)
+}
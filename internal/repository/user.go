@@ -3,9 +3,12 @@ package repository
 import (
 	"context"
 	"errors"
+	"strings"
+	"time"
 
 	"github.com/LucasAndFlores/user_api/internal/dto"
 	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/LucasAndFlores/user_api/internal/pii"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
@@ -17,7 +20,15 @@ type UserRepository struct {
 type Repository interface {
 	Insert(context.Context, *model.User) error
 	CheckIfUserExist(context.Context, dto.UserDTO) (bool, error)
-	FindByExternalId(context.Context, uuid.UUID) (*model.User, error)
+	FindByExternalId(ctx context.Context, externalId uuid.UUID, columns ...string) (*model.User, error)
+	FindByEmail(context.Context, string) (*model.User, error)
+	FindById(context.Context, int) (*model.User, error)
+	Update(context.Context, *model.User) (bool, error)
+	UpdateColumns(ctx context.Context, user *model.User, columns ...string) (bool, error)
+	Delete(context.Context, *model.User) (bool, error)
+	FindByExternalIds(context.Context, []uuid.UUID) ([]model.User, error)
+	List(ctx context.Context, filter dto.UserFilter, afterId int, limit int, columns ...string) ([]model.User, error)
+	IsErased(ctx context.Context, externalId uuid.UUID) (bool, error)
 }
 
 func NewUserRepository(d *gorm.DB) Repository {
@@ -27,7 +38,9 @@ func NewUserRepository(d *gorm.DB) Repository {
 }
 
 func (r *UserRepository) Insert(ctx context.Context, user *model.User) error {
-	result := r.db.Create(user)
+	user.EmailIndex = pii.BlindIndex(user.Email)
+
+	result := conn(ctx, r.db).Create(user)
 
 	if result.Error != nil {
 		return result.Error
@@ -39,7 +52,7 @@ func (r *UserRepository) Insert(ctx context.Context, user *model.User) error {
 func (r *UserRepository) CheckIfUserExist(ctx context.Context, user dto.UserDTO) (bool, error) {
 	var foundUser model.User
 
-	err := r.db.Select("email", "external_id").Where("email = ?", user.Email).Or("external_id = ?", user.ExternalId).Take(&foundUser).Error
+	err := r.db.Select("email", "external_id").Where("email_index = ?", pii.BlindIndex(user.Email)).Or("external_id = ?", user.ExternalId).Take(&foundUser).Error
 
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return false, nil
@@ -52,10 +65,42 @@ func (r *UserRepository) CheckIfUserExist(ctx context.Context, user dto.UserDTO)
 	return true, nil
 }
 
-func (r *UserRepository) FindByExternalId(ctx context.Context, externalId uuid.UUID) (*model.User, error) {
+// FindByExternalId loads the given columns of the user, or all of them when
+// none is given.
+func (r *UserRepository) FindByExternalId(ctx context.Context, externalId uuid.UUID, columns ...string) (*model.User, error) {
+	var user model.User
+
+	query := r.db
+
+	if len(columns) != 0 {
+		query = query.Select(columns)
+	}
+
+	err := query.Find(&user, "external_id = ?", externalId).Error
+
+	if err != nil {
+		return &model.User{}, err
+	}
+
+	return &user, nil
+}
+
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+	var user model.User
+
+	err := r.db.WithContext(ctx).Find(&user, "email_index = ?", pii.BlindIndex(email)).Error
+
+	if err != nil {
+		return &model.User{}, err
+	}
+
+	return &user, nil
+}
+
+func (r *UserRepository) FindById(ctx context.Context, id int) (*model.User, error) {
 	var user model.User
 
-	err := r.db.Find(&user, "external_id = ?", externalId).Error
+	err := r.db.WithContext(ctx).Find(&user, "id = ?", id).Error
 
 	if err != nil {
 		return &model.User{}, err
@@ -63,3 +108,147 @@ func (r *UserRepository) FindByExternalId(ctx context.Context, externalId uuid.U
 
 	return &user, nil
 }
+
+// Update saves the profile fields only if the stored version still matches
+// user.Version, and bumps it. It reports false when someone else changed the
+// user in the meantime.
+func (r *UserRepository) Update(ctx context.Context, user *model.User) (bool, error) {
+	user.EmailIndex = pii.BlindIndex(user.Email)
+
+	return r.UpdateColumns(ctx, user, "name", "email", "email_index", "date_of_birth", "email_verified_at")
+}
+
+// UpdateColumns saves the given columns of the user with the rules of Update.
+//
+// The fields are given as a struct rather than a map, as GORM only encrypts
+// the pii columns of structs.
+func (r *UserRepository) UpdateColumns(ctx context.Context, user *model.User, columns ...string) (bool, error) {
+	updated := *user
+
+	updated.Version++
+
+	// GORM sets the updated_at of the model, left untouched on conflicts
+	result := conn(ctx, r.db).Model(&updated).
+		Where("version = ?", user.Version).
+		Select(append(columns, "version")).
+		Updates(&updated)
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	user.UpdatedAt = updated.UpdatedAt
+	user.Version++
+
+	return true, nil
+}
+
+// Delete removes the user, along with its credentials and sessions, only if
+// the stored version still matches user.Version.
+func (r *UserRepository) Delete(ctx context.Context, user *model.User) (bool, error) {
+	deleted := false
+
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND version = ?", user.Id, user.Version).Delete(&model.User{})
+
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		deleted = true
+
+		for _, related := range []interface{}{
+			&model.RefreshToken{},
+			&model.EmailVerification{},
+			&model.PasswordReset{},
+			&model.TOTPCredential{},
+			&model.RecoveryCode{},
+		} {
+			err := tx.Where("user_id = ?", user.Id).Delete(related).Error
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return deleted, err
+}
+
+// FindByExternalIds loads several users in one query. Unknown ids are
+// missing from the result.
+func (r *UserRepository) FindByExternalIds(ctx context.Context, externalIds []uuid.UUID) ([]model.User, error) {
+	var users []model.User
+
+	err := r.db.WithContext(ctx).Where("external_id IN ?", externalIds).Find(&users).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// SYNC_LAG widens the updated_since filters. updated_at is set by the clock
+// of the API before the transaction commits, so a change can become visible
+// after a sync starting later than its updated_at. Listing the changes since
+// SYNC_LAG earlier catches the transactions committing within SYNC_LAG, and
+// the clocks of the replicas drifting by less.
+const SYNC_LAG = time.Minute
+
+// List returns up to limit users matching the filter with an id greater
+// than afterId, ordered by id, so pages stay stable while users are created.
+// Like FindByExternalId, it loads all columns unless some are given.
+func (r *UserRepository) List(ctx context.Context, filter dto.UserFilter, afterId int, limit int, columns ...string) ([]model.User, error) {
+	var users []model.User
+
+	query := r.db.WithContext(ctx).Where("id > ?", afterId)
+
+	if len(columns) != 0 {
+		query = query.Select(columns)
+	}
+
+	if filter.Email != "" {
+		query = query.Where("email_index = ?", pii.BlindIndex(filter.Email))
+	}
+
+	if filter.Name != "" {
+		query = query.Where("name ILIKE ?", "%"+escapeLike(filter.Name)+"%")
+	}
+
+	if filter.UpdatedSince != nil {
+		query = query.Where("updated_at >= ?", filter.UpdatedSince.Add(-SYNC_LAG))
+	}
+
+	err := query.Order("id").Limit(limit).Find(&users).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+// IsErased tells whether the data of the user was erased, which forbids
+// registering its id again.
+func (r *UserRepository) IsErased(ctx context.Context, externalId uuid.UUID) (bool, error) {
+	var count int64
+
+	err := r.db.WithContext(ctx).Model(&model.ErasedUser{}).Where("external_id = ?", externalId).Count(&count).Error
+
+	return count > 0, err
+}
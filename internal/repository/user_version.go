@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type UserVersionRepository struct {
+	db *gorm.DB
+}
+
+type VersionRepository interface {
+	Insert(context.Context, *model.UserVersion) error
+	List(ctx context.Context, externalId uuid.UUID, afterVersion int, limit int) ([]model.UserVersion, error)
+	FindVersion(ctx context.Context, externalId uuid.UUID, version int) (*model.UserVersion, error)
+	FindAsOf(ctx context.Context, externalId uuid.UUID, at time.Time) (*model.UserVersion, error)
+	DeleteAll(ctx context.Context, externalId uuid.UUID) error
+}
+
+func NewVersionRepository(d *gorm.DB) VersionRepository {
+	return &UserVersionRepository{
+		db: d,
+	}
+}
+
+// Insert joins the transaction of the context, like the outbox.
+func (r *UserVersionRepository) Insert(ctx context.Context, version *model.UserVersion) error {
+	return conn(ctx, r.db).Create(version).Error
+}
+
+// List returns every version after afterVersion when limit is -1.
+func (r *UserVersionRepository) List(ctx context.Context, externalId uuid.UUID, afterVersion int, limit int) ([]model.UserVersion, error) {
+	var versions []model.UserVersion
+
+	err := r.db.WithContext(ctx).
+		Where("user_external_id = ? AND version > ?", externalId, afterVersion).
+		Order("version").
+		Limit(limit).
+		Find(&versions).Error
+
+	return versions, err
+}
+
+// FindVersion returns an empty version, with a zero Id, when it doesn't exist.
+func (r *UserVersionRepository) FindVersion(ctx context.Context, externalId uuid.UUID, version int) (*model.UserVersion, error) {
+	return r.first(r.db.WithContext(ctx).Where("user_external_id = ? AND version = ?", externalId, version))
+}
+
+// FindAsOf returns the version current at the given time, or an empty one
+// when the user didn't exist yet.
+func (r *UserVersionRepository) FindAsOf(ctx context.Context, externalId uuid.UUID, at time.Time) (*model.UserVersion, error) {
+	return r.first(r.db.WithContext(ctx).
+		Where("user_external_id = ? AND recorded_at <= ?", externalId, at).
+		Order("version DESC"))
+}
+
+func (r *UserVersionRepository) first(query *gorm.DB) (*model.UserVersion, error) {
+	var version model.UserVersion
+
+	err := query.Limit(1).Find(&version).Error
+
+	if err != nil {
+		return &model.UserVersion{}, err
+	}
+
+	return &version, nil
+}
+
+// DeleteAll joins the transaction of the context, for erasures.
+func (r *UserVersionRepository) DeleteAll(ctx context.Context, externalId uuid.UUID) error {
+	return conn(ctx, r.db).Where("user_external_id = ?", externalId).Delete(&model.UserVersion{}).Error
+}
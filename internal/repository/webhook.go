@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookSubscriptionRepository struct {
+	db *gorm.DB
+}
+
+type WebhookRepository interface {
+	InsertSubscription(context.Context, *model.WebhookSubscription) error
+	FindSubscriptions(context.Context) ([]model.WebhookSubscription, error)
+	FindSubscriptionByExternalId(context.Context, uuid.UUID) (*model.WebhookSubscription, error)
+	FindSubscriptionById(context.Context, int) (*model.WebhookSubscription, error)
+	DeleteSubscription(context.Context, *model.WebhookSubscription) error
+	EnableSubscription(context.Context, *model.WebhookSubscription) error
+	ResetFailures(context.Context, *model.WebhookSubscription) error
+	CountFailure(ctx context.Context, subscription *model.WebhookSubscription, disableAfter int, now time.Time) error
+	InsertDelivery(context.Context, *model.WebhookDelivery) error
+	FindDeliveries(ctx context.Context, subscriptionId int, limit int) ([]model.WebhookDelivery, error)
+	FindDeliveryByExternalId(ctx context.Context, subscriptionId int, externalId uuid.UUID) (*model.WebhookDelivery, error)
+	ClaimDueDeliveries(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]model.WebhookDelivery, error)
+	SaveAttempt(context.Context, *model.WebhookDelivery) error
+	FindSharedEvents(ctx context.Context, aggregateId uuid.UUID) ([]SharedEvent, error)
+	ReplaceDeliveredPayloads(ctx context.Context, aggregateId uuid.UUID, payload []byte) error
+}
+
+// SharedEvent is a delivery of an event of some aggregate, along with the URL
+// it was sent to.
+type SharedEvent struct {
+	EventId   int
+	EventType string
+	URL       string
+	Status    string
+	CreatedAt time.Time
+}
+
+// deliveredAggregate matches the deliveries of the events of an aggregate,
+// whose id is only found in their payload.
+const deliveredAggregate = "convert_from(webhook_deliveries.payload, 'UTF8')::jsonb->>'aggregate_id' = ?"
+
+func NewWebhookRepository(d *gorm.DB) WebhookRepository {
+	return &WebhookSubscriptionRepository{
+		db: d,
+	}
+}
+
+func (r *WebhookSubscriptionRepository) InsertSubscription(ctx context.Context, subscription *model.WebhookSubscription) error {
+	return conn(ctx, r.db).Create(subscription).Error
+}
+
+func (r *WebhookSubscriptionRepository) FindSubscriptions(ctx context.Context) ([]model.WebhookSubscription, error) {
+	var subscriptions []model.WebhookSubscription
+
+	err := conn(ctx, r.db).Order("id").Find(&subscriptions).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+func (r *WebhookSubscriptionRepository) FindSubscriptionByExternalId(ctx context.Context, externalId uuid.UUID) (*model.WebhookSubscription, error) {
+	var subscription model.WebhookSubscription
+
+	err := conn(ctx, r.db).Find(&subscription, "external_id = ?", externalId).Error
+
+	if err != nil {
+		return &model.WebhookSubscription{}, err
+	}
+
+	return &subscription, nil
+}
+
+func (r *WebhookSubscriptionRepository) FindSubscriptionById(ctx context.Context, id int) (*model.WebhookSubscription, error) {
+	var subscription model.WebhookSubscription
+
+	err := conn(ctx, r.db).Find(&subscription, "id = ?", id).Error
+
+	if err != nil {
+		return &model.WebhookSubscription{}, err
+	}
+
+	return &subscription, nil
+}
+
+// DeleteSubscription removes the subscription along with its delivery log.
+func (r *WebhookSubscriptionRepository) DeleteSubscription(ctx context.Context, subscription *model.WebhookSubscription) error {
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("subscription_id = ?", subscription.Id).Delete(&model.WebhookDelivery{}).Error
+
+		if err != nil {
+			return err
+		}
+
+		return tx.Delete(&model.WebhookSubscription{}, subscription.Id).Error
+	})
+}
+
+// EnableSubscription lets a disabled subscription receive deliveries again,
+// including the ones that were pending when it was disabled.
+func (r *WebhookSubscriptionRepository) EnableSubscription(ctx context.Context, subscription *model.WebhookSubscription) error {
+	subscription.DisabledAt = nil
+	subscription.ConsecutiveFailures = 0
+
+	return conn(ctx, r.db).Model(&model.WebhookSubscription{}).
+		Where("id = ?", subscription.Id).
+		Updates(map[string]interface{}{"disabled_at": nil, "consecutive_failures": 0}).Error
+}
+
+func (r *WebhookSubscriptionRepository) ResetFailures(ctx context.Context, subscription *model.WebhookSubscription) error {
+	return conn(ctx, r.db).Model(&model.WebhookSubscription{}).
+		Where("id = ?", subscription.Id).
+		Update("consecutive_failures", 0).Error
+}
+
+// CountFailure adds a failed attempt to the subscription, and disables it
+// once disableAfter attempts in a row failed.
+func (r *WebhookSubscriptionRepository) CountFailure(ctx context.Context, subscription *model.WebhookSubscription, disableAfter int, now time.Time) error {
+	return conn(ctx, r.db).Model(&model.WebhookSubscription{}).
+		Where("id = ?", subscription.Id).
+		Updates(map[string]interface{}{
+			"consecutive_failures": gorm.Expr("consecutive_failures + 1"),
+			"disabled_at":          gorm.Expr("CASE WHEN disabled_at IS NULL AND consecutive_failures + 1 >= ? THEN ?::timestamptz ELSE disabled_at END", disableAfter, now),
+		}).Error
+}
+
+// InsertDelivery joins the transaction of the context, like the outbox
+// events the deliveries are made from.
+func (r *WebhookSubscriptionRepository) InsertDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	return conn(ctx, r.db).Create(delivery).Error
+}
+
+// FindDeliveries returns the latest deliveries of the subscription first.
+func (r *WebhookSubscriptionRepository) FindDeliveries(ctx context.Context, subscriptionId int, limit int) ([]model.WebhookDelivery, error) {
+	var deliveries []model.WebhookDelivery
+
+	err := conn(ctx, r.db).Where("subscription_id = ?", subscriptionId).Order("id DESC").Limit(limit).Find(&deliveries).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (r *WebhookSubscriptionRepository) FindDeliveryByExternalId(ctx context.Context, subscriptionId int, externalId uuid.UUID) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+
+	err := conn(ctx, r.db).Find(&delivery, "subscription_id = ? AND external_id = ?", subscriptionId, externalId).Error
+
+	if err != nil {
+		return &model.WebhookDelivery{}, err
+	}
+
+	return &delivery, nil
+}
+
+// ClaimDueDeliveries takes the pending deliveries of enabled subscriptions
+// that are due, skipping the ones locked by another dispatcher, and leases
+// them by postponing their next attempt by lease. It must run in a
+// transaction, whose commit hands the lease over to the caller.
+func (r *WebhookSubscriptionRepository) ClaimDueDeliveries(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]model.WebhookDelivery, error) {
+	var deliveries []model.WebhookDelivery
+
+	db := conn(ctx, r.db)
+
+	enabled := db.Model(&model.WebhookSubscription{}).Select("id").Where("disabled_at IS NULL")
+
+	err := db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ?", model.DELIVERY_PENDING).
+		Where("next_attempt_at <= ?", now).
+		Where("subscription_id IN (?)", enabled).
+		Order("id").
+		Limit(limit).
+		Find(&deliveries).Error
+
+	if err != nil || len(deliveries) == 0 {
+		return nil, err
+	}
+
+	ids := make([]int, len(deliveries))
+
+	for i := range deliveries {
+		ids[i] = deliveries[i].Id
+	}
+
+	err = db.Model(&model.WebhookDelivery{}).Where("id IN ?", ids).Update("next_attempt_at", now.Add(lease)).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (r *WebhookSubscriptionRepository) SaveAttempt(ctx context.Context, delivery *model.WebhookDelivery) error {
+	return conn(ctx, r.db).Model(&model.WebhookDelivery{}).
+		Where("id = ?", delivery.Id).
+		Updates(map[string]interface{}{
+			"status":          delivery.Status,
+			"attempts":        delivery.Attempts,
+			"response_status": delivery.ResponseStatus,
+			"last_error":      delivery.LastError,
+			"next_attempt_at": delivery.NextAttemptAt,
+			"last_attempt_at": delivery.LastAttemptAt,
+		}).Error
+}
+
+func (r *WebhookSubscriptionRepository) FindSharedEvents(ctx context.Context, aggregateId uuid.UUID) ([]SharedEvent, error) {
+	var shared []SharedEvent
+
+	err := r.db.WithContext(ctx).Model(&model.WebhookDelivery{}).
+		Select("webhook_deliveries.event_id, webhook_deliveries.event_type, webhook_subscriptions.url, webhook_deliveries.status, webhook_deliveries.created_at").
+		Joins("JOIN webhook_subscriptions ON webhook_subscriptions.id = webhook_deliveries.subscription_id").
+		Where(deliveredAggregate, aggregateId.String()).
+		Order("webhook_deliveries.id").
+		Scan(&shared).Error
+
+	return shared, err
+}
+
+// ReplaceDeliveredPayloads overwrites the payload of the event in the body of
+// every delivery of the aggregate, sent or not.
+func (r *WebhookSubscriptionRepository) ReplaceDeliveredPayloads(ctx context.Context, aggregateId uuid.UUID, payload []byte) error {
+	return conn(ctx, r.db).Model(&model.WebhookDelivery{}).
+		Where(deliveredAggregate, aggregateId.String()).
+		Update("payload", gorm.Expr("convert_to(jsonb_set(convert_from(payload, 'UTF8')::jsonb, '{payload}', ?::jsonb)::text, 'UTF8')", string(payload))).Error
+}
@@ -0,0 +1,133 @@
+package rotation
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/pii"
+	"github.com/LucasAndFlores/user_api/internal/repository"
+)
+
+type ReencryptorOptions struct {
+	// Interval is the pause between two searches for values encrypted with
+	// a retired key.
+	Interval  time.Duration
+	BatchSize int
+}
+
+// Reencryptor encrypts with the current key the pii still encrypted with a
+// retired one, so the retired keys can be removed from the keyring once it
+// is done.
+type Reencryptor struct {
+	pii     repository.ReencryptionRepository
+	options ReencryptorOptions
+}
+
+func NewReencryptor(r repository.ReencryptionRepository, options ReencryptorOptions) *Reencryptor {
+	return &Reencryptor{pii: r, options: options}
+}
+
+// Run re-encrypts the stale values until ctx is done.
+func (r *Reencryptor) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.options.Interval)
+
+	defer ticker.Stop()
+
+	for {
+		for {
+			rewritten, err := r.RunOnce(ctx)
+
+			if err != nil {
+				log.Printf("An error occurred when tried to re-encrypt the pii: %v", err)
+			}
+
+			if err != nil || rewritten == 0 {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce re-encrypts one batch of users, one of user versions, one of TOTP
+// credentials and one of webhook subscriptions, and reports how many rows
+// were rewritten.
+func (r *Reencryptor) RunOnce(ctx context.Context) (int, error) {
+	keyId := pii.CurrentKeyId()
+
+	users, err := r.pii.FindStaleUsers(ctx, keyId, r.options.BatchSize)
+
+	if err != nil {
+		return 0, err
+	}
+
+	rewritten := 0
+
+	for i := range users {
+		ok, err := r.pii.RewriteUser(ctx, &users[i])
+
+		if err != nil {
+			return rewritten, err
+		}
+
+		if ok {
+			rewritten++
+		}
+	}
+
+	versions, err := r.pii.FindStaleVersions(ctx, keyId, r.options.BatchSize)
+
+	if err != nil {
+		return rewritten, err
+	}
+
+	for i := range versions {
+		err := r.pii.RewriteVersion(ctx, &versions[i])
+
+		if err != nil {
+			return rewritten, err
+		}
+
+		rewritten++
+	}
+
+	credentials, err := r.pii.FindStaleTOTPCredentials(ctx, keyId, r.options.BatchSize)
+
+	if err != nil {
+		return rewritten, err
+	}
+
+	for i := range credentials {
+		err := r.pii.RewriteTOTPCredential(ctx, &credentials[i])
+
+		if err != nil {
+			return rewritten, err
+		}
+
+		rewritten++
+	}
+
+	subscriptions, err := r.pii.FindStaleWebhookSubscriptions(ctx, keyId, r.options.BatchSize)
+
+	if err != nil {
+		return rewritten, err
+	}
+
+	for i := range subscriptions {
+		err := r.pii.RewriteWebhookSubscription(ctx, &subscriptions[i])
+
+		if err != nil {
+			return rewritten, err
+		}
+
+		rewritten++
+	}
+
+	return rewritten, nil
+}
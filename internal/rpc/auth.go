@@ -0,0 +1,46 @@
+package rpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"github.com/LucasAndFlores/user_api/internal/audit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// AUDIT_PRINCIPAL is the actor of the changes made through gRPC, as every
+// caller shares the same token.
+const AUDIT_PRINCIPAL = "grpc"
+
+// TokenAuth accepts only the calls carrying the shared token in the
+// "authorization: Bearer <token>" metadata. The "x-request-id" metadata, when
+// sent, tags the audit records of the call.
+func TokenAuth(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		var sent string
+
+		for _, value := range md.Get("authorization") {
+			if bearer, found := strings.CutPrefix(value, "Bearer "); found {
+				sent = bearer
+			}
+		}
+
+		if sent == "" || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+
+		actor := audit.Actor{Principal: AUDIT_PRINCIPAL}
+
+		if ids := md.Get("x-request-id"); len(ids) > 0 {
+			actor.RequestId = ids[0]
+		}
+
+		return handler(audit.WithActor(ctx, actor), req)
+	}
+}
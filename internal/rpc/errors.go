@@ -0,0 +1,94 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// codesByStatus translates the HTTP statuses returned by the services.
+var codesByStatus = map[int]codes.Code{
+	fiber.StatusBadRequest:          codes.InvalidArgument,
+	fiber.StatusUnauthorized:        codes.Unauthenticated,
+	fiber.StatusForbidden:           codes.PermissionDenied,
+	fiber.StatusNotFound:            codes.NotFound,
+	fiber.StatusConflict:            codes.AlreadyExists,
+	fiber.StatusPreconditionFailed:  codes.FailedPrecondition,
+	fiber.StatusUnprocessableEntity: codes.InvalidArgument,
+	fiber.StatusTooManyRequests:     codes.ResourceExhausted,
+}
+
+func toStatus(httpStatus int, body map[string]interface{}) error {
+	code, ok := codesByStatus[httpStatus]
+
+	if !ok {
+		code = codes.Internal
+	}
+
+	message, _ := body["message"].(string)
+
+	return status.Error(code, message)
+}
+
+// invalidArgument reports the violated fields the way the Google APIs do,
+// with a BadRequest detail.
+func invalidArgument(violations ...*errdetails.BadRequest_FieldViolation) error {
+	st, err := status.New(codes.InvalidArgument, "invalid request").WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	return st.Err()
+}
+
+// validate checks a DTO with the validate tags used by the HTTP API. The
+// violations name the fields by their json name, which is also their name
+// in the protobuf messages.
+func validate(rules *validator.Validate, v interface{}) error {
+	err := rules.Struct(v)
+
+	var fieldErrors validator.ValidationErrors
+
+	if !errors.As(err, &fieldErrors) {
+		return err
+	}
+
+	var violations []*errdetails.BadRequest_FieldViolation
+
+	for _, fieldError := range fieldErrors {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       jsonName(v, fieldError.StructField()),
+			Description: describe(fieldError),
+		})
+	}
+
+	return invalidArgument(violations...)
+}
+
+func jsonName(v interface{}, structField string) string {
+	field, ok := reflect.TypeOf(v).FieldByName(structField)
+
+	if !ok {
+		return structField
+	}
+
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+	return name
+}
+
+func describe(fieldError validator.FieldError) string {
+	if fieldError.Param() == "" {
+		return fmt.Sprintf("failed the %v rule", fieldError.Tag())
+	}
+
+	return fmt.Sprintf("failed the %v=%v rule", fieldError.Tag(), fieldError.Param())
+}
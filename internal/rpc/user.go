@@ -0,0 +1,190 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/LucasAndFlores/user_api/internal/dto"
+	"github.com/LucasAndFlores/user_api/internal/service"
+	userv1 "github.com/LucasAndFlores/user_api/proto/user/v1"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// UserServer serves the gRPC API with the same service as the HTTP API, so
+// both apply the same rules.
+type UserServer struct {
+	userv1.UnimplementedUserServiceServer
+	service   service.Service
+	validator *validator.Validate
+}
+
+func NewUserServer(s service.Service, v *validator.Validate) userv1.UserServiceServer {
+	return &UserServer{service: s, validator: v}
+}
+
+func (s *UserServer) Create(ctx context.Context, req *userv1.CreateRequest) (*userv1.CreateResponse, error) {
+	body := dto.UserDTO{
+		Name:        req.GetName(),
+		Email:       req.GetEmail(),
+		ExternalId:  req.GetId(),
+		DateOfBirth: req.GetDateOfBirth(),
+		Password:    req.GetPassword(),
+	}
+
+	err := validate(s.validator, body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	status, resp := s.service.Create(ctx, body)
+
+	if status != fiber.StatusCreated {
+		return nil, toStatus(status, resp)
+	}
+
+	user, err := s.find(ctx, uuid.MustParse(body.ExternalId))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &userv1.CreateResponse{User: user}, nil
+}
+
+func (s *UserServer) Get(ctx context.Context, req *userv1.GetRequest) (*userv1.GetResponse, error) {
+	id, err := parseId(req.GetId())
+
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.find(ctx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &userv1.GetResponse{User: user}, nil
+}
+
+func (s *UserServer) Update(ctx context.Context, req *userv1.UpdateRequest) (*userv1.UpdateResponse, error) {
+	id, err := parseId(req.GetId())
+
+	if err != nil {
+		return nil, err
+	}
+
+	body := dto.UpdateUserDTO{
+		Name:        req.GetName(),
+		Email:       req.GetEmail(),
+		DateOfBirth: req.GetDateOfBirth(),
+	}
+
+	err = validate(s.validator, body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	status, resp := s.service.Update(ctx, id, body, expectedVersion(req.ExpectedVersion))
+
+	if status != fiber.StatusOK {
+		return nil, toStatus(status, resp)
+	}
+
+	return &userv1.UpdateResponse{User: toUser(resp["user"].(dto.UserDTO))}, nil
+}
+
+func (s *UserServer) Delete(ctx context.Context, req *userv1.DeleteRequest) (*userv1.DeleteResponse, error) {
+	id, err := parseId(req.GetId())
+
+	if err != nil {
+		return nil, err
+	}
+
+	status, resp := s.service.Delete(ctx, id, expectedVersion(req.ExpectedVersion))
+
+	if status != fiber.StatusOK {
+		return nil, toStatus(status, resp)
+	}
+
+	return &userv1.DeleteResponse{}, nil
+}
+
+func (s *UserServer) List(ctx context.Context, req *userv1.ListRequest) (*userv1.ListResponse, error) {
+	var filter dto.UserFilter
+
+	if req.UpdatedSince != nil {
+		err := req.UpdatedSince.CheckValid()
+
+		if err != nil {
+			return nil, invalidArgument(&errdetails.BadRequest_FieldViolation{Field: "updated_since", Description: "must be a valid timestamp"})
+		}
+
+		since := req.UpdatedSince.AsTime()
+
+		filter.UpdatedSince = &since
+	}
+
+	status, resp := s.service.List(ctx, filter, nil, int(req.GetPageSize()), req.GetPageToken())
+
+	if status != fiber.StatusOK {
+		return nil, toStatus(status, resp)
+	}
+
+	found := resp["users"].([]dto.UserDTO)
+
+	users := make([]*userv1.User, len(found))
+
+	for i, user := range found {
+		users[i] = toUser(user)
+	}
+
+	return &userv1.ListResponse{Users: users, NextPageToken: resp["next_cursor"].(string)}, nil
+}
+
+func (s *UserServer) find(ctx context.Context, id uuid.UUID) (*userv1.User, error) {
+	status, resp := s.service.FindUserByExternalId(ctx, id, nil)
+
+	if status != fiber.StatusOK {
+		return nil, toStatus(status, resp)
+	}
+
+	return toUser(resp["user"].(dto.UserDTO)), nil
+}
+
+func parseId(id string) (uuid.UUID, error) {
+	parsed, err := uuid.Parse(id)
+
+	if err != nil {
+		return uuid.UUID{}, invalidArgument(&errdetails.BadRequest_FieldViolation{Field: "id", Description: "must be a UUID"})
+	}
+
+	return parsed, nil
+}
+
+func expectedVersion(version *int64) *int {
+	if version == nil {
+		return nil
+	}
+
+	expected := int(*version)
+
+	return &expected
+}
+
+func toUser(user dto.UserDTO) *userv1.User {
+	return &userv1.User{
+		Id:          user.ExternalId,
+		Name:        user.Name,
+		Email:       user.Email,
+		DateOfBirth: user.DateOfBirth,
+		Version:     int64(user.Version),
+		CreatedAt:   timestamppb.New(user.CreatedAt),
+		UpdatedAt:   timestamppb.New(user.UpdatedAt),
+	}
+}
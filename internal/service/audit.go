@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+
+	"github.com/LucasAndFlores/user_api/internal/dto"
+	"github.com/LucasAndFlores/user_api/internal/repository"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type AuditService interface {
+	Trail(ctx context.Context, externalId uuid.UUID, pageSize int, cursor string) (int, responseBody)
+}
+
+type AuditTrailService struct {
+	audits repository.AuditRepository
+}
+
+func NewAuditService(a repository.AuditRepository) AuditService {
+	return &AuditTrailService{audits: a}
+}
+
+// Trail pages through the audit records of a user, the oldest first, like
+// UserService.List. Deleted users keep their trail.
+func (s *AuditTrailService) Trail(ctx context.Context, externalId uuid.UUID, pageSize int, cursor string) (int, responseBody) {
+	if pageSize == 0 {
+		pageSize = DEFAULT_PAGE_SIZE
+	}
+
+	afterId, err := decodeCursor(cursor)
+
+	if err != nil || pageSize < 0 || pageSize > MAX_PAGE_SIZE {
+		return fiber.StatusBadRequest, responseBody{"message": INVALID_PAGE_MESSAGE}
+	}
+
+	found, err := s.audits.FindByUserExternalId(ctx, externalId, afterId, pageSize+1)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	nextCursor := ""
+
+	if len(found) > pageSize {
+		found = found[:pageSize]
+		nextCursor = encodeCursor(found[pageSize-1].Id)
+	}
+
+	records := make([]dto.AuditRecordDTO, len(found))
+
+	for i := range found {
+		err = records[i].ConvertToAuditRecordDTO(&found[i])
+
+		if err != nil {
+			return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+		}
+	}
+
+	return fiber.StatusOK, responseBody{"records": records, "next_cursor": nextCursor}
+}
@@ -0,0 +1,294 @@
+package service
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/auth"
+	"github.com/LucasAndFlores/user_api/internal/dto"
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/LucasAndFlores/user_api/internal/ratelimit"
+	"github.com/LucasAndFlores/user_api/internal/repository"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	MFA_LOGIN_PURPOSE = "mfa_login"
+	MFA_LOGIN_TTL     = 5 * time.Minute
+	// MFA_LOGIN_ATTEMPTS is how many codes an mfa token may be presented
+	// with before the login must start over with the password.
+	MFA_LOGIN_ATTEMPTS = 5
+
+	INVALID_CREDENTIALS_MESSAGE   = "invalid credentials"
+	INVALID_REFRESH_TOKEN_MESSAGE = "invalid refresh token"
+	INVALID_MFA_CODE_MESSAGE      = "invalid mfa token or code"
+	MFA_TOKEN_EXHAUSTED_MESSAGE   = "too many attempts, log in again"
+)
+
+type AuthService interface {
+	Login(context.Context, dto.LoginDTO) (int, responseBody)
+	LoginMFA(context.Context, dto.MFALoginDTO) (int, responseBody)
+	Refresh(context.Context, dto.RefreshTokenDTO) (int, responseBody)
+	Logout(context.Context, dto.RefreshTokenDTO) (int, responseBody)
+	RevokeAllSessions(context.Context, uuid.UUID) (int, responseBody)
+}
+
+type AuthOptions struct {
+	RefreshTokenTTL      time.Duration
+	RequireVerifiedEmail bool
+	// MFALimit throttles the second step of the logins of each user,
+	// whatever the mfa token.
+	MFALimit ratelimit.Limit
+}
+
+type UserAuthService struct {
+	users   repository.Repository
+	tokens  repository.TokenRepository
+	mfa     repository.MFARepository
+	hasher  auth.PasswordHasher
+	issuer  *auth.TokenIssuer
+	limiter ratelimit.Store
+	options AuthOptions
+}
+
+func NewAuthService(u repository.Repository, t repository.TokenRepository, m repository.MFARepository, h auth.PasswordHasher, i *auth.TokenIssuer, l ratelimit.Store, o AuthOptions) AuthService {
+	return &UserAuthService{users: u, tokens: t, mfa: m, hasher: h, issuer: i, limiter: l, options: o}
+}
+
+func (s *UserAuthService) Login(ctx context.Context, credentials dto.LoginDTO) (int, responseBody) {
+	user, err := s.users.FindByEmail(ctx, credentials.Email)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	// Compare runs even when the user or its password is missing, so the
+	// response time does not reveal which emails are registered.
+	if !s.hasher.Compare(user.PasswordHash, credentials.Password) || user.Id == 0 {
+		return fiber.StatusUnauthorized, responseBody{"message": INVALID_CREDENTIALS_MESSAGE}
+	}
+
+	if s.options.RequireVerifiedEmail && user.EmailVerifiedAt == nil {
+		return fiber.StatusForbidden, responseBody{"message": "email not verified"}
+	}
+
+	credential, err := s.mfa.FindTOTPByUser(ctx, user.Id)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if credential.ConfirmedAt != nil {
+		mfaToken, err := s.issuer.IssuePurposeToken(MFA_LOGIN_PURPOSE, user.ExternalId, uuid.New(), MFA_LOGIN_TTL)
+
+		if err != nil {
+			return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+		}
+
+		return fiber.StatusOK, responseBody{"mfa_required": true, "mfa_token": mfaToken}
+	}
+
+	return s.issueTokens(ctx, user, uuid.New(), false)
+}
+
+// LoginMFA is the second step of a login for users with TOTP enabled. The
+// code is either the current TOTP code or one of the recovery codes.
+func (s *UserAuthService) LoginMFA(ctx context.Context, body dto.MFALoginDTO) (int, responseBody) {
+	subject, tokenId, err := s.issuer.ParsePurposeToken(MFA_LOGIN_PURPOSE, body.MFAToken)
+
+	if err != nil {
+		return fiber.StatusUnauthorized, responseBody{"message": INVALID_MFA_CODE_MESSAGE}
+	}
+
+	status, errBody := s.limitMFALogin(ctx, subject, tokenId)
+
+	if errBody != nil {
+		return status, errBody
+	}
+
+	user, err := s.users.FindByExternalId(ctx, subject)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if user.Id == 0 {
+		return fiber.StatusUnauthorized, responseBody{"message": INVALID_MFA_CODE_MESSAGE}
+	}
+
+	credential, err := s.mfa.FindTOTPByUser(ctx, user.Id)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if credential.ConfirmedAt == nil {
+		return fiber.StatusUnauthorized, responseBody{"message": INVALID_MFA_CODE_MESSAGE}
+	}
+
+	var accepted bool
+
+	if step, ok := auth.ValidateTOTP(credential.Secret, body.Code, time.Now()); ok {
+		accepted, err = s.mfa.UseTOTPStep(ctx, credential, step)
+	} else {
+		accepted, err = s.mfa.UseRecoveryCode(ctx, user.Id, auth.HashRecoveryCode(body.Code))
+	}
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if !accepted {
+		return fiber.StatusUnauthorized, responseBody{"message": INVALID_MFA_CODE_MESSAGE}
+	}
+
+	return s.issueTokens(ctx, user, uuid.New(), true)
+}
+
+// limitMFALogin throttles the guesses at the codes of a user, and caps the
+// attempts of each mfa token. The bucket of a token refills less than one
+// attempt before the token expires, so once it is spent the token is useless.
+func (s *UserAuthService) limitMFALogin(ctx context.Context, subject uuid.UUID, tokenId uuid.UUID) (int, responseBody) {
+	result, err := s.limiter.Take(ctx, "mfa_login:user:"+subject.String(), s.options.MFALimit)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if !result.Allowed {
+		return fiber.StatusTooManyRequests, responseBody{"message": TOO_MANY_REQUESTS_MESSAGE, "retry_after": math.Ceil(result.RetryAfter.Seconds())}
+	}
+
+	result, err = s.limiter.Take(ctx, "mfa_login:token:"+tokenId.String(), ratelimit.Limit{Requests: 1, Period: MFA_LOGIN_TTL, Burst: MFA_LOGIN_ATTEMPTS})
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if !result.Allowed {
+		return fiber.StatusUnauthorized, responseBody{"message": MFA_TOKEN_EXHAUSTED_MESSAGE}
+	}
+
+	return 0, nil
+}
+
+// Refresh rotates the refresh token: the presented token is consumed and a
+// new one from the same family is returned. Presenting a token that was
+// already rotated means it leaked, so the whole family is revoked.
+func (s *UserAuthService) Refresh(ctx context.Context, body dto.RefreshTokenDTO) (int, responseBody) {
+	token, err := s.tokens.FindByHash(ctx, auth.HashOpaqueToken(body.RefreshToken))
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if token.Id == 0 || token.RevokedAt != nil || time.Now().After(token.ExpiresAt) {
+		return fiber.StatusUnauthorized, responseBody{"message": INVALID_REFRESH_TOKEN_MESSAGE}
+	}
+
+	rotated, err := s.tokens.MarkRotated(ctx, token.Id)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if !rotated {
+		err = s.tokens.RevokeFamily(ctx, token.FamilyId)
+
+		if err != nil {
+			return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+		}
+
+		return fiber.StatusUnauthorized, responseBody{"message": INVALID_REFRESH_TOKEN_MESSAGE}
+	}
+
+	user, err := s.users.FindById(ctx, token.UserId)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if user.Id == 0 {
+		return fiber.StatusUnauthorized, responseBody{"message": INVALID_REFRESH_TOKEN_MESSAGE}
+	}
+
+	return s.issueTokens(ctx, user, token.FamilyId, token.MFA)
+}
+
+func (s *UserAuthService) Logout(ctx context.Context, body dto.RefreshTokenDTO) (int, responseBody) {
+	token, err := s.tokens.FindByHash(ctx, auth.HashOpaqueToken(body.RefreshToken))
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if token.Id == 0 {
+		return fiber.StatusUnauthorized, responseBody{"message": INVALID_REFRESH_TOKEN_MESSAGE}
+	}
+
+	err = s.tokens.RevokeFamily(ctx, token.FamilyId)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	return fiber.StatusOK, responseBody{"message": "successfully logged out"}
+}
+
+func (s *UserAuthService) RevokeAllSessions(ctx context.Context, externalId uuid.UUID) (int, responseBody) {
+	user, err := s.users.FindByExternalId(ctx, externalId)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if user.Id == 0 {
+		return fiber.StatusNotFound, responseBody{"message": "user not found"}
+	}
+
+	err = s.tokens.RevokeAllForUser(ctx, user.Id)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	return fiber.StatusOK, responseBody{"message": "all sessions revoked"}
+}
+
+func (s *UserAuthService) issueTokens(ctx context.Context, user *model.User, familyId uuid.UUID, mfa bool) (int, responseBody) {
+	accessToken, err := s.issuer.IssueAccessToken(user.ExternalId, user.Role, mfa)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	refreshToken, refreshHash, err := auth.NewOpaqueToken()
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	now := time.Now()
+
+	err = s.tokens.Insert(ctx, &model.RefreshToken{
+		UserId:    user.Id,
+		FamilyId:  familyId,
+		TokenHash: refreshHash,
+		MFA:       mfa,
+		ExpiresAt: now.Add(s.options.RefreshTokenTTL),
+		CreatedAt: now,
+	})
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	return fiber.StatusOK, responseBody{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"token_type":    "Bearer",
+		"expires_in":    int(s.issuer.AccessTokenTTL().Seconds()),
+	}
+}
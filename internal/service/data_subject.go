@@ -0,0 +1,318 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/audit"
+	"github.com/LucasAndFlores/user_api/internal/dto"
+	"github.com/LucasAndFlores/user_api/internal/events"
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/LucasAndFlores/user_api/internal/repository"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const USER_ERASED_MESSAGE = "the data of the user was erased"
+
+// errUserModified rolls a change back, such as an erasure or a password
+// reset, when the user changed since it was read.
+var errUserModified = errors.New("the user was modified in the meantime")
+
+// ALL_ROWS is the limit of the repositories to read every row.
+const ALL_ROWS = -1
+
+type DataSubjectService interface {
+	Export(context.Context, uuid.UUID) (int, responseBody)
+	Erase(context.Context, uuid.UUID) (int, responseBody)
+}
+
+type GDPRService struct {
+	users      repository.Repository
+	subjects   repository.SubjectRepository
+	versions   repository.VersionRepository
+	audits     repository.AuditRepository
+	outbox     repository.OutboxRepository
+	webhooks   repository.WebhookRepository
+	keys       repository.IdempotencyRepository
+	transactor repository.Transactor
+}
+
+func NewDataSubjectService(u repository.Repository, s repository.SubjectRepository, v repository.VersionRepository, a repository.AuditRepository, o repository.OutboxRepository, w repository.WebhookRepository, k repository.IdempotencyRepository, t repository.Transactor) DataSubjectService {
+	return &GDPRService{
+		users:      u,
+		subjects:   s,
+		versions:   v,
+		audits:     a,
+		outbox:     o,
+		webhooks:   w,
+		keys:       k,
+		transactor: t,
+	}
+}
+
+// Export gathers everything stored about the user, including after it was
+// deleted, until its data is erased.
+func (s *GDPRService) Export(ctx context.Context, externalId uuid.UUID) (int, responseBody) {
+	erased, err := s.users.IsErased(ctx, externalId)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if erased {
+		return fiber.StatusGone, responseBody{"message": USER_ERASED_MESSAGE}
+	}
+
+	found, err := s.users.FindByExternalId(ctx, externalId)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	versions, err := s.versions.List(ctx, externalId, 0, ALL_ROWS)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	records, err := s.audits.FindByUserExternalId(ctx, externalId, 0, ALL_ROWS)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if found.Id == 0 && len(versions) == 0 && len(records) == 0 {
+		return fiber.StatusNotFound, responseBody{"message": USER_NOT_FOUND_MESSAGE}
+	}
+
+	export := dto.DataSubjectExportDTO{
+		ExportedAt:         time.Now(),
+		Versions:           make([]dto.UserVersionDTO, len(versions)),
+		AuditRecords:       make([]dto.AuditRecordDTO, len(records)),
+		Sessions:           []dto.ExportedSessionDTO{},
+		EmailVerifications: []dto.ExportedTokenDTO{},
+		PasswordResets:     []dto.ExportedTokenDTO{},
+	}
+
+	for i := range versions {
+		export.Versions[i].ConvertToUserVersionDTO(&versions[i])
+	}
+
+	for i := range records {
+		err = export.AuditRecords[i].ConvertToAuditRecordDTO(&records[i])
+
+		if err != nil {
+			return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+		}
+	}
+
+	if found.Id != 0 {
+		export.User = &dto.ExportedUserDTO{}
+		export.User.ConvertToExportedUserDTO(found)
+
+		err = s.exportRelated(ctx, found, &export)
+
+		if err != nil {
+			return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+		}
+	}
+
+	err = s.exportEvents(ctx, externalId, &export)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	return fiber.StatusOK, responseBody{"export": export}
+}
+
+func (s *GDPRService) exportRelated(ctx context.Context, user *model.User, export *dto.DataSubjectExportDTO) error {
+	related, err := s.subjects.FindRelated(ctx, user.Id)
+
+	if err != nil {
+		return err
+	}
+
+	for i := range related.RefreshTokens {
+		var session dto.ExportedSessionDTO
+
+		session.ConvertToExportedSessionDTO(&related.RefreshTokens[i])
+
+		export.Sessions = append(export.Sessions, session)
+	}
+
+	for _, verification := range related.EmailVerifications {
+		export.EmailVerifications = append(export.EmailVerifications, dto.ExportedTokenDTO{CreatedAt: verification.CreatedAt, ExpiresAt: verification.ExpiresAt, UsedAt: verification.UsedAt})
+	}
+
+	for _, reset := range related.PasswordResets {
+		export.PasswordResets = append(export.PasswordResets, dto.ExportedTokenDTO{CreatedAt: reset.CreatedAt, ExpiresAt: reset.ExpiresAt, UsedAt: reset.UsedAt})
+	}
+
+	export.MFA.Enrolled = related.TOTPCredential.Id != 0
+	export.MFA.ConfirmedAt = related.TOTPCredential.ConfirmedAt
+	export.MFA.RecoveryCodes = len(related.RecoveryCodes)
+
+	for _, code := range related.RecoveryCodes {
+		if code.UsedAt != nil {
+			export.MFA.RecoveryCodesUsed++
+		}
+	}
+
+	return nil
+}
+
+func (s *GDPRService) exportEvents(ctx context.Context, externalId uuid.UUID, export *dto.DataSubjectExportDTO) error {
+	found, err := s.outbox.FindByAggregate(ctx, externalId)
+
+	if err != nil {
+		return err
+	}
+
+	export.Events = make([]dto.ExportedEventDTO, len(found))
+
+	for i := range found {
+		found[i].Payload, err = events.OpenUserPayload(found[i].Payload)
+
+		if err != nil {
+			return err
+		}
+
+		err = export.Events[i].ConvertToExportedEventDTO(&found[i])
+
+		if err != nil {
+			return err
+		}
+	}
+
+	shared, err := s.webhooks.FindSharedEvents(ctx, externalId)
+
+	if err != nil {
+		return err
+	}
+
+	export.SharedEvents = make([]dto.SharedEventDTO, len(shared))
+
+	for i, event := range shared {
+		export.SharedEvents[i] = dto.SharedEventDTO{EventId: event.EventId, EventType: event.EventType, URL: event.URL, Status: event.Status, CreatedAt: event.CreatedAt}
+	}
+
+	return nil
+}
+
+// Erase deletes the user, with its credentials, sessions, versions and the
+// stored responses of idempotent requests about it, and removes its data
+// from the audit records, the outbox and the webhook deliveries, which only
+// keep its id. A tombstone keeps the id from being
+// registered again. Erasing the data of an erased user succeeds again.
+func (s *GDPRService) Erase(ctx context.Context, externalId uuid.UUID) (int, responseBody) {
+	erased, err := s.users.IsErased(ctx, externalId)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if erased {
+		return fiber.StatusOK, responseBody{"message": "user successfully erased"}
+	}
+
+	found, err := s.users.FindByExternalId(ctx, externalId)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	versions, err := s.versions.List(ctx, externalId, 0, 1)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if found.Id == 0 && len(versions) == 0 {
+		return fiber.StatusNotFound, responseBody{"message": USER_NOT_FOUND_MESSAGE}
+	}
+
+	// the events keep the id only, like the ones of deletions
+	payload, err := json.Marshal(events.UserPayload{Id: externalId.String()})
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	err = s.transactor.Transaction(ctx, func(ctx context.Context) error {
+		err := s.outbox.ReplacePayloads(ctx, externalId, payload)
+
+		if err != nil {
+			return err
+		}
+
+		err = s.webhooks.ReplaceDeliveredPayloads(ctx, externalId, payload)
+
+		if err != nil {
+			return err
+		}
+
+		if found.Id != 0 {
+			deleted, err := s.users.Delete(ctx, found)
+
+			if err != nil {
+				return err
+			}
+
+			if !deleted {
+				return errUserModified
+			}
+
+			event, err := events.NewUserEvent(events.USER_DELETED, found)
+
+			if err != nil {
+				return err
+			}
+
+			err = s.outbox.Insert(ctx, event)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		err = s.versions.DeleteAll(ctx, externalId)
+
+		if err != nil {
+			return err
+		}
+
+		err = s.keys.DeleteForUser(ctx, externalId)
+
+		if err != nil {
+			return err
+		}
+
+		err = s.audits.Redact(ctx, externalId, audit.ERASED)
+
+		if err != nil {
+			return err
+		}
+
+		err = s.audits.Append(ctx, audit.NewErasureRecord(ctx, externalId))
+
+		if err != nil {
+			return err
+		}
+
+		return s.subjects.InsertTombstone(ctx, &model.ErasedUser{ExternalId: externalId, ErasedAt: time.Now()})
+	})
+
+	if errors.Is(err, errUserModified) {
+		return fiber.StatusConflict, responseBody{"message": "the user was modified during the erasure, retry"}
+	}
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	return fiber.StatusOK, responseBody{"message": "user successfully erased"}
+}
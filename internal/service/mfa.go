@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/auth"
+	"github.com/LucasAndFlores/user_api/internal/dto"
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/LucasAndFlores/user_api/internal/repository"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const RECOVERY_CODES_COUNT = 10
+
+type MFAService interface {
+	EnrollTOTP(context.Context, uuid.UUID) (int, responseBody)
+	ConfirmTOTP(context.Context, uuid.UUID, dto.TOTPCodeDTO) (int, responseBody)
+}
+
+type TOTPService struct {
+	users  repository.Repository
+	mfa    repository.MFARepository
+	issuer string
+}
+
+func NewMFAService(u repository.Repository, m repository.MFARepository, issuer string) MFAService {
+	return &TOTPService{users: u, mfa: m, issuer: issuer}
+}
+
+// EnrollTOTP starts a new enrollment. It stays pending, and the login keeps
+// asking for the password only, until ConfirmTOTP receives a valid code.
+func (s *TOTPService) EnrollTOTP(ctx context.Context, externalId uuid.UUID) (int, responseBody) {
+	user, credential, status, body := s.findUserCredential(ctx, externalId)
+
+	if body != nil {
+		return status, body
+	}
+
+	if credential.ConfirmedAt != nil {
+		return fiber.StatusConflict, responseBody{"message": "totp already enabled"}
+	}
+
+	secret, err := auth.NewTOTPSecret()
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	err = s.mfa.ReplaceUnconfirmedTOTP(ctx, &model.TOTPCredential{
+		UserId:    user.Id,
+		Secret:    secret,
+		CreatedAt: time.Now(),
+	})
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	return fiber.StatusCreated, responseBody{
+		"secret":           secret,
+		"provisioning_uri": auth.TOTPProvisioningURI(s.issuer, user.Email, secret),
+	}
+}
+
+// ConfirmTOTP enables the pending enrollment and returns the recovery codes.
+// They are shown only once; only their hashes are stored.
+func (s *TOTPService) ConfirmTOTP(ctx context.Context, externalId uuid.UUID, body dto.TOTPCodeDTO) (int, responseBody) {
+	_, credential, status, errBody := s.findUserCredential(ctx, externalId)
+
+	if errBody != nil {
+		return status, errBody
+	}
+
+	if credential.Id == 0 {
+		return fiber.StatusNotFound, responseBody{"message": "no pending totp enrollment"}
+	}
+
+	if credential.ConfirmedAt != nil {
+		return fiber.StatusConflict, responseBody{"message": "totp already enabled"}
+	}
+
+	step, ok := auth.ValidateTOTP(credential.Secret, body.Code, time.Now())
+
+	if !ok {
+		return fiber.StatusUnprocessableEntity, responseBody{"message": "invalid totp code"}
+	}
+
+	codes, err := auth.NewRecoveryCodes(RECOVERY_CODES_COUNT)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	hashes := make([]string, len(codes))
+
+	for i, code := range codes {
+		hashes[i] = auth.HashRecoveryCode(code)
+	}
+
+	confirmed, err := s.mfa.ConfirmTOTP(ctx, credential, step, hashes)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if !confirmed {
+		return fiber.StatusConflict, responseBody{"message": "totp already enabled"}
+	}
+
+	return fiber.StatusOK, responseBody{"recovery_codes": codes}
+}
+
+func (s *TOTPService) findUserCredential(ctx context.Context, externalId uuid.UUID) (*model.User, *model.TOTPCredential, int, responseBody) {
+	user, err := s.users.FindByExternalId(ctx, externalId)
+
+	if err != nil {
+		return nil, nil, fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if user.Id == 0 {
+		return nil, nil, fiber.StatusNotFound, responseBody{"message": "user not found"}
+	}
+
+	credential, err := s.mfa.FindTOTPByUser(ctx, user.Id)
+
+	if err != nil {
+		return nil, nil, fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	return user, credential, 0, nil
+}
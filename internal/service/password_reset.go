@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/auth"
+	"github.com/LucasAndFlores/user_api/internal/dto"
+	"github.com/LucasAndFlores/user_api/internal/mail"
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/LucasAndFlores/user_api/internal/ratelimit"
+	"github.com/LucasAndFlores/user_api/internal/repository"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	PASSWORD_RESET_PURPOSE = "password_reset"
+
+	INVALID_RESET_TOKEN_MESSAGE = "invalid or expired reset token"
+	RESET_REQUESTED_MESSAGE     = "if the email is registered, a password reset email was sent"
+	TOO_MANY_REQUESTS_MESSAGE   = "too many requests"
+)
+
+type PasswordResetService interface {
+	Forgot(context.Context, dto.EmailDTO, string) (int, responseBody)
+	Reset(context.Context, dto.ResetPasswordDTO) (int, responseBody)
+}
+
+type PasswordResetOptions struct {
+	BaseURL    string
+	TTL        time.Duration
+	EmailLimit ratelimit.Limit
+	IPLimit    ratelimit.Limit
+}
+
+type UserPasswordResetService struct {
+	tracker
+	users      repository.Repository
+	resets     repository.ResetRepository
+	transactor repository.Transactor
+	hasher     auth.PasswordHasher
+	issuer     *auth.TokenIssuer
+	mailer     mail.Mailer
+	limiter    ratelimit.Store
+	options    PasswordResetOptions
+}
+
+func NewPasswordResetService(u repository.Repository, r repository.ResetRepository, ob repository.OutboxRepository, a repository.AuditRepository, vr repository.VersionRepository, t repository.Transactor, h auth.PasswordHasher, i *auth.TokenIssuer, m mail.Mailer, l ratelimit.Store, o PasswordResetOptions) PasswordResetService {
+	return &UserPasswordResetService{
+		tracker:    tracker{outbox: ob, audits: a, versions: vr},
+		users:      u,
+		resets:     r,
+		transactor: t,
+		hasher:     h,
+		issuer:     i,
+		mailer:     m,
+		limiter:    l,
+		options:    o,
+	}
+}
+
+// Forgot never reveals whether the email is registered: the limits are
+// applied to any email, and the answer is the same, and as fast, for unknown
+// ones.
+func (s *UserPasswordResetService) Forgot(ctx context.Context, body dto.EmailDTO, ip string) (int, responseBody) {
+	limits := []struct {
+		key   string
+		limit ratelimit.Limit
+	}{
+		{key: "password_reset:ip:" + ip, limit: s.options.IPLimit},
+		{key: "password_reset:email:" + strings.ToLower(body.Email), limit: s.options.EmailLimit},
+	}
+
+	for _, l := range limits {
+		result, err := s.limiter.Take(ctx, l.key, l.limit)
+
+		if err != nil {
+			return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+		}
+
+		if !result.Allowed {
+			return fiber.StatusTooManyRequests, responseBody{"message": TOO_MANY_REQUESTS_MESSAGE, "retry_after": math.Ceil(result.RetryAfter.Seconds())}
+		}
+	}
+
+	user, err := s.users.FindByEmail(ctx, body.Email)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	// the reset is stored and mailed after answering, or the time taken
+	// would tell the registered emails apart
+	if user.Id != 0 {
+		go func() {
+			err := s.sendReset(context.WithoutCancel(ctx), user)
+
+			if err != nil {
+				log.Printf("An error occurred when tried to send the password reset email: %v", err)
+			}
+		}()
+	}
+
+	return fiber.StatusAccepted, responseBody{"message": RESET_REQUESTED_MESSAGE}
+}
+
+func (s *UserPasswordResetService) sendReset(ctx context.Context, user *model.User) error {
+	now := time.Now()
+
+	reset := model.PasswordReset{
+		UserId:    user.Id,
+		TokenId:   uuid.New(),
+		ExpiresAt: now.Add(s.options.TTL),
+		CreatedAt: now,
+	}
+
+	token, err := s.issuer.IssuePurposeToken(PASSWORD_RESET_PURPOSE, user.ExternalId, reset.TokenId, s.options.TTL)
+
+	if err != nil {
+		return err
+	}
+
+	err = s.resets.Insert(ctx, &reset)
+
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", s.options.BaseURL, url.QueryEscape(token))
+
+	return s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body:    fmt.Sprintf("Hello %s,\n\nreset your password by opening the link below:\n\n%s\n\nThe link expires in %s. If you didn't ask for it, ignore this email.", user.Name, link, s.options.TTL),
+	})
+}
+
+// Reset stores the new password and revokes every session of the user.
+func (s *UserPasswordResetService) Reset(ctx context.Context, body dto.ResetPasswordDTO) (int, responseBody) {
+	subject, tokenId, err := s.issuer.ParsePurposeToken(PASSWORD_RESET_PURPOSE, body.Token)
+
+	if err != nil {
+		return fiber.StatusBadRequest, responseBody{"message": INVALID_RESET_TOKEN_MESSAGE}
+	}
+
+	reset, err := s.resets.FindByTokenId(ctx, tokenId)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if reset.Id == 0 || reset.UsedAt != nil || time.Now().After(reset.ExpiresAt) {
+		return fiber.StatusBadRequest, responseBody{"message": INVALID_RESET_TOKEN_MESSAGE}
+	}
+
+	user, err := s.users.FindById(ctx, reset.UserId)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if user.Id == 0 || user.ExternalId != subject {
+		return fiber.StatusBadRequest, responseBody{"message": INVALID_RESET_TOKEN_MESSAGE}
+	}
+
+	passwordHash, err := s.hasher.Hash(body.Password)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	changed := *user
+
+	changed.PasswordHash = passwordHash
+
+	consumed := false
+
+	err = s.transactor.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+
+		consumed, err = s.resets.Consume(ctx, reset)
+
+		if err != nil || !consumed {
+			return err
+		}
+
+		updated, err := s.users.UpdateColumns(ctx, &changed, "password_hash")
+
+		if err != nil {
+			return err
+		}
+
+		if !updated {
+			return errUserModified
+		}
+
+		return s.track(ctx, model.AUDIT_ACTION_UPDATE, user, &changed)
+	})
+
+	if errors.Is(err, errUserModified) {
+		return fiber.StatusConflict, responseBody{"message": "the user was modified during the reset, retry"}
+	}
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if !consumed {
+		return fiber.StatusBadRequest, responseBody{"message": INVALID_RESET_TOKEN_MESSAGE}
+	}
+
+	return fiber.StatusOK, responseBody{"message": "password successfully reset"}
+}
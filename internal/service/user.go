@@ -2,8 +2,17 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
+	"log"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/LucasAndFlores/user_api/internal/audit"
+	"github.com/LucasAndFlores/user_api/internal/auth"
 	"github.com/LucasAndFlores/user_api/internal/dto"
+	"github.com/LucasAndFlores/user_api/internal/events"
+	"github.com/LucasAndFlores/user_api/internal/model"
 	"github.com/LucasAndFlores/user_api/internal/repository"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -13,17 +22,40 @@ type responseBody = map[string]interface{}
 
 const INTERNAL_SERVER_ERROR_MESSAGE = "internal server error"
 
+const (
+	DEFAULT_PAGE_SIZE = 50
+	MAX_PAGE_SIZE     = 100
+)
+
+const (
+	INVALID_PAGE_MESSAGE        = "invalid page size or cursor"
+	USER_NOT_FOUND_MESSAGE      = "user not found"
+	VERSION_NOT_FOUND_MESSAGE   = "version not found"
+	PRECONDITION_FAILED_MESSAGE = "the user was modified, fetch it again and retry"
+)
+
 type Service interface {
 	Create(context.Context, dto.UserDTO) (int, responseBody)
-	FindUserByExternalId(context.Context, uuid.UUID) (int, responseBody)
+	FindUserByExternalId(context.Context, uuid.UUID, dto.UserFields) (int, responseBody)
+	Update(context.Context, uuid.UUID, dto.UpdateUserDTO, *int) (int, responseBody)
+	Delete(context.Context, uuid.UUID, *int) (int, responseBody)
+	FindUsersByExternalIds(context.Context, []uuid.UUID) (int, responseBody)
+	List(ctx context.Context, filter dto.UserFilter, fields dto.UserFields, pageSize int, cursor string) (int, responseBody)
+	FindUserAsOf(ctx context.Context, externalId uuid.UUID, at time.Time) (int, responseBody)
+	Versions(ctx context.Context, externalId uuid.UUID, pageSize int, cursor string) (int, responseBody)
+	Revert(ctx context.Context, externalId uuid.UUID, version int, expectedVersion *int) (int, responseBody)
 }
 
 type UserService struct {
-	repo repository.Repository
+	tracker
+	repo       repository.Repository
+	transactor repository.Transactor
+	hasher     auth.PasswordHasher
+	verifier   VerificationService
 }
 
-func NewUserService(r repository.Repository) Service {
-	return &UserService{repo: r}
+func NewUserService(r repository.Repository, o repository.OutboxRepository, a repository.AuditRepository, vr repository.VersionRepository, t repository.Transactor, h auth.PasswordHasher, v VerificationService) Service {
+	return &UserService{tracker: tracker{outbox: o, audits: a, versions: vr}, repo: r, transactor: t, hasher: h, verifier: v}
 }
 
 func (s *UserService) Create(ctx context.Context, user dto.UserDTO) (int, responseBody) {
@@ -43,17 +75,74 @@ func (s *UserService) Create(ctx context.Context, user dto.UserDTO) (int, respon
 		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
 	}
 
-	err = s.repo.Insert(ctx, &userModel)
+	erased, err := s.repo.IsErased(ctx, userModel.ExternalId)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if erased {
+		return fiber.StatusConflict, responseBody{"message": USER_ERASED_MESSAGE}
+	}
+
+	if user.Password != "" {
+		userModel.PasswordHash, err = s.hasher.Hash(user.Password)
+
+		if err != nil {
+			return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+		}
+	}
+
+	err = s.transactor.Transaction(ctx, func(ctx context.Context) error {
+		err := s.repo.Insert(ctx, &userModel)
+
+		if err != nil {
+			return err
+		}
+
+		return s.track(ctx, model.AUDIT_ACTION_CREATE, nil, &userModel)
+	})
 
 	if err != nil {
 		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
 	}
 
+	// the user can ask for a new email later, so a delivery failure must not
+	// fail the creation
+	err = s.verifier.SendVerification(ctx, &userModel)
+
+	if err != nil {
+		log.Printf("An error occurred when tried to send the verification email: %v", err)
+	}
+
 	return fiber.StatusCreated, responseBody{"message": "user successfully created"}
 
 }
 
-func (s *UserService) FindUserByExternalId(ctx context.Context, externalId uuid.UUID) (int, responseBody) {
+// FindUserByExternalId loads only the selected fields; the others are left
+// empty in the returned DTO.
+func (s *UserService) FindUserByExternalId(ctx context.Context, externalId uuid.UUID, fields dto.UserFields) (int, responseBody) {
+	found, err := s.repo.FindByExternalId(ctx, externalId, fields.Columns()...)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if found.Id == 0 {
+		return fiber.StatusNotFound, responseBody{"message": USER_NOT_FOUND_MESSAGE}
+	}
+
+	var userDTO dto.UserDTO
+
+	userDTO.ConvertToUserDTO(found)
+
+	return fiber.StatusOK, responseBody{"user": userDTO}
+}
+
+// Update replaces the profile of the user. When expectedVersion is given (from
+// an If-Match header) it must match the stored version. Either way the write
+// only succeeds if nobody changed the user since it was read.
+func (s *UserService) Update(ctx context.Context, externalId uuid.UUID, body dto.UpdateUserDTO, expectedVersion *int) (int, responseBody) {
 	found, err := s.repo.FindByExternalId(ctx, externalId)
 
 	if err != nil {
@@ -61,7 +150,65 @@ func (s *UserService) FindUserByExternalId(ctx context.Context, externalId uuid.
 	}
 
 	if found.Id == 0 {
-		return fiber.StatusNotFound, responseBody{"message": "user not found"}
+		return fiber.StatusNotFound, responseBody{"message": USER_NOT_FOUND_MESSAGE}
+	}
+
+	if expectedVersion != nil && *expectedVersion != found.Version {
+		return fiber.StatusPreconditionFailed, responseBody{"message": PRECONDITION_FAILED_MESSAGE}
+	}
+
+	emailChanged := !strings.EqualFold(found.Email, body.Email)
+
+	if emailChanged {
+		owner, err := s.repo.FindByEmail(ctx, body.Email)
+
+		if err != nil {
+			return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+		}
+
+		if owner.Id != 0 && owner.Id != found.Id {
+			return fiber.StatusConflict, responseBody{"message": "user already exists"}
+		}
+
+		found.EmailVerifiedAt = nil
+	}
+
+	before := *found
+
+	err = body.ApplyTo(found)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	updated := false
+
+	err = s.transactor.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+
+		updated, err = s.repo.Update(ctx, found)
+
+		if err != nil || !updated {
+			return err
+		}
+
+		return s.track(ctx, model.AUDIT_ACTION_UPDATE, &before, found)
+	})
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if !updated {
+		return fiber.StatusPreconditionFailed, responseBody{"message": PRECONDITION_FAILED_MESSAGE}
+	}
+
+	if emailChanged {
+		err = s.verifier.SendVerification(ctx, found)
+
+		if err != nil {
+			log.Printf("An error occurred when tried to send the verification email: %v", err)
+		}
 	}
 
 	var userDTO dto.UserDTO
@@ -70,3 +217,291 @@ func (s *UserService) FindUserByExternalId(ctx context.Context, externalId uuid.
 
 	return fiber.StatusOK, responseBody{"user": userDTO}
 }
+
+func (s *UserService) Delete(ctx context.Context, externalId uuid.UUID, expectedVersion *int) (int, responseBody) {
+	found, err := s.repo.FindByExternalId(ctx, externalId)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if found.Id == 0 {
+		return fiber.StatusNotFound, responseBody{"message": USER_NOT_FOUND_MESSAGE}
+	}
+
+	if expectedVersion != nil && *expectedVersion != found.Version {
+		return fiber.StatusPreconditionFailed, responseBody{"message": PRECONDITION_FAILED_MESSAGE}
+	}
+
+	deleted := false
+
+	err = s.transactor.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+
+		deleted, err = s.repo.Delete(ctx, found)
+
+		if err != nil || !deleted {
+			return err
+		}
+
+		return s.track(ctx, model.AUDIT_ACTION_DELETE, found, nil)
+	})
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if !deleted {
+		return fiber.StatusPreconditionFailed, responseBody{"message": PRECONDITION_FAILED_MESSAGE}
+	}
+
+	return fiber.StatusOK, responseBody{"message": "user successfully deleted"}
+}
+
+// userEvents are the events of the outbox for each action.
+var userEvents = map[string]string{
+	model.AUDIT_ACTION_CREATE: events.USER_CREATED,
+	model.AUDIT_ACTION_UPDATE: events.USER_UPDATED,
+	model.AUDIT_ACTION_DELETE: events.USER_DELETED,
+}
+
+// tracker stores what follows a change of a user. Every change of the users
+// table goes through it, so the audit trail, the versions and the events
+// miss none.
+type tracker struct {
+	outbox   repository.OutboxRepository
+	audits   repository.AuditRepository
+	versions repository.VersionRepository
+}
+
+// track stores what follows a change of a user: its audit record, the new
+// version of the user and its event in the outbox. It must be called in the
+// transaction of the change, so all of them are committed or none is.
+func (s *tracker) track(ctx context.Context, action string, before *model.User, after *model.User) error {
+	record, err := audit.NewRecord(ctx, action, before, after)
+
+	if err != nil {
+		return err
+	}
+
+	err = s.audits.Append(ctx, record)
+
+	if err != nil {
+		return err
+	}
+
+	err = s.versions.Insert(ctx, snapshot(before, after))
+
+	if err != nil {
+		return err
+	}
+
+	user := after
+
+	if user == nil {
+		user = before
+	}
+
+	event, err := events.NewUserEvent(userEvents[action], user)
+
+	if err != nil {
+		return err
+	}
+
+	return s.outbox.Insert(ctx, event)
+}
+
+// snapshot is the version of the user after a change. A deletion, which
+// leaves no user, is recorded as the version following the last one.
+func snapshot(before *model.User, after *model.User) *model.UserVersion {
+	user, deleted := after, false
+
+	if user == nil {
+		user, deleted = before, true
+	}
+
+	version := &model.UserVersion{
+		UserExternalId:  user.ExternalId,
+		Version:         user.Version,
+		Name:            user.Name,
+		Email:           user.Email,
+		DateOfBirth:     user.DateOfBirth,
+		Role:            user.Role,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		Deleted:         deleted,
+		RecordedAt:      time.Now(),
+	}
+
+	if deleted {
+		version.Version++
+	}
+
+	return version
+}
+
+// FindUsersByExternalIds returns the users found among externalIds, in no
+// particular order.
+func (s *UserService) FindUsersByExternalIds(ctx context.Context, externalIds []uuid.UUID) (int, responseBody) {
+	found, err := s.repo.FindByExternalIds(ctx, externalIds)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	users := make([]dto.UserDTO, len(found))
+
+	for i := range found {
+		users[i].ConvertToUserDTO(&found[i])
+	}
+
+	return fiber.StatusOK, responseBody{"users": users}
+}
+
+// List pages through the users matching the filter, loading only the
+// selected fields like FindUserByExternalId. The cursor is the
+// next_cursor of the previous page, empty for the first one; a pageSize of 0
+// means DEFAULT_PAGE_SIZE. The response also has the cursor of every user,
+// for clients resuming after any of them.
+func (s *UserService) List(ctx context.Context, filter dto.UserFilter, fields dto.UserFields, pageSize int, cursor string) (int, responseBody) {
+	if pageSize == 0 {
+		pageSize = DEFAULT_PAGE_SIZE
+	}
+
+	afterId, err := decodeCursor(cursor)
+
+	if err != nil || pageSize < 0 || pageSize > MAX_PAGE_SIZE {
+		return fiber.StatusBadRequest, responseBody{"message": INVALID_PAGE_MESSAGE}
+	}
+
+	// one more user than asked tells whether there is a next page
+	found, err := s.repo.List(ctx, filter, afterId, pageSize+1, fields.Columns()...)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	nextCursor := ""
+
+	if len(found) > pageSize {
+		found = found[:pageSize]
+		nextCursor = encodeCursor(found[pageSize-1].Id)
+	}
+
+	users := make([]dto.UserDTO, len(found))
+	cursors := make([]string, len(found))
+
+	for i := range found {
+		users[i].ConvertToUserDTO(&found[i])
+		cursors[i] = encodeCursor(found[i].Id)
+	}
+
+	return fiber.StatusOK, responseBody{"users": users, "cursors": cursors, "next_cursor": nextCursor}
+}
+
+// FindUserAsOf returns the user as it was at the given time, from its
+// versions, even when it was deleted since.
+func (s *UserService) FindUserAsOf(ctx context.Context, externalId uuid.UUID, at time.Time) (int, responseBody) {
+	found, err := s.versions.FindAsOf(ctx, externalId, at)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if found.Id == 0 || found.Deleted {
+		return fiber.StatusNotFound, responseBody{"message": USER_NOT_FOUND_MESSAGE}
+	}
+
+	// the user was created along with its first version
+	first, err := s.versions.List(ctx, externalId, 0, 1)
+
+	if err != nil || len(first) == 0 {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	user := found.User()
+
+	user.CreatedAt = first[0].RecordedAt
+
+	var userDTO dto.UserDTO
+
+	userDTO.ConvertToUserDTO(user)
+
+	return fiber.StatusOK, responseBody{"user": userDTO}
+}
+
+// Versions pages through the versions of a user, the oldest first, like
+// List. The cursor is a version number.
+func (s *UserService) Versions(ctx context.Context, externalId uuid.UUID, pageSize int, cursor string) (int, responseBody) {
+	if pageSize == 0 {
+		pageSize = DEFAULT_PAGE_SIZE
+	}
+
+	afterVersion, err := decodeCursor(cursor)
+
+	if err != nil || pageSize < 0 || pageSize > MAX_PAGE_SIZE {
+		return fiber.StatusBadRequest, responseBody{"message": INVALID_PAGE_MESSAGE}
+	}
+
+	found, err := s.versions.List(ctx, externalId, afterVersion, pageSize+1)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if len(found) == 0 && afterVersion == 0 {
+		return fiber.StatusNotFound, responseBody{"message": USER_NOT_FOUND_MESSAGE}
+	}
+
+	nextCursor := ""
+
+	if len(found) > pageSize {
+		found = found[:pageSize]
+		nextCursor = encodeCursor(found[pageSize-1].Version)
+	}
+
+	versions := make([]dto.UserVersionDTO, len(found))
+
+	for i := range found {
+		versions[i].ConvertToUserVersionDTO(&found[i])
+	}
+
+	return fiber.StatusOK, responseBody{"versions": versions, "next_cursor": nextCursor}
+}
+
+// Revert restores the profile of a past version as a new version, with the
+// same checks as an Update.
+func (s *UserService) Revert(ctx context.Context, externalId uuid.UUID, version int, expectedVersion *int) (int, responseBody) {
+	found, err := s.versions.FindVersion(ctx, externalId, version)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if found.Id == 0 || found.Deleted {
+		return fiber.StatusNotFound, responseBody{"message": VERSION_NOT_FOUND_MESSAGE}
+	}
+
+	return s.Update(ctx, externalId, dto.UpdateUserDTO{
+		Name:        found.Name,
+		Email:       found.Email,
+		DateOfBirth: found.DateOfBirth.String(),
+	}, expectedVersion)
+}
+
+func encodeCursor(id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(id)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(string(decoded))
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/auth"
+	"github.com/LucasAndFlores/user_api/internal/dto"
+	"github.com/LucasAndFlores/user_api/internal/mail"
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/LucasAndFlores/user_api/internal/pii"
+	"github.com/LucasAndFlores/user_api/internal/repository"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	EMAIL_VERIFICATION_PURPOSE = "email_verification"
+
+	INVALID_VERIFICATION_TOKEN_MESSAGE = "invalid or expired verification token"
+	VERIFICATION_RESENT_MESSAGE        = "if the email belongs to an unverified user, a new verification email was sent"
+)
+
+type VerificationService interface {
+	SendVerification(context.Context, *model.User) error
+	Verify(context.Context, string) (int, responseBody)
+	Resend(context.Context, dto.EmailDTO) (int, responseBody)
+}
+
+type EmailVerificationService struct {
+	tracker
+	users          repository.Repository
+	verifications  repository.VerificationRepository
+	transactor     repository.Transactor
+	issuer         *auth.TokenIssuer
+	mailer         mail.Mailer
+	baseURL        string
+	ttl            time.Duration
+	resendInterval time.Duration
+}
+
+func NewVerificationService(u repository.Repository, v repository.VerificationRepository, o repository.OutboxRepository, a repository.AuditRepository, vr repository.VersionRepository, t repository.Transactor, i *auth.TokenIssuer, m mail.Mailer, baseURL string, ttl time.Duration, resendInterval time.Duration) VerificationService {
+	return &EmailVerificationService{
+		tracker:        tracker{outbox: o, audits: a, versions: vr},
+		users:          u,
+		verifications:  v,
+		transactor:     t,
+		issuer:         i,
+		mailer:         m,
+		baseURL:        baseURL,
+		ttl:            ttl,
+		resendInterval: resendInterval,
+	}
+}
+
+func (s *EmailVerificationService) SendVerification(ctx context.Context, user *model.User) error {
+	now := time.Now()
+
+	verification := model.EmailVerification{
+		UserId:     user.Id,
+		TokenId:    uuid.New(),
+		EmailIndex: pii.BlindIndex(user.Email),
+		ExpiresAt:  now.Add(s.ttl),
+		CreatedAt:  now,
+	}
+
+	token, err := s.issuer.IssuePurposeToken(EMAIL_VERIFICATION_PURPOSE, user.ExternalId, verification.TokenId, s.ttl)
+
+	if err != nil {
+		return err
+	}
+
+	err = s.verifications.Insert(ctx, &verification)
+
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/api/v1/auth/verify-email?token=%s", s.baseURL, url.QueryEscape(token))
+
+	return s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body:    fmt.Sprintf("Hello %s,\n\nconfirm your email by opening the link below:\n\n%s\n\nThe link expires in %s.", user.Name, link, s.ttl),
+	})
+}
+
+func (s *EmailVerificationService) Verify(ctx context.Context, token string) (int, responseBody) {
+	subject, tokenId, err := s.issuer.ParsePurposeToken(EMAIL_VERIFICATION_PURPOSE, token)
+
+	if err != nil {
+		return fiber.StatusBadRequest, responseBody{"message": INVALID_VERIFICATION_TOKEN_MESSAGE}
+	}
+
+	verification, err := s.verifications.FindByTokenId(ctx, tokenId)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if verification.Id == 0 || verification.UsedAt != nil || time.Now().After(verification.ExpiresAt) {
+		return fiber.StatusBadRequest, responseBody{"message": INVALID_VERIFICATION_TOKEN_MESSAGE}
+	}
+
+	user, err := s.users.FindById(ctx, verification.UserId)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	// the link was sent to an email the user has changed since
+	if user.Id == 0 || user.ExternalId != subject || verification.EmailIndex != user.EmailIndex {
+		return fiber.StatusBadRequest, responseBody{"message": INVALID_VERIFICATION_TOKEN_MESSAGE}
+	}
+
+	consumed := false
+
+	err = s.transactor.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+
+		consumed, err = s.verifications.Consume(ctx, verification)
+
+		if err != nil || !consumed || user.EmailVerifiedAt != nil {
+			return err
+		}
+
+		verified := *user
+		now := time.Now()
+
+		verified.EmailVerifiedAt = &now
+
+		updated, err := s.users.UpdateColumns(ctx, &verified, "email_verified_at")
+
+		if err != nil {
+			return err
+		}
+
+		if !updated {
+			return errUserModified
+		}
+
+		return s.track(ctx, model.AUDIT_ACTION_UPDATE, user, &verified)
+	})
+
+	if errors.Is(err, errUserModified) {
+		return fiber.StatusConflict, responseBody{"message": "the user was modified during the verification, retry"}
+	}
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if !consumed {
+		return fiber.StatusBadRequest, responseBody{"message": INVALID_VERIFICATION_TOKEN_MESSAGE}
+	}
+
+	return fiber.StatusOK, responseBody{"message": "email successfully verified"}
+}
+
+// Resend answers the same way whether or not the email is registered, and
+// silently skips sending while the previous email is younger than the resend
+// interval.
+func (s *EmailVerificationService) Resend(ctx context.Context, body dto.EmailDTO) (int, responseBody) {
+	user, err := s.users.FindByEmail(ctx, body.Email)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if user.Id == 0 || user.EmailVerifiedAt != nil {
+		return fiber.StatusAccepted, responseBody{"message": VERIFICATION_RESENT_MESSAGE}
+	}
+
+	latest, err := s.verifications.FindLatestForUser(ctx, user.Id)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if latest.Id != 0 && time.Since(latest.CreatedAt) < s.resendInterval {
+		return fiber.StatusAccepted, responseBody{"message": VERIFICATION_RESENT_MESSAGE}
+	}
+
+	err = s.SendVerification(ctx, user)
+
+	if err != nil {
+		log.Printf("An error occurred when tried to resend the verification email: %v", err)
+	}
+
+	return fiber.StatusAccepted, responseBody{"message": VERIFICATION_RESENT_MESSAGE}
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/dto"
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/LucasAndFlores/user_api/internal/repository"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const DELIVERY_LOG_SIZE = 100
+
+const (
+	WEBHOOK_NOT_FOUND_MESSAGE  = "webhook not found"
+	DELIVERY_NOT_FOUND_MESSAGE = "delivery not found"
+)
+
+type WebhookService interface {
+	Create(context.Context, dto.WebhookDTO) (int, responseBody)
+	List(context.Context) (int, responseBody)
+	Find(context.Context, uuid.UUID) (int, responseBody)
+	Delete(context.Context, uuid.UUID) (int, responseBody)
+	Enable(context.Context, uuid.UUID) (int, responseBody)
+	Deliveries(context.Context, uuid.UUID) (int, responseBody)
+	Redeliver(ctx context.Context, webhookId uuid.UUID, deliveryId uuid.UUID) (int, responseBody)
+}
+
+type WebhookSubscriptionService struct {
+	webhooks repository.WebhookRepository
+}
+
+func NewWebhookService(w repository.WebhookRepository) WebhookService {
+	return &WebhookSubscriptionService{webhooks: w}
+}
+
+// Create subscribes the URL to the event types. The answer is the only one
+// showing the secret, which is generated when none is given.
+func (s *WebhookSubscriptionService) Create(ctx context.Context, body dto.WebhookDTO) (int, responseBody) {
+	subscription := body.ConvertToSubscriptionModel()
+
+	subscription.ExternalId = uuid.New()
+	subscription.CreatedAt = time.Now()
+
+	if subscription.Secret == "" {
+		secret := make([]byte, 32)
+
+		_, err := rand.Read(secret)
+
+		if err != nil {
+			return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+		}
+
+		subscription.Secret = hex.EncodeToString(secret)
+	}
+
+	err := s.webhooks.InsertSubscription(ctx, &subscription)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	var webhookDTO dto.WebhookResponseDTO
+
+	webhookDTO.ConvertToWebhookDTO(&subscription)
+	webhookDTO.Secret = subscription.Secret
+
+	return fiber.StatusCreated, responseBody{"webhook": webhookDTO}
+}
+
+func (s *WebhookSubscriptionService) List(ctx context.Context) (int, responseBody) {
+	found, err := s.webhooks.FindSubscriptions(ctx)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	webhooks := make([]dto.WebhookResponseDTO, len(found))
+
+	for i := range found {
+		webhooks[i].ConvertToWebhookDTO(&found[i])
+	}
+
+	return fiber.StatusOK, responseBody{"webhooks": webhooks}
+}
+
+func (s *WebhookSubscriptionService) Find(ctx context.Context, externalId uuid.UUID) (int, responseBody) {
+	subscription, status, body := s.findSubscription(ctx, externalId)
+
+	if body != nil {
+		return status, body
+	}
+
+	var webhookDTO dto.WebhookResponseDTO
+
+	webhookDTO.ConvertToWebhookDTO(subscription)
+
+	return fiber.StatusOK, responseBody{"webhook": webhookDTO}
+}
+
+func (s *WebhookSubscriptionService) Delete(ctx context.Context, externalId uuid.UUID) (int, responseBody) {
+	subscription, status, body := s.findSubscription(ctx, externalId)
+
+	if body != nil {
+		return status, body
+	}
+
+	err := s.webhooks.DeleteSubscription(ctx, subscription)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	return fiber.StatusOK, responseBody{"message": "webhook successfully deleted"}
+}
+
+// Enable turns a subscription disabled after repeated failures back on.
+// Its pending deliveries are sent again.
+func (s *WebhookSubscriptionService) Enable(ctx context.Context, externalId uuid.UUID) (int, responseBody) {
+	subscription, status, body := s.findSubscription(ctx, externalId)
+
+	if body != nil {
+		return status, body
+	}
+
+	err := s.webhooks.EnableSubscription(ctx, subscription)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	var webhookDTO dto.WebhookResponseDTO
+
+	webhookDTO.ConvertToWebhookDTO(subscription)
+
+	return fiber.StatusOK, responseBody{"webhook": webhookDTO}
+}
+
+// Deliveries returns the last DELIVERY_LOG_SIZE deliveries of the
+// subscription, the latest first.
+func (s *WebhookSubscriptionService) Deliveries(ctx context.Context, externalId uuid.UUID) (int, responseBody) {
+	subscription, status, body := s.findSubscription(ctx, externalId)
+
+	if body != nil {
+		return status, body
+	}
+
+	found, err := s.webhooks.FindDeliveries(ctx, subscription.Id, DELIVERY_LOG_SIZE)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	deliveries := make([]dto.WebhookDeliveryDTO, len(found))
+
+	for i := range found {
+		deliveries[i].ConvertToDeliveryDTO(&found[i])
+	}
+
+	return fiber.StatusOK, responseBody{"deliveries": deliveries}
+}
+
+// Redeliver queues a new delivery of the same payload, leaving the log of
+// the original one untouched.
+func (s *WebhookSubscriptionService) Redeliver(ctx context.Context, webhookId uuid.UUID, deliveryId uuid.UUID) (int, responseBody) {
+	subscription, status, body := s.findSubscription(ctx, webhookId)
+
+	if body != nil {
+		return status, body
+	}
+
+	original, err := s.webhooks.FindDeliveryByExternalId(ctx, subscription.Id, deliveryId)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if original.Id == 0 {
+		return fiber.StatusNotFound, responseBody{"message": DELIVERY_NOT_FOUND_MESSAGE}
+	}
+
+	now := time.Now()
+
+	delivery := model.WebhookDelivery{
+		ExternalId:     uuid.New(),
+		SubscriptionId: subscription.Id,
+		EventId:        original.EventId,
+		EventType:      original.EventType,
+		Payload:        original.Payload,
+		Status:         model.DELIVERY_PENDING,
+		CreatedAt:      now,
+		NextAttemptAt:  now,
+	}
+
+	err = s.webhooks.InsertDelivery(ctx, &delivery)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	var deliveryDTO dto.WebhookDeliveryDTO
+
+	deliveryDTO.ConvertToDeliveryDTO(&delivery)
+
+	return fiber.StatusAccepted, responseBody{"delivery": deliveryDTO}
+}
+
+func (s *WebhookSubscriptionService) findSubscription(ctx context.Context, externalId uuid.UUID) (*model.WebhookSubscription, int, responseBody) {
+	subscription, err := s.webhooks.FindSubscriptionByExternalId(ctx, externalId)
+
+	if err != nil {
+		return nil, fiber.StatusInternalServerError, responseBody{"message": INTERNAL_SERVER_ERROR_MESSAGE}
+	}
+
+	if subscription.Id == 0 {
+		return nil, fiber.StatusNotFound, responseBody{"message": WEBHOOK_NOT_FOUND_MESSAGE}
+	}
+
+	return subscription, 0, nil
+}
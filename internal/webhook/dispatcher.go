@@ -0,0 +1,217 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/events"
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/LucasAndFlores/user_api/internal/repository"
+)
+
+type DispatcherOptions struct {
+	// Interval is the pause between two polls once no delivery is due.
+	Interval  time.Duration
+	BatchSize int
+	// Timeout bounds every attempt, including reading the response.
+	Timeout time.Duration
+	// MaxAttempts is how many times a delivery is tried before it is
+	// given up as failed.
+	MaxAttempts int
+	// MaxBackoff caps the exponential delay between two attempts.
+	MaxBackoff time.Duration
+	// DisableAfter is the number of failed attempts in a row, across
+	// deliveries, after which a subscription is disabled.
+	DisableAfter int
+	// AllowPrivateTargets lets the deliveries reach addresses that aren't
+	// public, e.g. for receivers on the same host during development.
+	AllowPrivateTargets bool
+}
+
+// Dispatcher sends the pending deliveries, signed with the secret of their
+// subscription. A delivery succeeds when the receiver answers with a 2xx.
+type Dispatcher struct {
+	webhooks   repository.WebhookRepository
+	transactor repository.Transactor
+	client     *http.Client
+	options    DispatcherOptions
+}
+
+func NewDispatcher(w repository.WebhookRepository, t repository.Transactor, options DispatcherOptions) *Dispatcher {
+	return &Dispatcher{
+		webhooks:   w,
+		transactor: t,
+		client:     newClient(options.Timeout, options.AllowPrivateTargets),
+		options:    options,
+	}
+}
+
+// Run polls the deliveries until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.options.Interval)
+
+	defer ticker.Stop()
+
+	for {
+		for {
+			sent, err := d.RunOnce(ctx)
+
+			if err != nil {
+				log.Printf("An error occurred when tried to dispatch the webhooks: %v", err)
+			}
+
+			if err != nil || sent == 0 {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce makes an attempt for one batch of due deliveries and reports how
+// many were attempted. The batch is claimed with a lease in a transaction of
+// its own, so that no transaction stays open while the receivers answer;
+// should this dispatcher stop midway, another one takes the rest of the
+// batch over once the lease expires.
+func (d *Dispatcher) RunOnce(ctx context.Context) (int, error) {
+	var claimed []model.WebhookDelivery
+
+	err := d.transactor.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+
+		claimed, err = d.webhooks.ClaimDueDeliveries(ctx, time.Now(), d.options.BatchSize, d.lease())
+
+		return err
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range claimed {
+		err = d.attempt(ctx, &claimed[i])
+
+		if err != nil {
+			return i, err
+		}
+	}
+
+	return len(claimed), nil
+}
+
+// lease covers the attempts of a whole batch, which are made one after the
+// other and each bounded by the timeout.
+func (d *Dispatcher) lease() time.Duration {
+	return time.Duration(d.options.BatchSize+1) * d.options.Timeout
+}
+
+// attempt sends the delivery, then saves the attempt and its outcome for
+// the subscription in one short transaction.
+func (d *Dispatcher) attempt(ctx context.Context, delivery *model.WebhookDelivery) error {
+	subscription, err := d.webhooks.FindSubscriptionById(ctx, delivery.SubscriptionId)
+
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus, err = d.send(ctx, subscription, delivery, now)
+
+	if err == nil {
+		delivery.Status = model.DELIVERY_SUCCEEDED
+		delivery.LastError = ""
+
+		return d.transactor.Transaction(ctx, func(ctx context.Context) error {
+			err := d.webhooks.SaveAttempt(ctx, delivery)
+
+			if err != nil {
+				return err
+			}
+
+			return d.webhooks.ResetFailures(ctx, subscription)
+		})
+	}
+
+	delivery.LastError = err.Error()
+	delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+
+	if delivery.Attempts >= d.options.MaxAttempts {
+		delivery.Status = model.DELIVERY_FAILED
+	}
+
+	return d.transactor.Transaction(ctx, func(ctx context.Context) error {
+		err := d.webhooks.SaveAttempt(ctx, delivery)
+
+		if err != nil {
+			return err
+		}
+
+		return d.webhooks.CountFailure(ctx, subscription, d.options.DisableAfter, now)
+	})
+}
+
+// send posts the delivery and returns the status of the response, or 0
+// when none was received.
+func (d *Dispatcher) send(ctx context.Context, subscription *model.WebhookSubscription, delivery *model.WebhookDelivery, now time.Time) (int, error) {
+	body, err := events.OpenEvent(delivery.Payload)
+
+	if err != nil {
+		return 0, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+
+	if err != nil {
+		return 0, err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "user_api-webhooks")
+	request.Header.Set(ID_HEADER, delivery.ExternalId.String())
+	request.Header.Set(EVENT_HEADER, delivery.EventType)
+	request.Header.Set(SIGNATURE_HEADER, Sign(subscription.Secret, now, body))
+
+	response, err := d.client.Do(request)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer response.Body.Close()
+
+	// the body is drained so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("the receiver answered %d", response.StatusCode)
+	}
+
+	return response.StatusCode, nil
+}
+
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := time.Second
+
+	for i := 1; i < attempts && delay < d.options.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > d.options.MaxBackoff {
+		return d.options.MaxBackoff
+	}
+
+	return delay
+}
@@ -0,0 +1,70 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/LucasAndFlores/user_api/internal/events"
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/LucasAndFlores/user_api/internal/repository"
+	"github.com/google/uuid"
+)
+
+// Publisher turns an outbox event into a pending delivery for every enabled
+// subscription to its type. The deliveries are written in the transaction
+// of the relay, so an event is fanned out exactly once, and sent later by
+// the Dispatcher.
+type Publisher struct {
+	webhooks repository.WebhookRepository
+}
+
+func NewPublisher(w repository.WebhookRepository) events.Publisher {
+	return &Publisher{webhooks: w}
+}
+
+func (p *Publisher) Publish(ctx context.Context, event events.Event) error {
+	subscriptions, err := p.webhooks.FindSubscriptions(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	// the deliveries keep the pii encrypted until they are sent
+	event.Payload, err = events.SealUserPayload(event.Payload)
+
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(event)
+
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	for _, subscription := range subscriptions {
+		if subscription.DisabledAt != nil || !subscription.Subscribes(event.Type) {
+			continue
+		}
+
+		err = p.webhooks.InsertDelivery(ctx, &model.WebhookDelivery{
+			ExternalId:     uuid.New(),
+			SubscriptionId: subscription.Id,
+			EventId:        event.Id,
+			EventType:      event.Type,
+			Payload:        payload,
+			Status:         model.DELIVERY_PENDING,
+			CreatedAt:      now,
+			NextAttemptAt:  now,
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
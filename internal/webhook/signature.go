@@ -0,0 +1,83 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SIGNATURE_HEADER = "Webhook-Signature"
+	ID_HEADER        = "Webhook-Id"
+	EVENT_HEADER     = "Webhook-Event"
+)
+
+// DEFAULT_TOLERANCE is how old a signature receivers should accept, which
+// bounds the window for replaying a captured delivery.
+const DEFAULT_TOLERANCE = 5 * time.Minute
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrExpiredSignature = errors.New("expired webhook signature")
+)
+
+// Sign computes the signature header of a body sent at timestamp:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<unix seconds>.<body>">". The
+// timestamp is signed too, so it can't be replaced to replay a delivery.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+
+	return fmt.Sprintf("t=%s,v1=%s", unix, hex.EncodeToString(mac(secret, unix, body)))
+}
+
+// Verify checks a signature header computed by Sign, rejecting the ones
+// older than tolerance. Receivers written in Go can use it as is.
+func Verify(secret string, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var unix, signature string
+
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+
+		switch key {
+		case "t":
+			unix = value
+		case "v1":
+			signature = value
+		}
+	}
+
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	decoded, err := hex.DecodeString(signature)
+
+	if err != nil || !hmac.Equal(decoded, mac(secret, unix, body)) {
+		return ErrInvalidSignature
+	}
+
+	age := now.Sub(time.Unix(seconds, 0))
+
+	if age > tolerance || age < -tolerance {
+		return ErrExpiredSignature
+	}
+
+	return nil
+}
+
+func mac(secret string, unix string, body []byte) []byte {
+	hash := hmac.New(sha256.New, []byte(secret))
+
+	hash.Write([]byte(unix))
+	hash.Write([]byte("."))
+	hash.Write(body)
+
+	return hash.Sum(nil)
+}
@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateTarget rejects the receivers on the network of the API, so that
+// subscriptions can't be used to reach its internal services.
+var ErrPrivateTarget = errors.New("the webhook URL must resolve to public addresses only")
+
+var ErrInvalidTarget = errors.New("the webhook URL must be an http or https URL")
+
+// sharedAddressSpace is the range of carrier-grade NAT, RFC 6598.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// IsPublic reports whether ip may be the address of a receiver: loopback,
+// private, link-local, multicast and unspecified addresses may not, nor
+// the shared address space of carrier-grade NAT.
+func IsPublic(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified() &&
+		!sharedAddressSpace.Contains(ip)
+}
+
+// CheckTarget checks that rawURL is an http or https URL, and fails with
+// ErrPrivateTarget when its host is localhost or an address that isn't
+// public. Other hosts are not resolved: the dispatcher checks their
+// addresses when connecting, as they may change anyway.
+func CheckTarget(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+
+	if err != nil {
+		return err
+	}
+
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return ErrInvalidTarget
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(parsed.Hostname(), "."))
+
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrPrivateTarget
+	}
+
+	if ip := net.ParseIP(host); ip != nil && !IsPublic(ip) {
+		return ErrPrivateTarget
+	}
+
+	return nil
+}
+
+// newClient builds the client sending the deliveries. Unless allowPrivate
+// is set, it refuses to connect to addresses that aren't public, which also
+// covers hosts resolving to other addresses than when they were subscribed.
+// Redirects are never followed, so a 3xx is a failed attempt.
+func newClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+
+	if !allowPrivate {
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+
+			if err != nil {
+				return err
+			}
+
+			if ip := net.ParseIP(host); ip == nil || !IsPublic(ip) {
+				return ErrPrivateTarget
+			}
+
+			return nil
+		}
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		// no proxy, or the dialer would check its address instead
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConnsPerHost: 2,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/LucasAndFlores/user_api/internal/dto"
+	"github.com/LucasAndFlores/user_api/internal/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+func setupAuthRoutes(auth fiber.Router, h *Handlers) {
+	auth.Post("/login", middleware.ValidateRequestBody[dto.LoginDTO](h.deps.Validator), h.auth.HandleLogin)
+	auth.Post("/login/mfa", middleware.ValidateRequestBody[dto.MFALoginDTO](h.deps.Validator), h.auth.HandleLoginMFA)
+	auth.Post("/refresh", middleware.ValidateRequestBody[dto.RefreshTokenDTO](h.deps.Validator), h.auth.HandleRefresh)
+	auth.Post("/logout", middleware.ValidateRequestBody[dto.RefreshTokenDTO](h.deps.Validator), h.auth.HandleLogout)
+	auth.Get("/verify-email", h.auth.HandleVerifyEmail)
+	auth.Post("/verify-email/resend", middleware.ValidateRequestBody[dto.EmailDTO](h.deps.Validator), h.auth.HandleResendVerification)
+	auth.Post("/password/forgot", middleware.ValidateRequestBody[dto.EmailDTO](h.deps.Validator), h.auth.HandleForgotPassword)
+	auth.Post("/password/reset", middleware.ValidateRequestBody[dto.ResetPasswordDTO](h.deps.Validator), h.auth.HandleResetPassword)
+
+	auth.Post("/mfa/totp/enroll", h.authenticate, h.mfa.HandleEnrollTOTP)
+	auth.Post("/mfa/totp/confirm", h.authenticate, middleware.ValidateRequestBody[dto.TOTPCodeDTO](h.deps.Validator), h.mfa.HandleConfirmTOTP)
+}
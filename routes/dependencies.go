@@ -0,0 +1,170 @@
+package routes
+
+import (
+	"github.com/LucasAndFlores/user_api/config"
+	"github.com/LucasAndFlores/user_api/internal/auth"
+	"github.com/LucasAndFlores/user_api/internal/events"
+	"github.com/LucasAndFlores/user_api/internal/mail"
+	"github.com/LucasAndFlores/user_api/internal/middleware"
+	"github.com/LucasAndFlores/user_api/internal/ratelimit"
+	"github.com/LucasAndFlores/user_api/internal/repository"
+	"github.com/LucasAndFlores/user_api/internal/rotation"
+	"github.com/LucasAndFlores/user_api/internal/service"
+	"github.com/LucasAndFlores/user_api/internal/webhook"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// Dependencies holds the components shared by the route groups, built once
+// per application from the loaded configuration.
+type Dependencies struct {
+	Config *config.Config
+	Hasher auth.PasswordHasher
+	Issuer *auth.TokenIssuer
+	Mailer mail.Mailer
+	// RateLimiter is shared by every rate limited route of the app.
+	RateLimiter ratelimit.Store
+	// Validator checks the bodies of every API with the rules of the config.
+	Validator *validator.Validate
+}
+
+func NewDependencies(cfg *config.Config, db *gorm.DB) (*Dependencies, error) {
+	hasher, err := auth.NewBcryptHasher(cfg.Auth.BcryptCost)
+
+	if err != nil {
+		return nil, err
+	}
+
+	mailer, err := newMailer(cfg.Mail)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimiter := ratelimit.NewMemoryStore()
+
+	if cfg.RateLimit.Store == "postgres" {
+		rateLimiter = ratelimit.NewPostgresStore(db)
+	}
+
+	return &Dependencies{
+		Config:      cfg,
+		Hasher:      hasher,
+		Issuer:      auth.NewTokenIssuer(cfg.Auth.TokenSecret, cfg.Auth.AccessTokenTTL),
+		Mailer:      mailer,
+		RateLimiter: rateLimiter,
+		Validator: middleware.NewValidator(middleware.ValidatorOptions{
+			DateOfBirth:                middleware.DateOfBirthRules{MinAge: cfg.DateOfBirth.MinAge, MaxAge: cfg.DateOfBirth.MaxAge},
+			AllowPrivateWebhookTargets: cfg.Webhook.AllowPrivateTargets,
+		}),
+	}, nil
+}
+
+func toLimit(l config.RateLimit) ratelimit.Limit {
+	return ratelimit.Limit{Requests: l.Requests, Period: l.Period, Burst: l.Burst}
+}
+
+func newMailer(cfg config.MailConfig) (mail.Mailer, error) {
+	switch cfg.Driver {
+	case "smtp":
+		return mail.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From), nil
+	case "file":
+		return mail.NewFileMailer(cfg.FileDir, cfg.From)
+	default:
+		return mail.NewLogMailer(), nil
+	}
+}
+
+func newVerificationService(db *gorm.DB, deps *Dependencies) service.VerificationService {
+	return service.NewVerificationService(
+		repository.NewUserRepository(db),
+		repository.NewEmailVerificationRepository(db),
+		repository.NewOutboxRepository(db),
+		repository.NewAuditRepository(db),
+		repository.NewVersionRepository(db),
+		repository.NewTransactor(db),
+		deps.Issuer,
+		deps.Mailer,
+		deps.Config.BaseURL,
+		deps.Config.Auth.VerificationTTL,
+		deps.Config.Auth.VerificationResend,
+	)
+}
+
+// NewUserService builds the user service shared by the HTTP and gRPC APIs.
+func NewUserService(db *gorm.DB, deps *Dependencies) service.Service {
+	return service.NewUserService(
+		repository.NewUserRepository(db),
+		repository.NewOutboxRepository(db),
+		repository.NewAuditRepository(db),
+		repository.NewVersionRepository(db),
+		repository.NewTransactor(db),
+		deps.Hasher,
+		newVerificationService(db, deps),
+	)
+}
+
+// NewDataSubjectService builds the service exporting and erasing the data of
+// users, shared by the HTTP API and the gdpr command.
+func NewDataSubjectService(db *gorm.DB) service.DataSubjectService {
+	return service.NewDataSubjectService(
+		repository.NewUserRepository(db),
+		repository.NewDataSubjectRepository(db),
+		repository.NewVersionRepository(db),
+		repository.NewAuditRepository(db),
+		repository.NewOutboxRepository(db),
+		repository.NewWebhookRepository(db),
+		repository.NewIdempotencyKeyRepository(db),
+		repository.NewTransactor(db),
+	)
+}
+
+// NewRelay builds the worker publishing the events of the outbox, to the
+// configured publisher and to the webhook subscriptions.
+func NewRelay(db *gorm.DB, cfg config.OutboxConfig) (*events.Relay, error) {
+	publisher, err := newPublisher(cfg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	publisher = events.NewMultiPublisher(webhook.NewPublisher(repository.NewWebhookRepository(db)), publisher)
+
+	return events.NewRelay(repository.NewOutboxRepository(db), repository.NewTransactor(db), publisher, events.RelayOptions{
+		Interval:   cfg.Interval,
+		BatchSize:  cfg.BatchSize,
+		MaxBackoff: cfg.MaxBackoff,
+		Retention:  cfg.Retention,
+	}), nil
+}
+
+// NewDispatcher builds the worker sending the webhook deliveries.
+func NewDispatcher(db *gorm.DB, cfg config.WebhookConfig) *webhook.Dispatcher {
+	return webhook.NewDispatcher(repository.NewWebhookRepository(db), repository.NewTransactor(db), webhook.DispatcherOptions{
+		Interval:            cfg.Interval,
+		BatchSize:           cfg.BatchSize,
+		Timeout:             cfg.Timeout,
+		MaxAttempts:         cfg.MaxAttempts,
+		MaxBackoff:          cfg.MaxBackoff,
+		DisableAfter:        cfg.DisableAfter,
+		AllowPrivateTargets: cfg.AllowPrivateTargets,
+	})
+}
+
+// NewReencryptor builds the worker re-encrypting the pii encrypted with a
+// retired key.
+func NewReencryptor(db *gorm.DB, cfg config.PIIConfig) *rotation.Reencryptor {
+	return rotation.NewReencryptor(repository.NewReencryptionRepository(db), rotation.ReencryptorOptions{
+		Interval:  cfg.ReencryptInterval,
+		BatchSize: cfg.ReencryptBatchSize,
+	})
+}
+
+func newPublisher(cfg config.OutboxConfig) (events.Publisher, error) {
+	switch cfg.Publisher {
+	case "file":
+		return events.NewFilePublisher(cfg.FileDir)
+	default:
+		return events.NewLogPublisher(), nil
+	}
+}
@@ -0,0 +1,162 @@
+package routes
+
+import (
+	"github.com/LucasAndFlores/user_api/internal/controller"
+	"github.com/LucasAndFlores/user_api/internal/events"
+	"github.com/LucasAndFlores/user_api/internal/gql"
+	"github.com/LucasAndFlores/user_api/internal/middleware"
+	"github.com/LucasAndFlores/user_api/internal/model"
+	"github.com/LucasAndFlores/user_api/internal/openapi"
+	"github.com/LucasAndFlores/user_api/internal/ratelimit"
+	"github.com/LucasAndFlores/user_api/internal/repository"
+	"github.com/LucasAndFlores/user_api/internal/service"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Handlers holds the controllers and middlewares shared by every API
+// version, so each version only decides which paths they are mounted on.
+type Handlers struct {
+	deps     *Dependencies
+	document *openapi.Document
+
+	user    controller.Controller
+	auth    controller.AuthController
+	mfa     controller.MFAController
+	gql     controller.GraphQLController
+	webhook controller.WebhookController
+	events  controller.EventStreamController
+	audit   controller.AuditController
+	gdpr    controller.DataSubjectController
+
+	authenticate         fiber.Handler
+	optionalAuthenticate fiber.Handler
+	requireAdmin         []fiber.Handler
+	requireStaff         []fiber.Handler
+	selfOrAdmin          fiber.Handler
+	selfOrStaff          fiber.Handler
+	createLimit          fiber.Handler
+	getLimit             fiber.Handler
+	graphQLLimit         fiber.Handler
+	idempotency          fiber.Handler
+	// openAPIValidation is nil unless a validation is enabled in the config.
+	openAPIValidation fiber.Handler
+}
+
+func NewHandlers(db *gorm.DB, deps *Dependencies) (*Handlers, error) {
+	userRepo := repository.NewUserRepository(db)
+	tokenRepo := repository.NewRefreshTokenRepository(db)
+	mfaRepo := repository.NewMFARepository(db)
+
+	verificationService := newVerificationService(db, deps)
+
+	userService := NewUserService(db, deps)
+
+	authService := service.NewAuthService(userRepo, tokenRepo, mfaRepo, deps.Hasher, deps.Issuer, deps.RateLimiter, service.AuthOptions{
+		RefreshTokenTTL:      deps.Config.Auth.RefreshTokenTTL,
+		RequireVerifiedEmail: deps.Config.Auth.RequireVerifiedEmail,
+		MFALimit:             toLimit(deps.Config.RateLimit.MFALogin),
+	})
+
+	passwordResetService := service.NewPasswordResetService(
+		userRepo,
+		repository.NewPasswordResetRepository(db),
+		repository.NewOutboxRepository(db),
+		repository.NewAuditRepository(db),
+		repository.NewVersionRepository(db),
+		repository.NewTransactor(db),
+		deps.Hasher,
+		deps.Issuer,
+		deps.Mailer,
+		deps.RateLimiter,
+		service.PasswordResetOptions{
+			BaseURL:    deps.Config.BaseURL,
+			TTL:        deps.Config.Auth.ResetTTL,
+			EmailLimit: ratelimit.Limit{Requests: deps.Config.Auth.ResetEmailLimit, Period: deps.Config.Auth.ResetLimitWindow},
+			IPLimit:    ratelimit.Limit{Requests: deps.Config.Auth.ResetIPLimit, Period: deps.Config.Auth.ResetLimitWindow},
+		},
+	)
+
+	idempotencyKeys := repository.NewIdempotencyKeyRepository(db)
+
+	graphQLServer, err := gql.NewServer(userService, gql.Options{
+		MaxDepth:         deps.Config.GraphQL.MaxDepth,
+		MaxComplexity:    deps.Config.GraphQL.MaxComplexity,
+		RequireAdminMFA:  deps.Config.Auth.RequireAdminMFA,
+		Limiter:          deps.RateLimiter,
+		CreateLimit:      toLimit(deps.Config.RateLimit.CreateUser),
+		Idempotency:      idempotencyKeys,
+		IdempotencyTTL:   deps.Config.IdempotencyTTL,
+		IdempotencyLease: deps.Config.IdempotencyLease,
+		Validator:        deps.Validator,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	authenticate := middleware.Authenticate(deps.Issuer)
+
+	clients := middleware.NewClients(deps.Issuer, deps.Config.RateLimit.APIKeys)
+
+	requireAdmin := []fiber.Handler{authenticate, middleware.RequireRole(model.ROLE_ADMIN)}
+
+	// support reads the users with their pii masked, but can't change them
+	requireStaff := []fiber.Handler{authenticate, middleware.RequireRole(model.ROLE_ADMIN, model.ROLE_SUPPORT)}
+
+	if deps.Config.Auth.RequireAdminMFA {
+		requireAdmin = append(requireAdmin, middleware.RequireMFA)
+		requireStaff = append(requireStaff, middleware.RequireMFA)
+	}
+
+	eventStream := events.NewStream(repository.NewOutboxRepository(db), events.StreamOptions{BatchSize: 100})
+
+	document := NewDocument()
+
+	var openAPIValidation fiber.Handler
+
+	if deps.Config.OpenAPI.ValidateRequests || deps.Config.OpenAPI.ValidateResponses {
+		openAPIValidation = middleware.OpenAPIValidation(document, middleware.OpenAPIOptions{
+			ValidateRequests:  deps.Config.OpenAPI.ValidateRequests,
+			ValidateResponses: deps.Config.OpenAPI.ValidateResponses,
+		})
+	}
+
+	return &Handlers{
+		deps:     deps,
+		document: document,
+
+		user:    controller.NewUserController(userService),
+		auth:    controller.NewAuthController(authService, verificationService, passwordResetService),
+		gql:     controller.NewGraphQLController(graphQLServer, clients),
+		mfa:     controller.NewMFAController(service.NewMFAService(userRepo, mfaRepo, deps.Config.Auth.TOTPIssuer)),
+		webhook: controller.NewWebhookController(service.NewWebhookService(repository.NewWebhookRepository(db))),
+		audit:   controller.NewAuditController(service.NewAuditService(repository.NewAuditRepository(db))),
+		gdpr:    controller.NewDataSubjectController(NewDataSubjectService(db)),
+		events:  controller.NewEventStreamController(eventStream, controller.EventStreamOptions{PollInterval: deps.Config.SSE.PollInterval, Heartbeat: deps.Config.SSE.Heartbeat}),
+
+		authenticate:         authenticate,
+		optionalAuthenticate: middleware.OptionalAuthenticate(deps.Issuer),
+		requireAdmin:         requireAdmin,
+		requireStaff:         requireStaff,
+		selfOrAdmin:          middleware.RequireSelfOrRole(deps.Config.Auth.RequireAdminMFA, model.ROLE_ADMIN),
+		selfOrStaff:          middleware.RequireSelfOrRole(deps.Config.Auth.RequireAdminMFA, model.ROLE_ADMIN, model.ROLE_SUPPORT),
+		createLimit:          middleware.RateLimit(deps.RateLimiter, clients, "create_user", toLimit(deps.Config.RateLimit.CreateUser)),
+		getLimit:             middleware.RateLimit(deps.RateLimiter, clients, "get_user", toLimit(deps.Config.RateLimit.GetUser)),
+		graphQLLimit:         middleware.RateLimit(deps.RateLimiter, clients, "graphql", toLimit(deps.Config.RateLimit.GraphQL)),
+		idempotency:          middleware.Idempotency(idempotencyKeys, clients, deps.Config.IdempotencyTTL, deps.Config.IdempotencyLease),
+
+		openAPIValidation: openAPIValidation,
+	}, nil
+}
+
+// admin prepends the admin authorization chain to handlers.
+func (h *Handlers) admin(handlers ...fiber.Handler) []fiber.Handler {
+	return append(append([]fiber.Handler{}, h.requireAdmin...), handlers...)
+}
+
+// staff prepends the authorization chain of the admins and support to
+// handlers.
+func (h *Handlers) staff(handlers ...fiber.Handler) []fiber.Handler {
+	return append(append([]fiber.Handler{}, h.requireStaff...), handlers...)
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/LucasAndFlores/user_api/internal/dto"
+	"github.com/LucasAndFlores/user_api/internal/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+// setupLegacyRoutes keeps the paths from before the versioned API working
+// until their sunset. They squat on /api/:id, so they must be registered
+// after every version.
+func setupLegacyRoutes(api fiber.Router, h *Handlers) {
+	legacy := h.deps.Config.Legacy
+
+	usersSuccessor := middleware.Deprecated(legacy.DeprecatedAt, legacy.Sunset, "/api/v1/users")
+	authSuccessor := middleware.Deprecated(legacy.DeprecatedAt, legacy.Sunset, "/api/v1/auth")
+
+	setupAuthRoutes(api.Group("/auth", authSuccessor), h)
+
+	api.Delete("/admin/users/:id/sessions", append([]fiber.Handler{usersSuccessor}, h.admin(h.auth.HandleRevokeAllSessions)...)...)
+
+	api.Post("/save", usersSuccessor, middleware.Negotiate, h.createLimit, h.idempotency, middleware.ValidateRequestBody[dto.UserDTO](h.deps.Validator), h.user.HandleCreateUser)
+	api.Get("/:id", usersSuccessor, middleware.Negotiate, h.getLimit, h.optionalAuthenticate, withQuery("as_of", h.selfOrStaff), h.user.HandleFindUserByExternalId)
+	api.Put("/:id", usersSuccessor, middleware.Negotiate, h.authenticate, h.selfOrAdmin, middleware.ValidateRequestBody[dto.UpdateUserDTO](h.deps.Validator), h.user.HandleUpdateUser)
+	api.Delete("/:id", usersSuccessor, middleware.Negotiate, h.authenticate, h.selfOrAdmin, h.user.HandleDeleteUser)
+}
@@ -0,0 +1,585 @@
+package routes
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/LucasAndFlores/user_api/internal/auth"
+	"github.com/LucasAndFlores/user_api/internal/codec"
+	"github.com/LucasAndFlores/user_api/internal/dto"
+	"github.com/LucasAndFlores/user_api/internal/events"
+	"github.com/LucasAndFlores/user_api/internal/middleware"
+	"github.com/LucasAndFlores/user_api/internal/openapi"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/filesystem"
+)
+
+func init() {
+	openapi.RegisterValidation("password", func(s *openapi.Schema, _ string) {
+		minLength, maxLength := auth.MIN_PASSWORD_LENGTH, 72
+
+		s.MinLength = &minLength
+		s.MaxLength = &maxLength
+		s.Description = "Mixes at least three of lowercase, uppercase, digits and symbols."
+	})
+
+	// only on request bodies, as responses may mask the dates of birth to their year
+	openapi.RegisterValidation("date", func(s *openapi.Schema, _ string) {
+		s.Pattern = "^[0-9]{4}-[0-9]{2}-[0-9]{2}"
+	})
+
+	openapi.RegisterValidation("date_of_birth", func(s *openapi.Schema, _ string) {
+		s.Description = "YYYY-MM-DD, or an RFC 3339 time. Neither in the future nor out of the ages set by DATE_OF_BIRTH_MIN_AGE and DATE_OF_BIRTH_MAX_AGE."
+	})
+}
+
+// The bodies below only describe responses the services build as maps.
+
+type messageBody struct {
+	Message string `json:"message" validate:"required"`
+}
+
+type userBody struct {
+	User dto.UserDTO `json:"user" validate:"required"`
+}
+
+type usersBody struct {
+	Users      []struct{} `json:"users" validate:"required"`
+	NextCursor string     `json:"next_cursor" validate:"required"`
+}
+
+type tokensBody struct {
+	AccessToken  string `json:"access_token" validate:"required"`
+	RefreshToken string `json:"refresh_token" validate:"required"`
+	TokenType    string `json:"token_type" validate:"required"`
+	ExpiresIn    int    `json:"expires_in" validate:"required"`
+}
+
+type mfaChallengeBody struct {
+	MFARequired bool   `json:"mfa_required" validate:"required"`
+	MFAToken    string `json:"mfa_token" validate:"required"`
+}
+
+type totpEnrollmentBody struct {
+	Secret          string `json:"secret" validate:"required"`
+	ProvisioningURI string `json:"provisioning_uri" validate:"required"`
+}
+
+type recoveryCodesBody struct {
+	RecoveryCodes []string `json:"recovery_codes" validate:"required"`
+}
+
+type webhookBody struct {
+	Webhook dto.WebhookResponseDTO `json:"webhook" validate:"required"`
+}
+
+type webhooksBody struct {
+	Webhooks []dto.WebhookResponseDTO `json:"webhooks" validate:"required"`
+}
+
+type deliveryBody struct {
+	Delivery dto.WebhookDeliveryDTO `json:"delivery" validate:"required"`
+}
+
+type deliveriesBody struct {
+	Deliveries []dto.WebhookDeliveryDTO `json:"deliveries" validate:"required"`
+}
+
+type userVersionsBody struct {
+	Versions   []dto.UserVersionDTO `json:"versions" validate:"required"`
+	NextCursor string               `json:"next_cursor" validate:"required"`
+}
+
+type auditTrailBody struct {
+	Records    []dto.AuditRecordDTO `json:"records" validate:"required"`
+	NextCursor string               `json:"next_cursor" validate:"required"`
+}
+
+type tooManyRequestsBody struct {
+	Message    string `json:"message" validate:"required"`
+	RetryAfter int    `json:"retry_after"`
+}
+
+// NewDocument describes the current API version. Request schemas come from
+// the DTOs and their validate tags; every v1 route must be listed here.
+func NewDocument() *openapi.Document {
+	doc := openapi.New("user_api", "v1")
+
+	doc.Components.SecuritySchemes["bearer"] = openapi.SecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT"}
+
+	message := doc.Component("Message", messageBody{})
+	validationErrors := doc.Component("ValidationErrors", []middleware.RequestBodyError{})
+	doc.Component("User", dto.UserDTO{})
+	user := doc.Component("UserResponse", userBody{})
+
+	// the fields query parameter leaves any field out
+	selected := openapi.SchemaOf(dto.UserDTO{})
+	selected.Required = nil
+	doc.Components.Schemas["SelectedUser"] = selected
+
+	selectedUser := &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{"user": openapi.Ref("SelectedUser")}, Required: []string{"user"}}
+
+	selectedUsers := openapi.SchemaOf(usersBody{})
+	selectedUsers.Properties["users"].Items = openapi.Ref("SelectedUser")
+	tokens := doc.Component("Tokens", tokensBody{})
+
+	bearer := []map[string][]string{{"bearer": {}}}
+
+	invalid := openapi.Reply("A field is missing or invalid", validationErrors)
+	unauthorized := openapi.Reply("Missing or invalid bearer token", message)
+	forbidden := openapi.Reply("Not allowed for this principal", message)
+	notFound := openapi.Reply("User not found", message)
+	badId := openapi.Reply("Unable to parse the id", message)
+
+	unparsable := openapi.Reply("Unable to parse the body", message)
+	preconditionFailed := openapi.Reply("The ETag sent in If-Match is stale", message)
+	tooMany := openapi.Response{
+		Description: "Too many requests",
+		Headers:     map[string]openapi.Header{"Retry-After": {Schema: &openapi.Schema{Type: "integer"}}},
+		Content:     openapi.JSON(message),
+	}
+
+	fields := openapi.Parameter{Name: "fields", In: "query", Description: "Comma separated fields to return, among " + strings.Join(dto.UserFieldNames(), ", "), Schema: &openapi.Schema{Type: "string"}}
+
+	ifMatch := openapi.Parameter{Name: "If-Match", In: "header", Description: "ETag of the version being changed", Schema: &openapi.Schema{Type: "string"}}
+
+	users := "/api/v1/users"
+
+	doc.Add(fiber.MethodPost, users, &openapi.Operation{
+		OperationId: "createUser",
+		Summary:     "Create a user",
+		Tags:        []string{"users"},
+		Parameters: []openapi.Parameter{
+			{Name: middleware.IDEMPOTENCY_KEY_HEADER, In: "header", Description: "Makes retries replay the first response", Schema: &openapi.Schema{Type: "string"}},
+		},
+		RequestBody: openapi.Body(openapi.Ref("User")),
+		Responses: map[string]openapi.Response{
+			"201": {
+				Description: "User created",
+				Headers:     map[string]openapi.Header{"Location": {Schema: &openapi.Schema{Type: "string"}}},
+				Content:     openapi.JSON(message),
+			},
+			"400": openapi.Reply("Unable to parse the body, or the idempotency key is too long", message),
+			"409": openapi.Reply("The email or id is already registered, or the idempotency key is in use", message),
+			"422": openapi.Reply("A field is missing or invalid, or the idempotency key was used with another payload", &openapi.Schema{OneOf: []*openapi.Schema{message, validationErrors}}),
+			"429": tooMany,
+		},
+	})
+
+	doc.Add(fiber.MethodGet, users, &openapi.Operation{
+		OperationId: "listUsers",
+		Summary:     "List the users (admin or support)",
+		Tags:        []string{"users"},
+		Parameters: []openapi.Parameter{
+			fields,
+			{Name: "limit", In: "query", Description: "Defaults to 50, at most 100", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "cursor", In: "query", Description: "The next_cursor of the previous page", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "email", In: "query", Description: "Exact email, case-insensitive", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "name", In: "query", Description: "Part of the name, case-insensitive", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "updated_since", In: "query", Description: "RFC 3339 time, to list the users updated at or after it only, widened by a minute to catch the changes committing late", Schema: &openapi.Schema{Type: "string", Format: "date-time"}},
+		},
+		Security: bearer,
+		Responses: map[string]openapi.Response{
+			"200": openapi.Reply("A page of users, with the selected fields only", selectedUsers),
+			"400": openapi.Reply("Invalid limit, cursor, fields or updated_since", message),
+			"401": unauthorized,
+			"403": forbidden,
+		},
+	})
+
+	doc.Add(fiber.MethodGet, users+"/events", &openapi.Operation{
+		OperationId: "streamUserEvents",
+		Summary:     "Stream the user events as Server-Sent Events (admin)",
+		Tags:        []string{"users"},
+		Parameters: []openapi.Parameter{
+			{Name: "types", In: "query", Description: "Comma separated event types to receive, among " + strings.Join(events.TYPES, ", "), Schema: &openapi.Schema{Type: "string"}},
+			{Name: "Last-Event-ID", In: "header", Description: "Resume after this event", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "last_event_id", In: "query", Description: "Resume after this event, for clients that can't set headers", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Security: bearer,
+		Responses: map[string]openapi.Response{
+			"200": {
+				Description: "An endless stream of events, with the event id as id and the event type as event",
+				Content:     map[string]openapi.MediaType{"text/event-stream": {Schema: &openapi.Schema{Type: "string"}}},
+			},
+			"400": openapi.Reply("Unknown event type or invalid last event id", message),
+			"401": unauthorized,
+			"403": forbidden,
+			"410": openapi.Reply("The last event was cleaned up from the outbox, so the events following it may be gone: reconnect without it", message),
+		},
+	})
+
+	doc.Add(fiber.MethodGet, users+"/:id", &openapi.Operation{
+		OperationId: "getUser",
+		Summary:     "Find a user by id",
+		Tags:        []string{"users"},
+		Parameters: []openapi.Parameter{
+			fields,
+			{Name: "If-None-Match", In: "header", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "as_of", In: "query", Description: "RFC 3339 time to read the user as it was then, for the user, an admin or support only", Schema: &openapi.Schema{Type: "string", Format: "date-time"}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": {
+				Description: "The user, with the selected fields only",
+				Headers:     map[string]openapi.Header{"ETag": {Schema: &openapi.Schema{Type: "string"}}},
+				Content:     openapi.JSON(selectedUser),
+			},
+			"304": {Description: "The user matches If-None-Match"},
+			"400": openapi.Reply("Unable to parse the id or as_of, or unknown fields", message),
+			"401": unauthorized,
+			"403": forbidden,
+			"404": notFound,
+			"429": tooMany,
+		},
+	})
+
+	doc.Add(fiber.MethodPut, users+"/:id", &openapi.Operation{
+		OperationId: "updateUser",
+		Summary:     "Update a user",
+		Tags:        []string{"users"},
+		Parameters:  []openapi.Parameter{ifMatch},
+		RequestBody: openapi.Body(doc.Component("UpdateUser", dto.UpdateUserDTO{})),
+		Security:    bearer,
+		Responses: map[string]openapi.Response{
+			"200": openapi.Reply("The updated user", user),
+			"400": openapi.Reply("Unable to parse the id or the body", message),
+			"401": unauthorized,
+			"403": forbidden,
+			"404": notFound,
+			"409": openapi.Reply("The email belongs to another user", message),
+			"412": preconditionFailed,
+			"422": invalid,
+		},
+	})
+
+	doc.Add(fiber.MethodDelete, users+"/:id", &openapi.Operation{
+		OperationId: "deleteUser",
+		Summary:     "Delete a user with its sessions and credentials",
+		Tags:        []string{"users"},
+		Parameters:  []openapi.Parameter{ifMatch},
+		Security:    bearer,
+		Responses: map[string]openapi.Response{
+			"200": openapi.Reply("User deleted", message),
+			"400": badId,
+			"401": unauthorized,
+			"403": forbidden,
+			"404": notFound,
+			"412": preconditionFailed,
+		},
+	})
+
+	doc.Add(fiber.MethodGet, users+"/:id/versions", &openapi.Operation{
+		OperationId: "listUserVersions",
+		Summary:     "List the versions of a user, the oldest first",
+		Tags:        []string{"users"},
+		Parameters: []openapi.Parameter{
+			{Name: "limit", In: "query", Description: "Defaults to 50, at most 100", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "cursor", In: "query", Description: "The next_cursor of the previous page", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Security: bearer,
+		Responses: map[string]openapi.Response{
+			"200": openapi.Reply("A page of versions, ending with a deleted one when the user was deleted", openapi.SchemaOf(userVersionsBody{})),
+			"400": openapi.Reply("Unable to parse the id, invalid limit or cursor", message),
+			"401": unauthorized,
+			"403": forbidden,
+			"404": notFound,
+		},
+	})
+
+	doc.Add(fiber.MethodPost, users+"/:id/versions/:version/revert", &openapi.Operation{
+		OperationId: "revertUser",
+		Summary:     "Restore the profile of a past version as a new version",
+		Tags:        []string{"users"},
+		Parameters:  []openapi.Parameter{ifMatch},
+		Security:    bearer,
+		Responses: map[string]openapi.Response{
+			"200": openapi.Reply("The user, with a new version", user),
+			"400": openapi.Reply("Unable to parse the id or the version", message),
+			"401": unauthorized,
+			"403": forbidden,
+			"404": openapi.Reply("User or version not found", message),
+			"409": openapi.Reply("The email of the version now belongs to another user", message),
+			"412": preconditionFailed,
+		},
+	})
+
+	for _, path := range []string{users, users + "/:id", users + "/:id/versions", users + "/:id/versions/:version/revert"} {
+		for _, op := range doc.Paths[openapi.Path(path)] {
+			negotiate(op, message)
+		}
+	}
+
+	doc.Add(fiber.MethodDelete, users+"/:id/sessions", &openapi.Operation{
+		OperationId: "revokeUserSessions",
+		Summary:     "Revoke every session of a user (admin)",
+		Tags:        []string{"users"},
+		Security:    bearer,
+		Responses: map[string]openapi.Response{
+			"200": openapi.Reply("Sessions revoked", message),
+			"400": badId,
+			"401": unauthorized,
+			"403": forbidden,
+			"404": notFound,
+		},
+	})
+
+	doc.Add(fiber.MethodGet, users+"/:id/export", &openapi.Operation{
+		OperationId: "exportUser",
+		Summary:     "Export everything stored about a user, as a JSON attachment",
+		Description: "The export always holds every field: unlike the other representations of the user, it doesn't accept the fields query parameter.",
+		Tags:        []string{"users"},
+		Security:    bearer,
+		Responses: map[string]openapi.Response{
+			"200": {
+				Description: "The archive, also available after the user was deleted",
+				Headers:     map[string]openapi.Header{"Content-Disposition": {Schema: &openapi.Schema{Type: "string"}}},
+				Content:     openapi.JSON(openapi.SchemaOf(dto.DataSubjectExportDTO{})),
+			},
+			"400": openapi.Reply("Unable to parse the id, or fields was sent", message),
+			"401": unauthorized,
+			"403": forbidden,
+			"404": notFound,
+			"410": openapi.Reply("The data of the user was erased", message),
+		},
+	})
+
+	doc.Add(fiber.MethodPost, users+"/:id/erase", &openapi.Operation{
+		OperationId: "eraseUser",
+		Summary:     "Erase the data of a user, keeping a tombstone of its id (admin)",
+		Tags:        []string{"users"},
+		Security:    bearer,
+		Responses: map[string]openapi.Response{
+			"200": openapi.Reply("The data is erased, or already was", message),
+			"400": badId,
+			"401": unauthorized,
+			"403": forbidden,
+			"404": notFound,
+			"409": openapi.Reply("The user was modified during the erasure", message),
+		},
+	})
+
+	doc.Add(fiber.MethodGet, users+"/:id/audit", &openapi.Operation{
+		OperationId: "listUserAuditRecords",
+		Summary:     "List the changes made to a user, the oldest first (admin or support)",
+		Tags:        []string{"users"},
+		Parameters: []openapi.Parameter{
+			{Name: "limit", In: "query", Description: "Defaults to 50, at most 100", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "cursor", In: "query", Description: "The next_cursor of the previous page", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Security: bearer,
+		Responses: map[string]openapi.Response{
+			"200": openapi.Reply("A page of audit records, kept after the user is deleted", openapi.SchemaOf(auditTrailBody{})),
+			"400": openapi.Reply("Unable to parse the id, invalid limit or cursor", message),
+			"401": unauthorized,
+			"403": forbidden,
+		},
+	})
+
+	authPath := "/api/v1/auth"
+
+	authOperation := func(method, path, id, summary string, body interface{}, responses map[string]openapi.Response) {
+		op := &openapi.Operation{OperationId: id, Summary: summary, Tags: []string{"auth"}, Responses: responses}
+
+		if body != nil {
+			op.RequestBody = openapi.Body(openapi.SchemaOf(body))
+		}
+
+		if _, ok := responses["422"]; !ok && body != nil {
+			responses["422"] = invalid
+		}
+
+		if _, ok := responses["400"]; !ok && body != nil {
+			responses["400"] = unparsable
+		}
+
+		doc.Add(method, authPath+path, op)
+	}
+
+	authOperation(fiber.MethodPost, "/login", "login", "Open a session", dto.LoginDTO{}, map[string]openapi.Response{
+		"200": openapi.Reply("The session tokens, or an MFA challenge when TOTP is enabled", &openapi.Schema{OneOf: []*openapi.Schema{tokens, openapi.SchemaOf(mfaChallengeBody{})}}),
+		"401": openapi.Reply("Invalid credentials", message),
+		"403": openapi.Reply("Email not verified", message),
+	})
+
+	authOperation(fiber.MethodPost, "/login/mfa", "loginMFA", "Complete a login with a TOTP or recovery code", dto.MFALoginDTO{}, map[string]openapi.Response{
+		"200": openapi.Reply("The session tokens", tokens),
+		"401": openapi.Reply("Invalid MFA token or code, or too many attempts with the token", message),
+		"429": {
+			Description: "Too many attempts for the user",
+			Headers:     tooMany.Headers,
+			Content:     openapi.JSON(openapi.SchemaOf(tooManyRequestsBody{})),
+		},
+	})
+
+	authOperation(fiber.MethodPost, "/refresh", "refresh", "Rotate a refresh token", dto.RefreshTokenDTO{}, map[string]openapi.Response{
+		"200": openapi.Reply("The new session tokens", tokens),
+		"401": openapi.Reply("Invalid refresh token", message),
+	})
+
+	authOperation(fiber.MethodPost, "/logout", "logout", "Revoke the session of a refresh token", dto.RefreshTokenDTO{}, map[string]openapi.Response{
+		"200": openapi.Reply("Logged out", message),
+		"401": openapi.Reply("Invalid refresh token", message),
+	})
+
+	doc.Add(fiber.MethodGet, authPath+"/verify-email", &openapi.Operation{
+		OperationId: "verifyEmail",
+		Summary:     "Verify an email with the token sent by email",
+		Tags:        []string{"auth"},
+		Parameters: []openapi.Parameter{
+			{Name: "token", In: "query", Required: true, Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": openapi.Reply("Email verified", message),
+			"400": openapi.Reply("Invalid or expired token", message),
+		},
+	})
+
+	authOperation(fiber.MethodPost, "/verify-email/resend", "resendVerification", "Send a new verification email", dto.EmailDTO{}, map[string]openapi.Response{
+		"202": openapi.Reply("Sent when the email is registered and unverified", message),
+	})
+
+	authOperation(fiber.MethodPost, "/password/forgot", "forgotPassword", "Send a password reset email", dto.EmailDTO{}, map[string]openapi.Response{
+		"202": openapi.Reply("Sent when the email is registered", message),
+		"429": {
+			Description: "Too many reset requests",
+			Headers:     tooMany.Headers,
+			Content:     openapi.JSON(openapi.SchemaOf(tooManyRequestsBody{})),
+		},
+	})
+
+	authOperation(fiber.MethodPost, "/password/reset", "resetPassword", "Set a new password with a reset token", dto.ResetPasswordDTO{}, map[string]openapi.Response{
+		"200": openapi.Reply("Password reset", message),
+		"400": openapi.Reply("Invalid or expired token, or unable to parse the body", message),
+	})
+
+	doc.Add(fiber.MethodPost, authPath+"/mfa/totp/enroll", &openapi.Operation{
+		OperationId: "enrollTOTP",
+		Summary:     "Start a TOTP enrollment",
+		Tags:        []string{"mfa"},
+		Security:    bearer,
+		Responses: map[string]openapi.Response{
+			"201": openapi.Reply("The secret to add to an authenticator app", openapi.SchemaOf(totpEnrollmentBody{})),
+			"401": unauthorized,
+			"409": openapi.Reply("TOTP already enabled", message),
+		},
+	})
+
+	doc.Add(fiber.MethodPost, authPath+"/mfa/totp/confirm", &openapi.Operation{
+		OperationId: "confirmTOTP",
+		Summary:     "Enable TOTP with a first code",
+		Tags:        []string{"mfa"},
+		RequestBody: openapi.Body(openapi.SchemaOf(dto.TOTPCodeDTO{})),
+		Security:    bearer,
+		Responses: map[string]openapi.Response{
+			"200": openapi.Reply("The recovery codes, shown only once", openapi.SchemaOf(recoveryCodesBody{})),
+			"400": unparsable,
+			"401": unauthorized,
+			"404": openapi.Reply("No pending enrollment", message),
+			"409": openapi.Reply("TOTP already enabled", message),
+			"422": openapi.Reply("Invalid code or body", &openapi.Schema{OneOf: []*openapi.Schema{message, validationErrors}}),
+		},
+	})
+
+	webhooks := "/api/v1/webhooks"
+	webhook := doc.Component("WebhookResponse", webhookBody{})
+	webhookNotFound := openapi.Reply("Webhook not found", message)
+
+	adminOperation := func(method, path, id, summary string, responses map[string]openapi.Response) *openapi.Operation {
+		responses["401"] = unauthorized
+		responses["403"] = forbidden
+
+		op := &openapi.Operation{OperationId: id, Summary: summary, Tags: []string{"webhooks"}, Security: bearer, Responses: responses}
+
+		doc.Add(method, path, op)
+
+		return op
+	}
+
+	create := adminOperation(fiber.MethodPost, webhooks, "createWebhook", "Subscribe a URL to user events (admin)", map[string]openapi.Response{
+		"201": openapi.Reply("The subscription, with its secret shown only once", webhook),
+		"400": unparsable,
+		"422": invalid,
+	})
+	create.RequestBody = openapi.Body(doc.Component("Webhook", dto.WebhookDTO{}))
+
+	adminOperation(fiber.MethodGet, webhooks, "listWebhooks", "List the webhook subscriptions (admin)", map[string]openapi.Response{
+		"200": openapi.Reply("Every subscription", openapi.SchemaOf(webhooksBody{})),
+	})
+
+	adminOperation(fiber.MethodGet, webhooks+"/:id", "getWebhook", "Find a webhook subscription (admin)", map[string]openapi.Response{
+		"200": openapi.Reply("The subscription", webhook),
+		"400": badId,
+		"404": webhookNotFound,
+	})
+
+	adminOperation(fiber.MethodDelete, webhooks+"/:id", "deleteWebhook", "Delete a webhook subscription and its deliveries (admin)", map[string]openapi.Response{
+		"200": openapi.Reply("Webhook deleted", message),
+		"400": badId,
+		"404": webhookNotFound,
+	})
+
+	adminOperation(fiber.MethodPost, webhooks+"/:id/enable", "enableWebhook", "Enable a subscription disabled after repeated failures (admin)", map[string]openapi.Response{
+		"200": openapi.Reply("The enabled subscription", webhook),
+		"400": badId,
+		"404": webhookNotFound,
+	})
+
+	adminOperation(fiber.MethodGet, webhooks+"/:id/deliveries", "listWebhookDeliveries", "List the latest deliveries of a subscription (admin)", map[string]openapi.Response{
+		"200": openapi.Reply("The last 100 deliveries, the latest first", openapi.SchemaOf(deliveriesBody{})),
+		"400": badId,
+		"404": webhookNotFound,
+	})
+
+	adminOperation(fiber.MethodPost, webhooks+"/:id/deliveries/:deliveryId/redeliver", "redeliverWebhook", "Send a delivery again (admin)", map[string]openapi.Response{
+		"202": openapi.Reply("The new delivery, queued", openapi.SchemaOf(deliveryBody{})),
+		"400": openapi.Reply("Unable to parse the id or the delivery id", message),
+		"404": openapi.Reply("Webhook or delivery not found", message),
+	})
+
+	return doc
+}
+
+func setupDocsRoutes(app fiber.Router, doc *openapi.Document) {
+	app.Get("/openapi.json", func(fi *fiber.Ctx) error {
+		return fi.JSON(doc)
+	})
+
+	app.Get("/docs", func(fi *fiber.Ctx) error {
+		fi.Type("html")
+
+		return fi.Send(openapi.DocsPage)
+	})
+
+	app.Use("/docs/assets", filesystem.New(filesystem.Config{
+		Root:   http.FS(openapi.DocsAssets),
+		MaxAge: 86400,
+	}))
+}
+
+// negotiate lists the media types of the codec package next to JSON, with
+// the answers of middleware.Negotiate, which are always JSON.
+func negotiate(op *openapi.Operation, message *openapi.Schema) {
+	alternatives := func(content map[string]openapi.MediaType) {
+		media, ok := content[codec.JSON.MediaType]
+
+		if !ok {
+			return
+		}
+
+		for _, c := range codec.CODECS {
+			content[c.MediaType] = media
+		}
+	}
+
+	if op.RequestBody != nil {
+		alternatives(op.RequestBody.Content)
+
+		op.Responses["415"] = openapi.Reply("The body is in an unsupported media type", message)
+	}
+
+	for _, response := range op.Responses {
+		alternatives(response.Content)
+	}
+
+	op.Responses["406"] = openapi.Reply("None of the accepted media types is available", message)
+}
@@ -0,0 +1,43 @@
+package routes
+
+import (
+	"github.com/LucasAndFlores/user_api/internal/middleware"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type apiVersion struct {
+	prefix string
+	setup  func(fiber.Router, *Handlers)
+}
+
+// versions are mounted side by side under /api. A new version gets its own
+// setup function, reusing the route groups that didn't change.
+var versions = []apiVersion{
+	{prefix: "/v1", setup: setupV1Routes},
+}
+
+func Setup(app fiber.Router, db *gorm.DB, deps *Dependencies) error {
+	h, err := NewHandlers(db, deps)
+
+	if err != nil {
+		return err
+	}
+
+	app.Use(middleware.RequestID)
+
+	api := app.Group("/api")
+
+	for _, version := range versions {
+		version.setup(api.Group(version.prefix), h)
+	}
+
+	setupLegacyRoutes(api, h)
+
+	setupDocsRoutes(app, h.document)
+
+	// GraphQL has a single evolving schema instead of versions
+	app.Post("/graphql", h.optionalAuthenticate, h.graphQLLimit, h.gql.HandleGraphQL)
+
+	return nil
+}
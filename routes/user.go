@@ -1,20 +1,34 @@
 package routes
 
 import (
-	"github.com/LucasAndFlores/user_api/internal/controller"
+	"github.com/LucasAndFlores/user_api/internal/dto"
 	"github.com/LucasAndFlores/user_api/internal/middleware"
-	"github.com/LucasAndFlores/user_api/internal/repository"
-	"github.com/LucasAndFlores/user_api/internal/service"
 	"github.com/gofiber/fiber/v2"
-	"gorm.io/gorm"
 )
 
-func SetupUserRoutes(api fiber.Router, db *gorm.DB) {
-	repo := repository.NewUserRepository(db)
-	service := service.NewUserService(repo)
+func setupUserRoutes(users fiber.Router, h *Handlers) {
+	// before /:id, which would take events for an id
+	users.Get("/events", h.admin(h.events.HandleStreamUserEvents)...)
+	users.Post("/", middleware.Negotiate, h.createLimit, h.idempotency, middleware.ValidateRequestBody[dto.UserDTO](h.deps.Validator), h.user.HandleCreateUser)
+	users.Get("/", append([]fiber.Handler{middleware.Negotiate}, h.staff(h.user.HandleListUsers)...)...)
+	users.Get("/:id", middleware.Negotiate, h.getLimit, h.optionalAuthenticate, withQuery("as_of", h.selfOrStaff), h.user.HandleFindUserByExternalId)
+	users.Put("/:id", middleware.Negotiate, h.authenticate, h.selfOrAdmin, middleware.ValidateRequestBody[dto.UpdateUserDTO](h.deps.Validator), h.user.HandleUpdateUser)
+	users.Delete("/:id", middleware.Negotiate, h.authenticate, h.selfOrAdmin, h.user.HandleDeleteUser)
+	users.Delete("/:id/sessions", h.admin(h.auth.HandleRevokeAllSessions)...)
+	users.Get("/:id/audit", h.staff(h.audit.HandleUserAuditTrail)...)
+	users.Get("/:id/versions", middleware.Negotiate, h.authenticate, h.selfOrStaff, h.user.HandleListUserVersions)
+	users.Post("/:id/versions/:version/revert", middleware.Negotiate, h.authenticate, h.selfOrAdmin, h.user.HandleRevertUser)
+	users.Get("/:id/export", h.authenticate, h.selfOrStaff, h.gdpr.HandleExportUser)
+	users.Post("/:id/erase", h.admin(h.gdpr.HandleEraseUser)...)
+}
 
-	userController := controller.NewUserController(service)
+// withQuery applies handler to the requests with the query parameter only.
+func withQuery(name string, handler fiber.Handler) fiber.Handler {
+	return func(fi *fiber.Ctx) error {
+		if fi.Query(name) == "" {
+			return fi.Next()
+		}
 
-	api.Post("/save", middleware.ValidateUserRequestBody, userController.HandleCreateUser)
-	api.Get("/:id", userController.HandleFindUserByExternalId)
+		return handler(fi)
+	}
 }
@@ -0,0 +1,13 @@
+package routes
+
+import "github.com/gofiber/fiber/v2"
+
+func setupV1Routes(v1 fiber.Router, h *Handlers) {
+	if h.openAPIValidation != nil {
+		v1.Use(h.openAPIValidation)
+	}
+
+	setupAuthRoutes(v1.Group("/auth"), h)
+	setupUserRoutes(v1.Group("/users"), h)
+	setupWebhookRoutes(v1.Group("/webhooks"), h)
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"github.com/LucasAndFlores/user_api/internal/dto"
+	"github.com/LucasAndFlores/user_api/internal/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+// setupWebhookRoutes lets admins manage the subscriptions of partners.
+func setupWebhookRoutes(webhooks fiber.Router, h *Handlers) {
+	webhooks.Post("/", h.admin(middleware.ValidateRequestBody[dto.WebhookDTO](h.deps.Validator), h.webhook.HandleCreateWebhook)...)
+	webhooks.Get("/", h.admin(h.webhook.HandleListWebhooks)...)
+	webhooks.Get("/:id", h.admin(h.webhook.HandleFindWebhook)...)
+	webhooks.Delete("/:id", h.admin(h.webhook.HandleDeleteWebhook)...)
+	webhooks.Post("/:id/enable", h.admin(h.webhook.HandleEnableWebhook)...)
+	webhooks.Get("/:id/deliveries", h.admin(h.webhook.HandleListDeliveries)...)
+	webhooks.Post("/:id/deliveries/:deliveryId/redeliver", h.admin(h.webhook.HandleRedeliver)...)
+}